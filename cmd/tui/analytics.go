@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/analytics"
+	"github.com/glenntam/ibtui/internal/journal"
+	"github.com/glenntam/ibtui/internal/lots"
+	"github.com/glenntam/ibtui/internal/panels"
+)
+
+// Handle keys while the Trade Log shows analytics. Reports whether the key was used.
+func (m *model) updateAnalytics(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch msg.String() {
+	case "a", "esc":
+		m.showAnalytics = false
+	case "r":
+		m.openPrompt("Analyze trades closed from (YYYY-MM-DD[..YYYY-MM-DD], empty for all):",
+			m.tradeRange.String(), m.setTradeRange)
+	default:
+		return nil, false
+	}
+	m.panels[trades].Content = m.renderTradeLogContent()
+	return nil, true
+}
+
+// Sum up the active account's trades closed in the Trade Log's range, with
+// gains matched to lots by the portfolio's lot method.
+func (m *model) analyticsReport() analytics.Report {
+	snap := m.ibs.Snapshot()
+	book := m.lotBook()
+	gains := make([]lots.Gain, 0, len(book.Realized))
+	for _, g := range book.Realized {
+		if snap.InActiveAccount(g.Account) && m.tradeRange.contains(g.Closed) {
+			gains = append(gains, g)
+		}
+	}
+	notes := make(map[string]journal.Note, len(m.tradeHistory))
+	for _, t := range m.tradeHistory {
+		notes[t.ExecID] = t.Note
+	}
+	return analytics.Summarize(analytics.Results(gains, notes), time.Local)
+}
+
+// Render performance over the Trade Log's range: every trade, then by symbol and by tag.
+func (m *model) renderAnalytics() string {
+	r := m.analyticsReport()
+	help := "  a/esc trade log  r range"
+	if m.selectedTab != trades {
+		help = ""
+	}
+	title := fmt.Sprintf("Analytics %s (%v)  Max drawdown %s  Sharpe-like %s%s\n",
+		m.tradeRange, m.lotMethod,
+		panels.FormatNumber(r.MaxDrawdown, 2),
+		panels.FormatNumber(r.Sharpe, 2),
+		help,
+	)
+	if r.Trades == 0 {
+		return title + "No trades closed"
+	}
+	header := []string{"Group", "Trades", "Win rate", "Avg win", "Avg loss", "Profit factor", "Expectancy", "Net P&L"}
+	rows := [][]string{statsRow("All", r.Stats)}
+	for _, g := range r.BySymbol {
+		rows = append(rows, statsRow(g.Key, g.Stats))
+	}
+	for _, g := range r.ByTag {
+		rows = append(rows, statsRow("#"+g.Key, g.Stats))
+	}
+	return title + panels.RenderTable(header, rows, 1)
+}
+
+// Format one group's stats as a row of the analytics table.
+func statsRow(group string, s analytics.Stats) []string {
+	factor := panels.FormatNumber(s.ProfitFactor(), 2)
+	if math.IsInf(s.ProfitFactor(), 1) {
+		factor = "∞"
+	}
+	return []string{
+		group,
+		panels.FormatNumber(float64(s.Trades), 0),
+		panels.FormatNumber(s.WinRate()*100, 1) + "%",
+		panels.FormatNumber(s.AvgWin(), 2),
+		panels.FormatNumber(s.AvgLoss(), 2),
+		factor,
+		panels.FormatNumber(s.Expectancy(), 2),
+		panels.FormatNumber(s.NetPnL, 2),
+	}
+}
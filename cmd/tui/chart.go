@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/glenntam/ibtui/internal/chart"
+	"github.com/glenntam/ibtui/internal/contract"
+	"github.com/glenntam/ibtui/internal/indicator"
+	"github.com/glenntam/ibtui/internal/state"
+)
+
+const (
+	// The chart takes about half the screen, leaving the panels above and below theirs.
+	chartHeightShare = 2
+	minChartHeight   = 8
+	// Borders and padding either side of panel content.
+	panelFrameWidth = 4
+
+	rsiMax = 100 // RSI panes keep a fixed scale from 0
+
+	chartRedrawInterval = time.Second // Streamed bars redraw the chart at most this often
+
+	intradayAxisLayout = "01-02 15:04"
+	dailyAxisLayout    = "2006-01-02"
+)
+
+// chartView is the Chart panel's symbol, timeframe and studies. Its bars
+// stream into IBState.
+type chartView struct {
+	state.ChartKey
+	studies []indicator.Study
+	err     error
+	drawn   time.Time // When streamed bars last redrew the chart
+	stale   bool      // Bars arrived since, to draw on the next clock tick
+}
+
+// barsMsg reports whether streaming a chart's bars started.
+type barsMsg struct {
+	key state.ChartKey
+	err error
+}
+
+// A chart of 5 minute bars with studies, waiting for a symbol.
+func newChartView(studies []indicator.Study) chartView {
+	return chartView{
+		ChartKey: state.ChartKey{Timeframe: state.FiveMinutes, Duration: state.FiveMinutes.Durations()[0]},
+		studies:  studies,
+	}
+}
+
+// Handle keys while the Chart panel is selected. Reports whether the key was used.
+func (m *model) updateChart(msg tea.KeyMsg) (tea.Cmd, bool) {
+	c := &m.chart
+	var cmd tea.Cmd
+	switch msg.String() {
+	case "s":
+		m.openPrompt("Chart symbol[:secType[:exchange[:currency]]]:", c.Spec.String(), m.setChartSymbol)
+	case "o":
+		m.openPrompt("Studies, e.g. sma20 ema50 vwap bb20,2 rsi14 macd12,26,9:", studiesString(c.studies),
+			m.setStudies)
+	case "t":
+		c.Timeframe = c.Timeframe.Next()
+		c.Duration = c.Timeframe.Durations()[0]
+		cmd = m.streamBars()
+	case "D":
+		c.Duration = c.Timeframe.NextDuration(c.Duration)
+		cmd = m.streamBars()
+	case "r":
+		cmd = m.streamBars()
+	default:
+		return nil, false
+	}
+	m.panels[charts].Content = m.renderChartContent()
+	return cmd, true
+}
+
+// Redraw the chart for streamed bars at most once a redraw interval, since
+// each redraw copies the bars and recomputes every study. Bars arriving
+// sooner are drawn on the next clock tick.
+func (m *model) barsChanged() {
+	if time.Since(m.chart.drawn) < chartRedrawInterval {
+		m.chart.stale = true
+		return
+	}
+	m.drawChart()
+}
+
+// Redraw the chart with its latest bars.
+func (m *model) drawChart() {
+	m.chart.drawn, m.chart.stale = time.Now(), false
+	m.panels[charts].Content = m.renderChartContent()
+}
+
+// Chart the order entry form's symbol, if the chart has none yet.
+func (m *model) followQuoteSymbol() tea.Cmd {
+	if m.chart.Spec.Symbol != "" || m.orderForm.spec.Symbol == "" {
+		return nil
+	}
+	m.chart.Spec = m.orderForm.spec
+	return m.streamBars()
+}
+
+// Chart a typed contract.
+func (m *model) setChartSymbol(s string) tea.Cmd {
+	spec, err := contract.Parse(s)
+	if err != nil {
+		slog.Warn("Couldn't chart symbol", "input", s, "error", err)
+		return nil
+	}
+	m.chart.Spec = spec
+	cmd := m.streamBars()
+	m.panels[charts].Content = m.renderChartContent()
+	return cmd
+}
+
+// Replace the chart's studies with typed ones.
+func (m *model) setStudies(s string) tea.Cmd {
+	studies, err := indicator.ParseStudies(s)
+	if err != nil {
+		slog.Warn("Couldn't set chart studies", "input", s, "error", err)
+		return nil
+	}
+	m.chart.studies = studies
+	m.panels[charts].Content = m.renderChartContent()
+	return nil
+}
+
+// Start streaming the chart's bars in the background, replacing any chart streamed before.
+func (m *model) streamBars() tea.Cmd {
+	c := &m.chart
+	if c.Spec.Symbol == "" {
+		return nil
+	}
+	c.err = nil
+	feed, key := m.feed, c.ChartKey
+	return func() tea.Msg {
+		return barsMsg{key: key, err: feed.StreamBars(key)}
+	}
+}
+
+// Note why a chart couldn't stream. A stream for a chart since moved away
+// from may have started after the current one, so restart the current one.
+func (m *model) barsReady(msg barsMsg) tea.Cmd {
+	c := &m.chart
+	if msg.key != c.ChartKey {
+		if msg.err == nil && m.ibs.Snapshot().Chart.ChartKey == msg.key {
+			return m.streamBars()
+		}
+		return nil
+	}
+	if msg.err != nil {
+		slog.Error("Couldn't load chart", "symbol", msg.key.Spec.String(), "error", msg.err)
+	}
+	c.err = msg.err
+	return nil
+}
+
+// Render the Chart panel into a string for further Bubbletea rendering.
+func (m *model) renderChartContent() string {
+	c := m.chart
+	help := "  s symbol  t timeframe  D duration  o studies  r reload"
+	if m.selectedTab != charts {
+		help = ""
+	}
+	if c.Spec.Symbol == "" {
+		return "No symbol. Pick one in Quote / Order Entry or press s." + help
+	}
+	title := fmt.Sprintf("%s  %s  %s%s\n", c.Spec, c.Timeframe, c.Duration, help)
+	var bars []state.Bar
+	if snap := m.ibs.Snapshot(); snap.Chart.ChartKey == c.ChartKey {
+		bars = snap.Chart.Bars
+	}
+	switch {
+	case c.err != nil:
+		return title + c.err.Error()
+	case len(bars) == 0:
+		return title + "Waiting for bars…"
+	}
+	layout := intradayAxisLayout
+	if c.Timeframe == state.OneDay {
+		layout = dailyAxisLayout
+	}
+	overlays, panes := studyLines(c.studies, bars)
+	height := max(m.screenHeight/chartHeightShare, minChartHeight)
+	return title + chart.Render(chart.Chart{Bars: chartBars(bars), Overlays: overlays, Panes: panes, TimeLayout: layout},
+		m.screenWidth-panelFrameWidth, height)
+}
+
+// Convert bars for drawing.
+func chartBars(bars []state.Bar) []chart.Bar {
+	out := make([]chart.Bar, len(bars))
+	for i, b := range bars {
+		out[i] = chart.Bar(b)
+	}
+	return out
+}
+
+// Compute each study over bars: moving averages, VWAP and Bollinger Bands
+// as lines over the candles, RSI and MACD as panes. VWAP starts afresh each day.
+func studyLines(studies []indicator.Study, bars []state.Bar) ([]chart.Line, []chart.Pane) {
+	high, low, closes, volume := make([]float64, len(bars)), make([]float64, len(bars)),
+		make([]float64, len(bars)), make([]float64, len(bars))
+	newSession := make([]bool, len(bars))
+	for i, b := range bars {
+		high[i], low[i], closes[i], volume[i] = b.High, b.Low, b.Close, b.Volume
+		y, m, d := b.Time.Local().Date()
+		py, pm, pd := bars[max(i-1, 0)].Time.Local().Date()
+		newSession[i] = i == 0 || y != py || m != pm || d != pd
+	}
+	palette := studyColors()
+	var overlays []chart.Line
+	var panes []chart.Pane
+	for i, s := range studies {
+		label := strings.ToUpper(s.String())
+		color := palette[i%len(palette)]
+		switch s.Kind {
+		case indicator.KindSMA:
+			overlays = append(overlays, chart.Line{Label: label, Values: indicator.SMA(closes, s.Period(0)), Color: color})
+		case indicator.KindEMA:
+			overlays = append(overlays, chart.Line{Label: label, Values: indicator.EMA(closes, s.Period(0)), Color: color})
+		case indicator.KindVWAP:
+			vwap := indicator.VWAP(indicator.Typical(high, low, closes), volume, newSession)
+			overlays = append(overlays, chart.Line{Label: label, Values: vwap, Color: color})
+		case indicator.KindBollinger:
+			b := indicator.Bollinger(closes, s.Period(0), s.Settings[1])
+			overlays = append(overlays,
+				chart.Line{Label: label, Values: b.Middle, Color: color},
+				chart.Line{Values: b.Upper, Color: color},
+				chart.Line{Values: b.Lower, Color: color})
+		case indicator.KindRSI:
+			rsi := indicator.RSI(closes, s.Period(0))
+			panes = append(panes, chart.Pane{Lines: []chart.Line{{Label: label, Values: rsi, Color: color}}, Max: rsiMax})
+		case indicator.KindMACD:
+			macd := indicator.MACD(closes, s.Period(0), s.Period(1), s.Period(2))
+			panes = append(panes, chart.Pane{
+				Lines: []chart.Line{
+					{Label: label, Values: macd.MACD, Color: color},
+					{Label: "Signal", Values: macd.Signal, Color: palette[(i+1)%len(palette)]},
+				},
+				Histogram: chart.Line{Label: "Hist", Values: macd.Histogram},
+			})
+		}
+	}
+	return overlays, panes
+}
+
+// Colors given to studies in turn.
+func studyColors() []lipgloss.Color {
+	return []lipgloss.Color{"3", "6", "5", "4", "7"} // Yellow, cyan, magenta, blue, white
+}
+
+// Write studies the way indicator.ParseStudies reads them.
+func studiesString(studies []indicator.Study) string {
+	s := make([]string, 0, len(studies))
+	for _, study := range studies {
+		s = append(s, study.String())
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/panels"
+	"github.com/glenntam/ibtui/internal/state"
+)
+
+// completedOrders is the Open Orders panel's view of orders that finished
+// working, shown in place of the working ones.
+type completedOrders struct {
+	orders  []state.CompletedOrder
+	err     error
+	loading bool
+	cursor  int
+}
+
+// completedOrdersMsg carries the outcome of requesting completed orders.
+type completedOrdersMsg struct {
+	orders []state.CompletedOrder
+	err    error
+}
+
+// Handle keys while completed orders are shown. Reports whether the key was used.
+func (m *model) updateCompletedOrders(msg tea.KeyMsg) (tea.Cmd, bool) {
+	c := m.completed
+	rows := m.activeCompletedOrders()
+	var cmd tea.Cmd
+	switch msg.String() {
+	case "up", "k":
+		c.cursor = max(c.cursor-1, 0)
+	case "down", "j":
+		c.cursor = min(c.cursor+1, max(len(rows)-1, 0))
+	case "r":
+		cmd = m.reqCompletedOrders()
+	case "t":
+		if c.cursor < len(rows) {
+			m.openTimeline(rows[c.cursor].OrderID, rows[c.cursor].PermID, orders)
+		}
+	case "c", "esc":
+		m.completed = nil
+	default:
+		return nil, false
+	}
+	m.panels[orders].Content = m.renderOpenOrdersContent()
+	return cmd, true
+}
+
+// Switch the Open Orders panel to completed orders and request them from IB.
+func (m *model) showCompletedOrders() tea.Cmd {
+	m.completed = &completedOrders{}
+	return m.reqCompletedOrders()
+}
+
+// Request completed orders from IB in the background.
+func (m *model) reqCompletedOrders() tea.Cmd {
+	m.completed.loading = true
+	feed := m.feed
+	return func() tea.Msg {
+		done, err := feed.ReqCompletedOrders(false)
+		return completedOrdersMsg{orders: done, err: err}
+	}
+}
+
+// Keep the requested completed orders, unless the view was closed meanwhile.
+func (m *model) completedOrdersReady(msg completedOrdersMsg) {
+	if msg.err != nil {
+		slog.Error("Couldn't request completed orders", "error", msg.err)
+	}
+	c := m.completed
+	if c == nil {
+		return
+	}
+	c.orders, c.err, c.loading = msg.orders, msg.err, false
+	c.cursor = min(c.cursor, max(len(m.activeCompletedOrders())-1, 0))
+}
+
+// List the completed orders of the active account, or of all accounts, newest first.
+func (m *model) activeCompletedOrders() []state.CompletedOrder {
+	snap := m.ibs.Snapshot()
+	done := make([]state.CompletedOrder, 0, len(m.completed.orders))
+	for _, o := range m.completed.orders {
+		if snap.InActiveAccount(o.Account) {
+			done = append(done, o)
+		}
+	}
+	return done
+}
+
+// Render completed orders with their final status, fills and IB's reason, newest first.
+func (m *model) renderCompletedOrders() string {
+	c := m.completed
+	title := "Completed orders  ↑↓ select  t timeline  r refresh  c/esc open orders\n"
+	if m.selectedTab != orders {
+		title = "Completed orders\n"
+	}
+	done := m.activeCompletedOrders()
+	switch {
+	case c.err != nil:
+		return title + c.err.Error()
+	case len(done) == 0 && c.loading:
+		return title + "Requesting completed orders…"
+	case len(done) == 0:
+		return title + "No completed orders"
+	}
+
+	snap := m.ibs.Snapshot()
+	showAccount := snap.ActiveAccount == state.AllAccounts && len(snap.Accounts) > 1
+	header := []string{"Time", "Perm ID", "Symbol", "Side", "Type", "Status", "Reason", "Qty", "Filled", "Avg", "Limit"}
+	leftCols := 8
+	if showAccount {
+		header = append([]string{"Account"}, header...)
+		leftCols++
+	}
+	header = append([]string{" "}, header...)
+
+	rows := make([][]string, 0, len(done))
+	for i, o := range done {
+		cursor := " "
+		if i == c.cursor && m.selectedTab == orders {
+			cursor = "›"
+		}
+		completedAt := ""
+		if !o.Time.IsZero() {
+			completedAt = o.Time.Local().Format(tradeTimeLayout)
+		}
+		row := []string{cursor}
+		if showAccount {
+			row = append(row, o.Account)
+		}
+		row = append(row,
+			completedAt,
+			strconv.FormatInt(o.PermID, 10),
+			o.Symbol,
+			o.Side,
+			o.Type,
+			o.Status,
+			o.Reason,
+			panels.FormatNumber(o.Quantity, -1),
+			panels.FormatNumber(o.Filled, -1),
+			formatPrice(o.AvgPrice),
+			formatPrice(o.LimitPrice),
+		)
+		rows = append(rows, row)
+	}
+	return title + panels.RenderTable(header, rows, leftCols)
+}
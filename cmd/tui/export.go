@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/env"
+	"github.com/glenntam/ibtui/internal/export"
+	"github.com/glenntam/ibtui/internal/journal"
+	"github.com/glenntam/ibtui/internal/state"
+	"github.com/scmhub/ibsync"
+)
+
+const (
+	exportWait     = 5 * time.Second // For IB to send positions and balances
+	exportPollRate = 250 * time.Millisecond
+	exitUsage      = 2 // Bad arguments, like the flag package's own exit code
+)
+
+// exportedMsg reports the files an export from the TUI wrote.
+type exportedMsg struct {
+	paths []string
+	err   error
+}
+
+// Export the Trade Log's range, positions and balances of the active account
+// as typed "FORMAT [DIR]", in the background.
+func (m *model) exportTrades(s string) tea.Cmd {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil
+	}
+	f, err := export.ParseFormat(fields[0])
+	if err != nil {
+		slog.Warn("Couldn't export", "error", err)
+		return nil
+	}
+	dir := "."
+	if len(fields) > 1 {
+		dir = fields[1]
+	}
+	trades := m.tradeLogRows()
+	slices.Reverse(trades) // Oldest first, like the journal
+	snap := m.ibs.Snapshot()
+	summaries := make(map[string]state.AccountSummary)
+	for account, summary := range snap.Summaries {
+		if snap.InActiveAccount(account) {
+			summaries[account] = summary
+		}
+	}
+	d := export.Data{
+		From:      m.tradeRange.from,
+		To:        m.tradeRange.to,
+		Generated: time.Now(),
+		Trades:    trades,
+		Positions: snap.ActivePortfolio(),
+		Summaries: summaries,
+	}
+	return func() tea.Msg {
+		paths, err := export.Export(dir, f, d)
+		return exportedMsg{paths: paths, err: err}
+	}
+}
+
+// Log the outcome of an export.
+func exported(msg exportedMsg) {
+	if msg.err != nil {
+		slog.Error("Couldn't export", "error", msg.err)
+		return
+	}
+	slog.Info("Exported", "files", strings.Join(msg.paths, ", "))
+}
+
+// Run "ibtui export": write the journal, and positions and balances fetched
+// from IB, to files without starting the TUI. Returns the exit code.
+func runExport(cfg *env.Config, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "csv", "csv, jsonl or ofx")
+	period := fs.String("range", "", "trades from YYYY-MM-DD, or YYYY-MM-DD..YYYY-MM-DD; empty for all")
+	dir := fs.String("out", ".", "directory to write the files into")
+	offline := fs.Bool("offline", false, "export only the journal, without connecting to IB")
+	wait := fs.Duration("wait", exportWait, "how long to wait for positions and balances from IB")
+	clientID := fs.Int64("client-id", cfg.ClientID+1, "IB API client ID, different to a running ibtui's")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	r, err := parseDateRange(*period, time.Local)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	j, err := openJournalFile(cfg.JournalFile)
+	if errors.Is(err, journal.ErrInUse) {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "Quit ibtui first, or export from its Trade Log with E.")
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeJournal(j)
+	d, err := exportData(j, r)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !*offline {
+		d.Positions, d.Summaries = fetchAccounts(cfg, *clientID, *wait)
+	}
+	paths, err := export.Export(*dir, f, d)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, p := range paths {
+		fmt.Println(p)
+	}
+	return 0
+}
+
+// Read the journal's trades in r for export.
+func exportData(j *journal.Journal, r dateRange) (export.Data, error) {
+	trades, err := j.Trades(r.from, r.to)
+	if err != nil {
+		return export.Data{}, fmt.Errorf("couldn't read trades to export: %w", err)
+	}
+	return export.Data{From: r.from, To: r.to, Generated: time.Now(), Trades: trades}, nil
+}
+
+// Connect to IB just long enough to read every account's positions and
+// balances, waiting for IB to finish sending the positions and for every
+// account's balances to arrive. No streams are started.
+func fetchAccounts(
+	cfg *env.Config, clientID int64, wait time.Duration,
+) ([]state.PortfolioItem, map[string]state.AccountSummary) {
+	ib := ibsync.NewIB()
+	err := ib.Connect(ibsync.NewConfig(
+		ibsync.WithHost(cfg.Host),
+		ibsync.WithPort(cfg.Port),
+		ibsync.WithClientID(clientID),
+	))
+	if err != nil {
+		slog.Error("Couldn't connect to IB, exporting the journal only", "error", err)
+		return nil, nil
+	}
+	defer disconnect(ib)
+
+	feed := state.NewFeed(ib, state.NewIBState(), func(any) {})
+	deadline := time.Now().Add(wait)
+	accounts := ib.ManagedAccounts()
+	for ; len(accounts) == 0 && time.Now().Before(deadline); accounts = ib.ManagedAccounts() {
+		time.Sleep(exportPollRate)
+	}
+	portfolio, err := feed.ReqPortfolio(accounts)
+	if err != nil {
+		slog.Error("Couldn't wait for every position, some may be missing", "error", err)
+	}
+	summaries := feed.ReqSummaries(accounts)
+	for ; len(summaries) < len(accounts) && time.Now().Before(deadline); summaries = feed.ReqSummaries(accounts) {
+		time.Sleep(exportPollRate)
+	}
+	if len(summaries) < len(accounts) {
+		slog.Error("Couldn't get every account's balances in time, some are left out",
+			"accounts", len(accounts), "received", len(summaries))
+	}
+	return portfolio, summaries
+}
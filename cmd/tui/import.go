@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/env"
+	"github.com/glenntam/ibtui/internal/flex"
+	"github.com/glenntam/ibtui/internal/journal"
+)
+
+// Import a typed Flex Query XML file into the journal in the background,
+// then re-read it. Its times are read in IBTUI_TIMEZONE.
+func (m *model) importFlex(path string) tea.Cmd {
+	path = strings.TrimSpace(path)
+	j := m.journal
+	if path == "" {
+		return nil
+	}
+	if j == nil {
+		slog.Warn("Importing statements needs the trade journal, which isn't open")
+		return nil
+	}
+	return func() tea.Msg {
+		read, added, err := importStatement(j, path, time.Local)
+		if err != nil {
+			return journalMsg{err: err}
+		}
+		slog.Info("Imported Flex Query statement", "file", path, "executions", read, "new", added)
+		return readJournal(j)
+	}
+}
+
+// Run "ibtui import": add the executions in Flex Query XML files to the
+// journal without starting the TUI. Returns the exit code.
+func runImport(cfg *env.Config, args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	tz := fs.String("tz", time.Local.String(), "time zone the statements report times in")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: ibtui import [-tz ZONE] FILE.xml...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil || fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	j := openJournal(cfg.JournalFile)
+	if j == nil {
+		fmt.Fprintln(os.Stderr, "Couldn't open the trade journal. Is ibtui already running?")
+		return 1
+	}
+	defer closeJournal(j)
+	code := 0
+	for _, path := range fs.Args() {
+		read, added, err := importStatement(j, path, loc)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+			continue
+		}
+		fmt.Printf("%v: %d executions, %d new\n", path, read, added)
+	}
+	return code
+}
+
+// Add a statement's executions to the journal, keeping any it already has.
+// Returns how many were read and how many were new.
+func importStatement(j *journal.Journal, path string, loc *time.Location) (int, int, error) {
+	execs, err := flex.ReadFile(path, loc)
+	if err != nil {
+		return 0, 0, fmt.Errorf("couldn't read statement: %w", err)
+	}
+	read := len(execs)
+	execs, err = withoutFills(j, execs)
+	if err != nil {
+		return read, 0, fmt.Errorf("couldn't import %v: %w", path, err)
+	}
+	added, err := j.AddExecutions(execs)
+	if err != nil {
+		return read, 0, fmt.Errorf("couldn't import %v: %w", path, err)
+	}
+	return read, added, nil
+}
+
+// Drop executions without an exec ID that the journal already has under
+// another ID, e.g. fills captured live that an older statement lists again.
+func withoutFills(j *journal.Journal, execs []journal.Execution) ([]journal.Execution, error) {
+	kept := execs[:0]
+	for _, e := range execs {
+		if !flex.HasExecID(e) {
+			had, err := j.HasFill(e)
+			if err != nil {
+				return nil, fmt.Errorf("couldn't match execution %v: %w", e.ExecID, err)
+			}
+			if had {
+				continue
+			}
+		}
+		kept = append(kept, e)
+	}
+	return kept, nil
+}
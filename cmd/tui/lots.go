@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/journal"
+	"github.com/glenntam/ibtui/internal/lots"
+	"github.com/glenntam/ibtui/internal/panels"
+	"github.com/glenntam/ibtui/internal/state"
+)
+
+// lotKey picks out one position row of the Portfolio panel.
+type lotKey struct {
+	account string
+	conID   int64
+}
+
+// Handle keys while the Portfolio panel is selected. Reports whether the key was used.
+func (m *model) updatePortfolio(msg tea.KeyMsg) (tea.Cmd, bool) {
+	items := m.ibs.Snapshot().ActivePortfolio()
+	switch msg.String() {
+	case "up", "k":
+		m.portfolioCursor = max(m.portfolioCursor-1, 0)
+	case "down", "j":
+		m.portfolioCursor = min(m.portfolioCursor+1, max(len(items)-1, 0))
+	case "enter":
+		if m.portfolioCursor < len(items) {
+			p := items[m.portfolioCursor]
+			m.toggleLots(lotKey{p.Account, p.ConID})
+		}
+	case "L":
+		m.lotMethod, m.book = cycle(lots.Methods(), m.lotMethod, 1), nil
+		slog.Info("Switched tax lot method", "method", m.lotMethod)
+	case "Y":
+		m.openPrompt("Realized gains report for year:", strconv.Itoa(time.Now().Year()), m.showGains)
+	case "esc":
+		if m.gainsYear == 0 {
+			return nil, false
+		}
+		m.gainsYear = 0
+	default:
+		return nil, false
+	}
+	m.panels[portfolio].Content = m.renderPorfolioContent()
+	return nil, true
+}
+
+// Show or hide a position's open lots.
+func (m *model) toggleLots(k lotKey) {
+	if m.expandedLots == nil {
+		m.expandedLots = make(map[lotKey]bool)
+	}
+	if m.expandedLots[k] {
+		delete(m.expandedLots, k)
+		return
+	}
+	m.expandedLots[k] = true
+}
+
+// Show the realized gains report for a typed year in place of the positions.
+func (m *model) showGains(s string) tea.Cmd {
+	year, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || year <= 0 {
+		slog.Warn("Couldn't show realized gains", "year", s)
+		return nil
+	}
+	m.gainsYear = year
+	m.panels[portfolio].Content = m.renderPorfolioContent()
+	return nil
+}
+
+// Make a prompt callback that saves which lots a closing execution sells.
+func (m *model) pickLots(execID string) func(string) tea.Cmd {
+	return func(s string) tea.Cmd {
+		j := m.journal
+		if j == nil {
+			slog.Warn("Picking lots needs the trade journal, which isn't open")
+			return nil
+		}
+		var ids []string
+		for id := range strings.SplitSeq(s, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if m.lotMethod != lots.SpecificLot {
+			slog.Info("Picked lots are used once the tax lot method is specific", "method", m.lotMethod)
+		}
+		return func() tea.Msg {
+			if err := j.SetLotPicks(execID, ids); err != nil {
+				return journalMsg{err: err}
+			}
+			return readJournal(j)
+		}
+	}
+}
+
+// Match every known execution into tax lots by the chosen method. The match
+// is kept until the journal, this session's executions or the method change.
+func (m *model) lotBook() lots.Book {
+	if m.book != nil {
+		return *m.book
+	}
+	snap := m.ibs.Snapshot()
+	execs := make([]journal.Execution, 0, len(m.tradeHistory)+len(snap.Executions))
+	seen := make(map[string]bool)
+	for _, t := range m.tradeHistory {
+		execs = append(execs, t.Execution)
+		seen[t.ExecID] = true
+	}
+	for _, e := range snap.Executions {
+		if !seen[e.ExecID] {
+			execs = append(execs, journalExecution(e))
+		}
+	}
+	book := lots.Match(execs, m.lotMethod, m.lotPicks)
+	m.book = &book
+	return book
+}
+
+// Render a position's open lots as rows under it, matching the portfolio columns.
+func renderLotRows(open []lots.Lot, now time.Time, showAccount bool) [][]string {
+	rows := make([][]string, 0, len(open))
+	for _, l := range open {
+		term := "ST"
+		if l.LongTerm(now) {
+			term = "LT"
+		}
+		row := []string{
+			"",
+			"  └ " + l.Opened.Local().Format(dateLayout),
+			term,
+			panels.FormatNumber(l.Quantity, -1),
+			panels.FormatNumber(l.Price, 2),
+			"", "", "", "", "",
+		}
+		if showAccount {
+			row = append([]string{row[0], ""}, row[1:]...)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Render the realized gains of one year, split into short and long term.
+func (m *model) renderGainsReport(book lots.Book, snap state.Snapshot) string {
+	mine := lots.Book{Realized: make([]lots.Gain, 0, len(book.Realized))}
+	for _, g := range book.Realized {
+		if snap.InActiveAccount(g.Account) {
+			mine.Realized = append(mine.Realized, g)
+		}
+	}
+	r := mine.Report(m.gainsYear, time.Local)
+	title := fmt.Sprintf("Realized gains %d (%v)  Short-term %s  Long-term %s  Total %s  esc close\n",
+		r.Year, m.lotMethod,
+		panels.FormatNumber(r.ShortTerm, 2),
+		panels.FormatNumber(r.LongTerm, 2),
+		panels.FormatNumber(r.ShortTerm+r.LongTerm, 2),
+	)
+	header := []string{"Symbol", "Account", "Opened", "Closed", "Term", "Qty", "Cost", "Proceeds", "Gain"}
+	rows := make([][]string, 0, len(r.Gains))
+	for _, g := range r.Gains {
+		term := "Short"
+		if g.LongTerm {
+			term = "Long"
+		}
+		rows = append(rows, []string{
+			g.Symbol,
+			g.Account,
+			g.Opened.Local().Format(dateLayout),
+			g.Closed.Local().Format(dateLayout),
+			term,
+			panels.FormatNumber(g.Quantity, -1),
+			panels.FormatNumber(g.Cost, 2),
+			panels.FormatNumber(g.Proceeds, 2),
+			panels.FormatNumber(g.Amount(), 2),
+		})
+	}
+	if len(rows) == 0 {
+		return title + "No gains realized"
+	}
+	return title + panels.RenderTable(header, rows, 5)
+}
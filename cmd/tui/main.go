@@ -8,9 +8,13 @@ import (
 	"time"
 
 	"github.com/glenntam/ibtui/internal/env"
+	"github.com/glenntam/ibtui/internal/indicator"
+	"github.com/glenntam/ibtui/internal/journal"
 	"github.com/glenntam/ibtui/internal/logger"
+	"github.com/glenntam/ibtui/internal/lots"
 	"github.com/glenntam/ibtui/internal/smtp"
 	"github.com/glenntam/ibtui/internal/state"
+	lists "github.com/glenntam/ibtui/internal/watchlist"
 	"github.com/glenntam/ibtui/internal/zerobridge"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -26,7 +30,11 @@ const (
 
 // Assemble ibtui top-level components, including config, logger and tui.
 func main() {
-	cfg := env.ParseDotEnv()
+	cfg, err := env.ParseDotEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid setting in .env, fix it before starting: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Set up Logger:
 	timezone, err := time.LoadLocation(cfg.Timezone)
@@ -35,6 +43,14 @@ func main() {
 	} else {
 		time.Local = timezone
 	}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			os.Exit(runExport(cfg, os.Args[2:]))
+		case "import":
+			os.Exit(runImport(cfg, os.Args[2:]))
+		}
+	}
 
 	smtp := smtp.NewClient(
 		cfg.SMTPPort,
@@ -64,14 +80,33 @@ func main() {
 	ib.SetClientLogLevel(1)
 
 	// Set up TUI model:
+	watchlists, err := lists.Load(cfg.WatchlistFile)
+	if err != nil {
+		slog.Error("Couldn't load watchlists", "error", err)
+	}
+	tradeJournal := openJournal(cfg.JournalFile)
+	defer closeJournal(tradeJournal)
+	lotMethod, err := lots.ParseMethod(cfg.LotMethod)
+	if err != nil {
+		slog.Warn("Using FIFO tax lots", "error", err)
+	}
+	studies, err := indicator.ParseStudies(cfg.ChartStudies)
+	if err != nil {
+		slog.Warn("Charting without studies", "error", err)
+	}
 	ibs := state.NewIBState()
 	tui := &model{
-		ib:        ib,
-		ibs:       ibs,
-		timezone:  cfg.Timezone,
-		logFile:   logFile,
-		logHeight: logLinesDisplayed,
-		logFollow: true,
+		ib:           ib,
+		ibs:          ibs,
+		watchlists:   watchlists,
+		journal:      tradeJournal,
+		lotMethod:    lotMethod,
+		whatIfAbove:  cfg.WhatIfAbove,
+		chartStudies: studies,
+		timezone:     cfg.Timezone,
+		logFile:      logFile,
+		logHeight:    logLinesDisplayed,
+		logFollow:    true,
 	}
 
 	// Connect to IB API and start TUI:
@@ -87,6 +122,19 @@ func main() {
 	defer disconnect(ib)
 
 	p := tea.NewProgram(tui, tea.WithAltScreen())
+	feed := state.NewFeed(ib, ibs, func(msg any) { p.Send(msg) })
+	tui.feed = feed
+	feed.SetRiskLimits(cfg.Risk)
+	marketData, err := state.ParseMarketDataType(cfg.MarketData)
+	if err != nil {
+		slog.Warn("Using live market data", "error", err)
+		marketData = state.LiveData
+	}
+	if err = feed.SetMarketDataType(marketData); err != nil {
+		slog.Error("Couldn't set market data type", "error", err)
+	}
+	feed.Start()
+	defer feed.Stop()
 	if _, err := p.Run(); err != nil {
 		slog.Error("Couldn't run bubbletea", "error", err)
 	}
@@ -107,6 +155,41 @@ func closeLogFile(f *os.File) {
 	}
 }
 
+// Open the trade journal, or return nil to run without one.
+func openJournal(path string) *journal.Journal {
+	j, err := openJournalFile(path)
+	if err != nil {
+		slog.Error("Couldn't open trade journal, trades won't be kept", "error", err)
+		return nil
+	}
+	return j
+}
+
+// Open the trade journal at path, or at its default path if path is empty.
+func openJournalFile(path string) (*journal.Journal, error) {
+	if path == "" {
+		var err error
+		if path, err = journal.DefaultPath(); err != nil {
+			return nil, fmt.Errorf("couldn't find a place for the trade journal: %w", err)
+		}
+	}
+	j, err := journal.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open the trade journal: %w", err)
+	}
+	return j, nil
+}
+
+// A deferred cleanup function to close the trade journal.
+func closeJournal(j *journal.Journal) {
+	if j == nil {
+		return
+	}
+	if err := j.Close(); err != nil {
+		slog.Error("Couldn't close trade journal", "error", err)
+	}
+}
+
 // A deferred cleanup function to gracefully disconnect from IB API.
 func disconnect(ib *ibsync.IB) {
 	if ib == nil {
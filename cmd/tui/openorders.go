@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/panels"
+	"github.com/glenntam/ibtui/internal/state"
+)
+
+// Handle keys while the Open Orders panel is selected. Reports whether the key was used.
+func (m *model) updateOpenOrders(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if m.completed != nil {
+		return m.updateCompletedOrders(msg)
+	}
+	rows := state.Nest(m.ibs.Snapshot().ActiveOpenOrders())
+	var cmd tea.Cmd
+	switch msg.String() {
+	case "up", "k":
+		m.ordersCursor = max(m.ordersCursor-1, 0)
+	case "down", "j":
+		m.ordersCursor = min(m.ordersCursor+1, max(len(rows)-1, 0))
+	case "x", "delete":
+		if m.ordersCursor < len(rows) {
+			m.cancelOpenOrder(rows[m.ordersCursor].OrderID)
+		}
+	case "enter", "M":
+		if m.ordersCursor < len(rows) {
+			cmd = m.modifyOpenOrder(rows[m.ordersCursor].OpenOrder)
+			// Focus moves from this panel to the order form.
+			m.renderPanel(orders)
+			m.renderPanel(quote)
+			return cmd, true
+		}
+	case "t":
+		if m.ordersCursor < len(rows) {
+			m.openTimeline(rows[m.ordersCursor].OrderID, rows[m.ordersCursor].PermID, orders)
+		}
+	case "X":
+		m.openPrompt("Cancel ALL open orders in every account? Type y to confirm:", "", m.cancelAllOrders)
+	case "c":
+		cmd = m.showCompletedOrders()
+	default:
+		return nil, false
+	}
+	m.panels[orders].Content = m.renderOpenOrdersContent()
+	return cmd, true
+}
+
+// Ask IB to cancel one working order.
+func (m *model) cancelOpenOrder(orderID int64) {
+	if err := m.feed.CancelOrder(orderID); err != nil {
+		slog.Error("Couldn't cancel order", "orderID", orderID, "error", err)
+		return
+	}
+	slog.Info("Requested order cancel", "orderID", orderID)
+}
+
+// Send IB's global cancel once the user has confirmed it.
+func (m *model) cancelAllOrders(answer string) tea.Cmd {
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		return nil
+	}
+	m.feed.CancelAllOrders()
+	slog.Warn("Requested cancel of all open orders")
+	return nil
+}
+
+// Load a working order into the order entry form so its price or size can be changed.
+func (m *model) modifyOpenOrder(o state.OpenOrder) tea.Cmd {
+	t := o.Ticket()
+	cmd := m.setOrderSymbol(t.Spec.String())
+	f := &m.orderForm
+	*f = orderForm{
+		cursor:      fieldQuantity,
+		spec:        f.spec,
+		modifyID:    o.OrderID,
+		side:        t.Side,
+		quantity:    formatTyped(t.Quantity),
+		orderType:   t.Type,
+		limit:       formatTyped(t.LimitPrice),
+		stop:        formatTyped(t.StopPrice),
+		trail:       formatTyped(t.TrailAmount),
+		limitOffset: formatTyped(t.LimitOffset),
+		offset:      formatTyped(t.Offset),
+		tif:         t.TIF,
+		goodTill:    t.GoodTill,
+		outsideRTH:  t.OutsideRTH,
+		account:     t.Account,
+		message:     fmt.Sprintf("Modifying order #%d. Press esc for a new order instead.", o.OrderID),
+	}
+	if t.TrailPercent > 0 {
+		f.trail = formatTyped(t.TrailPercent) + "%"
+	}
+	if fixed, ok := t.Type.FixedTIF(); ok {
+		f.tif = fixed
+	}
+	m.selectedTab = quote
+	m.panels[quote].Revealed = true
+	m.panels[charts].Revealed = false
+	m.panels[orders].Revealed = false
+	m.panels[algos].Revealed = false
+	return cmd
+}
+
+// Render the Open Orders panel into a string for further Bubbletea rendering.
+// Bracket exits are indented under their entry order.
+func (m *model) renderOpenOrdersContent() string {
+	if m.timeline != nil && m.timeline.tab == orders {
+		return m.renderTimeline()
+	}
+	if m.completed != nil {
+		return m.renderCompletedOrders()
+	}
+	snap := m.ibs.Snapshot()
+	open := state.Nest(snap.ActiveOpenOrders())
+	if len(open) == 0 {
+		if m.selectedTab == orders {
+			return "No open orders  c completed"
+		}
+		return "No open orders"
+	}
+
+	// Only the aggregate view mixes accounts, so only it needs an account column.
+	showAccount := snap.ActiveAccount == state.AllAccounts && len(snap.Accounts) > 1
+	header := []string{
+		"Symbol", "Side", "Type", "TIF", "Status", "Why held", "OCA",
+		"Qty", "Filled", "Remaining", "Limit", "Aux",
+	}
+	leftCols := 9
+	if showAccount {
+		header = append([]string{"Account"}, header...)
+		leftCols++
+	}
+	header = append([]string{" ", "ID"}, header...)
+
+	rows := make([][]string, 0, len(open))
+	for i, o := range open {
+		cursor := " "
+		if i == m.ordersCursor && m.selectedTab == orders {
+			cursor = "›"
+		}
+		id := strconv.FormatInt(o.OrderID, 10)
+		if o.Depth > 0 {
+			id = "└ " + id
+		}
+		row := []string{cursor, id}
+		if showAccount {
+			row = append(row, o.Account)
+		}
+		row = append(row,
+			o.Symbol,
+			o.Side,
+			o.Type,
+			o.TIF,
+			o.Status,
+			o.WhyHeld,
+			o.OCAGroup,
+			panels.FormatNumber(o.Quantity, -1),
+			panels.FormatNumber(o.Filled, -1),
+			panels.FormatNumber(o.Remaining, -1),
+			formatPrice(o.LimitPrice),
+			formatPrice(o.AuxPrice),
+		)
+		rows = append(rows, row)
+	}
+	help := "\n↑↓ select  enter modify  t timeline  x cancel  X cancel all  c completed"
+	if m.selectedTab != orders {
+		help = ""
+	}
+	return panels.RenderTable(header, rows, leftCols) + help
+}
+
+// Format an order price, leaving it blank when the order type doesn't use it.
+func formatPrice(p float64) string {
+	if p == 0 {
+		return ""
+	}
+	return panels.FormatNumber(p, 2)
+}
+
+// Format a number the way it would be typed into the order form, blank for 0.
+func formatTyped(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,739 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/contract"
+	"github.com/glenntam/ibtui/internal/order"
+	"github.com/glenntam/ibtui/internal/panels"
+	"github.com/glenntam/ibtui/internal/state"
+)
+
+// errNoAccount occurs when an order could go to several accounts and none is chosen.
+var errNoAccount = errors.New("choose an account for the order")
+
+// formField is one row of the order entry form, numbered in the order they're shown.
+type formField int
+
+const (
+	fieldSymbol formField = iota
+	fieldSide
+	fieldQuantity
+	fieldType
+	fieldStop
+	fieldTrail
+	fieldLimitOffset
+	fieldOffset
+	fieldLimit
+	fieldTIF
+	fieldGoodTill
+	fieldOutsideRTH
+	fieldTakeProfit
+	fieldStopLoss
+	fieldOCAGroup
+	fieldAccount
+	fieldSubmit
+)
+
+// orderForm is the order entry form as typed so far. Numbers are kept as typed
+// and only parsed into an order.Ticket on submit.
+type orderForm struct {
+	cursor      formField
+	spec        contract.Spec
+	modifyID    int64 // Working order the form replaces, 0 for a new order
+	conID       int64 // Quote subscription for spec, 0 until IB resolves it
+	side        string
+	quantity    string
+	orderType   order.Type
+	limit       string
+	stop        string
+	trail       string // A price, or a percent ending in %
+	limitOffset string
+	offset      string
+	tif         order.TIF
+	goodTill    string
+	outsideRTH  bool
+	takeProfit  string // Attaches a profit taker exit when set
+	stopLoss    string // Attaches a stop loss exit when set
+	ocaGroup    string
+	account     string       // Empty places into the active account
+	message     string       // Outcome of the last edit or order
+	previews    []legPreview // One per order of the pending order or bracket
+}
+
+// orderQuoteMsg reports the outcome of subscribing to the order form's quote.
+type orderQuoteMsg struct {
+	spec  contract.Spec
+	conID int64
+	err   error
+}
+
+// orderPlacedMsg reports the outcome of placing an order, or every order of a bracket.
+type orderPlacedMsg struct {
+	summary  string
+	orderIDs []int64
+	modified bool
+	err      error
+}
+
+// pendingOrder is a checked order or bracket waiting for the user to confirm it.
+type pendingOrder struct {
+	ticket    order.Ticket
+	bracket   order.Bracket
+	isBracket bool
+	modifyID  int64
+}
+
+// legPreview is IB's preview of one order of a pending order or bracket.
+type legPreview struct {
+	ticket  order.Ticket
+	preview state.Preview
+}
+
+// whatIfMsg reports IB's preview of each order of a pending order or bracket.
+type whatIfMsg struct {
+	pending  pendingOrder
+	previews []legPreview
+	err      error
+}
+
+// An empty form with the safest choices preselected.
+func newOrderForm() orderForm {
+	return orderForm{
+		side:      order.Buy,
+		orderType: order.Limit,
+		tif:       order.Day,
+	}
+}
+
+// Label shown in front of a form field.
+func (f formField) label() string {
+	switch f {
+	case fieldSymbol:
+		return "Symbol"
+	case fieldSide:
+		return "Side"
+	case fieldQuantity:
+		return "Quantity"
+	case fieldType:
+		return "Type"
+	case fieldLimit:
+		return "Limit"
+	case fieldStop:
+		return "Stop"
+	case fieldTrail:
+		return "Trail"
+	case fieldLimitOffset:
+		return "Lmt offset"
+	case fieldOffset:
+		return "Offset"
+	case fieldTIF:
+		return "TIF"
+	case fieldGoodTill:
+		return "Good till"
+	case fieldOutsideRTH:
+		return "Outside RTH"
+	case fieldTakeProfit:
+		return "Take profit"
+	case fieldStopLoss:
+		return "Stop loss"
+	case fieldOCAGroup:
+		return "OCA group"
+	case fieldAccount:
+		return "Account"
+	case fieldSubmit:
+	}
+	return ""
+}
+
+// The fields shown, in order, for the form's current order type.
+func (f *orderForm) fields() []formField {
+	fields := []formField{fieldSymbol, fieldSide, fieldQuantity, fieldType}
+	t := f.orderType
+	if t.NeedsStop() {
+		fields = append(fields, fieldStop)
+	}
+	if t.NeedsTrail() {
+		fields = append(fields, fieldTrail)
+	}
+	if t.NeedsLimitOffset() {
+		fields = append(fields, fieldLimitOffset)
+	}
+	if t.NeedsOffset() {
+		fields = append(fields, fieldOffset)
+	}
+	if t.UsesLimit() {
+		fields = append(fields, fieldLimit)
+	}
+	if _, fixed := t.FixedTIF(); !fixed {
+		fields = append(fields, fieldTIF)
+		if f.tif == order.GTD {
+			fields = append(fields, fieldGoodTill)
+		}
+	}
+	if t.AllowsOutsideRTH() {
+		fields = append(fields, fieldOutsideRTH)
+	}
+	if f.modifyID != 0 {
+		// IB can't move a working order to another account or attach exits to it
+		return append(fields, fieldSubmit)
+	}
+	return append(fields, fieldTakeProfit, fieldStopLoss, fieldOCAGroup, fieldAccount, fieldSubmit)
+}
+
+// The text shown for a form field.
+func (f *orderForm) value(field formField) string {
+	switch field {
+	case fieldSymbol:
+		return f.spec.String()
+	case fieldSide:
+		return f.side
+	case fieldQuantity:
+		return f.quantity
+	case fieldType:
+		return string(f.orderType)
+	case fieldLimit:
+		return f.limit
+	case fieldStop:
+		return f.stop
+	case fieldTrail:
+		return f.trail
+	case fieldLimitOffset:
+		return f.limitOffset
+	case fieldOffset:
+		return f.offset
+	case fieldTIF:
+		return string(f.tif)
+	case fieldGoodTill:
+		return f.goodTill
+	case fieldOutsideRTH:
+		if f.outsideRTH {
+			return "yes"
+		}
+		return "no"
+	case fieldTakeProfit:
+		return f.takeProfit
+	case fieldStopLoss:
+		return f.stopLoss
+	case fieldOCAGroup:
+		return f.ocaGroup
+	case fieldAccount:
+		if f.account == "" {
+			return "active"
+		}
+		return f.account
+	case fieldSubmit:
+	}
+	return ""
+}
+
+// Move the cursor by step through the visible fields.
+func (f *orderForm) move(step int) {
+	fields := f.fields()
+	i, shown := slices.BinarySearch(fields, f.cursor)
+	if !shown && step > 0 {
+		// The cursor's field was hidden; moving down lands on the next shown one.
+		i--
+	}
+	f.cursor = fields[min(max(i+step, 0), len(fields)-1)]
+}
+
+// Handle keys while the Quote / Order Entry panel is selected. Reports whether the key was used.
+func (m *model) updateOrderEntry(msg tea.KeyMsg) (tea.Cmd, bool) {
+	var cmd tea.Cmd
+	f := &m.orderForm
+	switch msg.String() {
+	case "up", "k":
+		f.move(-1)
+	case "down", "j":
+		f.move(1)
+	case "left", "h":
+		m.cycleOrderField(-1)
+	case "right", "l":
+		m.cycleOrderField(1)
+	case "enter":
+		cmd = m.editOrderField()
+	case "esc":
+		if f.modifyID == 0 {
+			return nil, false
+		}
+		f.modifyID, f.message, f.previews = 0, "Back to a new order", nil
+	default:
+		return nil, false
+	}
+	m.panels[quote].Content = m.renderOrderEntryContent()
+	return cmd, true
+}
+
+// Step through the choices of the selected field, if it has any.
+func (m *model) cycleOrderField(step int) {
+	f := &m.orderForm
+	switch f.cursor {
+	case fieldSide:
+		f.side = cycle([]string{order.Buy, order.Sell}, f.side, step)
+	case fieldType:
+		f.orderType = cycle(order.Types(), f.orderType, step)
+	case fieldTIF:
+		f.tif = cycle(order.TIFs(), f.tif, step)
+	case fieldOutsideRTH:
+		f.outsideRTH = !f.outsideRTH
+	case fieldAccount:
+		f.account = cycle(append([]string{""}, m.ibs.Snapshot().Accounts...), f.account, step)
+	case fieldSymbol, fieldQuantity, fieldLimit, fieldStop, fieldTrail, fieldLimitOffset,
+		fieldOffset, fieldGoodTill, fieldTakeProfit, fieldStopLoss, fieldOCAGroup, fieldSubmit:
+	}
+}
+
+// Edit a text field through a prompt, cycle a choice field, or place the order.
+func (m *model) editOrderField() tea.Cmd {
+	f := &m.orderForm
+	switch f.cursor {
+	case fieldSymbol:
+		if f.modifyID != 0 {
+			f.message = "The symbol of a working order can't be changed"
+			return nil
+		}
+		m.openPrompt("Symbol (SYMBOL[:SECTYPE[:EXCHANGE[:CURRENCY]]]):", f.spec.String(), m.setOrderSymbol)
+	case fieldQuantity:
+		m.openPrompt("Quantity:", f.quantity, setOrderText(m, &f.quantity, order.ParseQuantity))
+	case fieldLimit:
+		m.openPrompt("Limit price:", f.limit, setOrderText(m, &f.limit, order.ParsePrice))
+	case fieldStop:
+		m.openPrompt("Stop price:", f.stop, setOrderText(m, &f.stop, order.ParsePrice))
+	case fieldTrail:
+		m.openPrompt("Trail by (price, or percent ending in %):", f.trail, setOrderText(m, &f.trail, order.ParseTrail))
+	case fieldLimitOffset:
+		m.openPrompt("Limit offset from stop:", f.limitOffset, setOrderText(m, &f.limitOffset, order.ParsePrice))
+	case fieldOffset:
+		m.openPrompt("Offset from NBBO:", f.offset, setOrderText(m, &f.offset, order.ParsePrice))
+	case fieldGoodTill:
+		m.openPrompt("Good till ("+order.GoodTillLayout+"):", f.goodTill, setOrderText(m, &f.goodTill, order.ParseGoodTill))
+	case fieldTakeProfit:
+		m.openPrompt("Take profit limit price (empty for none):", f.takeProfit,
+			setOrderText(m, &f.takeProfit, order.ParsePrice))
+	case fieldStopLoss:
+		m.openPrompt("Stop loss stop price (empty for none):", f.stopLoss,
+			setOrderText(m, &f.stopLoss, order.ParsePrice))
+	case fieldOCAGroup:
+		m.openPrompt("OCA group (orders sharing it cancel each other):", f.ocaGroup, m.setOCAGroup)
+	case fieldSide, fieldType, fieldTIF, fieldOutsideRTH, fieldAccount:
+		m.cycleOrderField(1)
+	case fieldSubmit:
+		return m.confirmOrder()
+	}
+	return nil
+}
+
+// Switch the form to a new contract and stream its quote above the form.
+func (m *model) setOrderSymbol(s string) tea.Cmd {
+	f := &m.orderForm
+	spec, err := contract.Parse(s)
+	if err != nil {
+		f.message = err.Error()
+		m.panels[quote].Content = m.renderOrderEntryContent()
+		return nil
+	}
+	if f.conID != 0 {
+		m.feed.CancelQuote(f.conID)
+	}
+	f.spec, f.conID, f.message = spec, 0, ""
+	m.panels[quote].Content = m.renderOrderEntryContent()
+	feed := m.feed
+	return func() tea.Msg {
+		conID, err := feed.ReqQuote(spec)
+		return orderQuoteMsg{spec: spec, conID: conID, err: err}
+	}
+}
+
+// Keep the form's quote subscription, unless the symbol changed while it resolved.
+func (m *model) orderQuoteReady(msg orderQuoteMsg) {
+	f := &m.orderForm
+	if msg.err != nil {
+		if msg.spec == f.spec {
+			f.message = msg.err.Error()
+		}
+		slog.Error("Couldn't subscribe to order entry quote", "symbol", msg.spec.String(), "error", msg.err)
+		return
+	}
+	if msg.spec != f.spec || f.conID != 0 {
+		m.feed.CancelQuote(msg.conID)
+		return
+	}
+	f.conID = msg.conID
+}
+
+// Make a prompt callback that stores typed text after checking it parses.
+func setOrderText[T any](m *model, dst *string, parse func(string) (T, error)) func(string) tea.Cmd {
+	return func(s string) tea.Cmd {
+		s = strings.TrimSpace(s)
+		m.orderForm.message = ""
+		if _, err := parse(s); err != nil {
+			m.orderForm.message = err.Error()
+		}
+		*dst = s
+		m.panels[quote].Content = m.renderOrderEntryContent()
+		return nil
+	}
+}
+
+// Store the typed OCA group name.
+func (m *model) setOCAGroup(s string) tea.Cmd {
+	m.orderForm.ocaGroup = strings.TrimSpace(s)
+	m.panels[quote].Content = m.renderOrderEntryContent()
+	return nil
+}
+
+// Build an order.Ticket from the form, checking it is complete.
+func (m *model) orderTicket() (order.Ticket, error) {
+	f := &m.orderForm
+	t := order.Ticket{
+		Spec:     f.spec,
+		Account:  f.account,
+		Side:     f.side,
+		Type:     f.orderType,
+		TIF:      f.tif,
+		GoodTill: f.goodTill,
+		OCAGroup: f.ocaGroup,
+	}
+	if fixed, ok := t.Type.FixedTIF(); ok {
+		t.TIF = fixed
+	}
+	t.OutsideRTH = f.outsideRTH && t.Type.AllowsOutsideRTH()
+	snap := m.ibs.Snapshot()
+	if t.Account == "" {
+		t.Account = snap.ActiveAccount
+	}
+	if t.Account == state.AllAccounts && len(snap.Accounts) > 1 {
+		return t, errNoAccount
+	}
+	var err error
+	if t.Quantity, err = order.ParseQuantity(f.quantity); err != nil {
+		return t, fmt.Errorf("couldn't build order: %w", err)
+	}
+	if err = parseOrderPrices(f, &t); err != nil {
+		return t, err
+	}
+	if err = t.Validate(); err != nil {
+		return t, fmt.Errorf("couldn't build order: %w", err)
+	}
+	return t, nil
+}
+
+// Parse the typed prices that t's order type uses.
+func parseOrderPrices(f *orderForm, t *order.Ticket) error {
+	var err error
+	if t.Type.UsesLimit() {
+		if t.LimitPrice, err = order.ParsePrice(f.limit); err != nil {
+			return fmt.Errorf("couldn't build order: %w", err)
+		}
+	}
+	if t.Type.NeedsStop() {
+		if t.StopPrice, err = order.ParsePrice(f.stop); err != nil {
+			return fmt.Errorf("couldn't build order: %w", err)
+		}
+	}
+	if t.Type.NeedsTrail() {
+		trail, err := order.ParseTrail(f.trail)
+		if err != nil {
+			return fmt.Errorf("couldn't build order: %w", err)
+		}
+		t.TrailAmount, t.TrailPercent = trail.Amount, trail.Percent
+	}
+	if t.Type.NeedsLimitOffset() {
+		if t.LimitOffset, err = order.ParsePrice(f.limitOffset); err != nil {
+			return fmt.Errorf("couldn't build order: %w", err)
+		}
+	}
+	if t.Type.NeedsOffset() {
+		if t.Offset, err = order.ParsePrice(f.offset); err != nil {
+			return fmt.Errorf("couldn't build order: %w", err)
+		}
+	}
+	return nil
+}
+
+// Build a bracket from the form. Reports false if no exit is attached.
+func (m *model) orderBracket(entry order.Ticket) (order.Bracket, bool, error) {
+	f := &m.orderForm
+	b := order.Bracket{Entry: entry}
+	var err error
+	if b.TakeProfit, err = order.ParsePrice(f.takeProfit); err != nil {
+		return b, true, fmt.Errorf("couldn't build bracket: %w", err)
+	}
+	if b.StopLoss, err = order.ParsePrice(f.stopLoss); err != nil {
+		return b, true, fmt.Errorf("couldn't build bracket: %w", err)
+	}
+	if b.TakeProfit == 0 && b.StopLoss == 0 {
+		return b, false, nil
+	}
+	if err = b.Validate(); err != nil {
+		return b, true, fmt.Errorf("couldn't build bracket: %w", err)
+	}
+	return b, true, nil
+}
+
+// Check the form and ask the user to confirm it. Orders worth at least the
+// what-if threshold are previewed by IB first, every leg of a bracket included.
+func (m *model) confirmOrder() tea.Cmd {
+	f := &m.orderForm
+	f.previews = nil
+	t, err := m.orderTicket()
+	if err != nil {
+		f.message = err.Error()
+		return nil
+	}
+	b, isBracket, err := m.orderBracket(t)
+	if err != nil {
+		f.message = err.Error()
+		return nil
+	}
+	p := pendingOrder{ticket: t, bracket: b, isBracket: isBracket, modifyID: f.modifyID}
+	tickets := p.tickets()
+	if !slices.ContainsFunc(tickets, m.needsWhatIf) {
+		f.message = ""
+		m.askToPlace(p)
+		return nil
+	}
+	f.message = "Previewing " + p.String() + " with IB..."
+	feed := m.feed
+	return func() tea.Msg {
+		previews := make([]legPreview, 0, len(tickets))
+		for _, t := range tickets {
+			preview, err := feed.WhatIf(t)
+			if err != nil {
+				return whatIfMsg{pending: p, err: err}
+			}
+			previews = append(previews, legPreview{ticket: t, preview: preview})
+		}
+		return whatIfMsg{pending: p, previews: previews}
+	}
+}
+
+// Report whether t is worth enough to need a what-if preview, counting
+// the contract multiplier of options and futures. An order with no price
+// to value it by is always previewed.
+func (m *model) needsWhatIf(t order.Ticket) bool {
+	q := m.ibs.Snapshot().Quotes[m.orderForm.conID]
+	price := 0.0
+	switch {
+	case t.Type.UsesLimit() && t.LimitPrice > 0:
+		price = t.LimitPrice
+	case t.Type.NeedsStop():
+		price = t.StopPrice
+	case q.Last > 0 && q.Last != state.Unset:
+		price = q.Last
+	}
+	multiplier := 1.0
+	if q.Multiplier > 0 {
+		multiplier = q.Multiplier
+	}
+	return price == 0 || t.Quantity*price*multiplier >= m.whatIfAbove
+}
+
+// Show IB's preview and ask to confirm the order it was made for.
+func (m *model) whatIfReady(msg whatIfMsg) {
+	f := &m.orderForm
+	if msg.err != nil {
+		f.message = "Order not placed: " + msg.err.Error()
+		slog.Error("Couldn't preview order", "order", msg.pending.String(), "error", msg.err)
+		return
+	}
+	f.message = ""
+	f.previews = msg.previews
+	m.askToPlace(msg.pending)
+}
+
+// Ask the user to confirm p before it is sent to IB. An order over risk
+// limits needs OVERRIDE typed out in full instead.
+func (m *model) askToPlace(p pendingOrder) {
+	feed := m.feed
+	if err := p.checkRisk(feed, m.orderForm.conID); err != nil {
+		slog.Warn("Risk check rejected order", "order", p.String(), "error", err)
+		m.orderForm.message = strings.ReplaceAll(err.Error(), "\n", "; ")
+		m.openPrompt("Over risk limits! Type OVERRIDE to place "+p.String()+" anyway:", "",
+			func(answer string) tea.Cmd {
+				m.orderForm.previews = nil
+				if strings.TrimSpace(answer) != "OVERRIDE" {
+					m.orderForm.message = "Order not placed"
+					m.panels[quote].Content = m.renderOrderEntryContent()
+					return nil
+				}
+				return func() tea.Msg {
+					return p.place(feed, true)
+				}
+			})
+		return
+	}
+	m.openPrompt("Place "+p.String()+"? Type y to confirm:", "", func(answer string) tea.Cmd {
+		m.orderForm.previews = nil
+		if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+			m.orderForm.message = "Order not placed"
+			m.panels[quote].Content = m.renderOrderEntryContent()
+			return nil
+		}
+		return func() tea.Msg {
+			return p.place(feed, false)
+		}
+	})
+}
+
+// Report placed orders in the form and the log.
+func (m *model) orderPlaced(msg orderPlacedMsg) {
+	if msg.err != nil {
+		m.orderForm.message = msg.err.Error()
+		slog.Error("Couldn't place order", "order", msg.summary, "error", msg.err)
+		return
+	}
+	if msg.modified {
+		m.orderForm.modifyID = 0
+		m.orderForm.message = fmt.Sprintf("Modified order %v: %v", formatOrderIDs(msg.orderIDs), msg.summary)
+		slog.Info("Modified order", "orderIDs", msg.orderIDs, "order", msg.summary)
+		return
+	}
+	m.orderForm.message = fmt.Sprintf("Placed order %v: %v", formatOrderIDs(msg.orderIDs), msg.summary)
+	slog.Info("Placed order", "orderIDs", msg.orderIDs, "order", msg.summary)
+}
+
+// Place the order, every order of the bracket, or the changes to a working order. Blocks on IB.
+func (p pendingOrder) place(feed *state.Feed, override bool) orderPlacedMsg {
+	msg := orderPlacedMsg{summary: p.String()}
+	if p.modifyID != 0 {
+		msg.orderIDs, msg.modified = []int64{p.modifyID}, true
+		msg.err = feed.ModifyOrder(p.modifyID, p.ticket, override)
+		return msg
+	}
+	if p.isBracket {
+		msg.orderIDs, msg.err = feed.PlaceBracket(p.bracket, override)
+		return msg
+	}
+	orderID, err := feed.PlaceOrder(p.ticket, override)
+	msg.orderIDs, msg.err = []int64{orderID}, err
+	return msg
+}
+
+// The orders p places: the ticket, or a bracket's entry and exits.
+func (p pendingOrder) tickets() []order.Ticket {
+	if p.isBracket && p.modifyID == 0 {
+		return p.bracket.Tickets()
+	}
+	return []order.Ticket{p.ticket}
+}
+
+// Check p against risk limits with the latest quote of conID.
+func (p pendingOrder) checkRisk(feed *state.Feed, conID int64) error {
+	if p.isBracket && p.modifyID == 0 {
+		return feed.CheckBracketRisk(p.bracket, conID)
+	}
+	return feed.CheckRisk(p.ticket, conID, p.modifyID)
+}
+
+// String summarises the order for a confirmation prompt.
+func (p pendingOrder) String() string {
+	if p.modifyID != 0 {
+		return fmt.Sprintf("#%d as %v", p.modifyID, p.ticket.String())
+	}
+	if p.isBracket {
+		return p.bracket.String()
+	}
+	return p.ticket.String()
+}
+
+// Render the Quote / Order Entry panel into a string for further Bubbletea rendering.
+func (m *model) renderOrderEntryContent() string {
+	f := &m.orderForm
+	var b strings.Builder
+	b.WriteString(m.renderOrderQuote())
+	b.WriteString("\n")
+	for _, field := range f.fields() {
+		cursor := " "
+		if field == f.cursor && m.selectedTab == quote {
+			cursor = "›"
+		}
+		if field == fieldSubmit && f.modifyID != 0 {
+			fmt.Fprintf(&b, "%s [ Modify order #%d ]\n", cursor, f.modifyID)
+			continue
+		}
+		if field == fieldSubmit {
+			fmt.Fprintf(&b, "%s [ Place order ]\n", cursor)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %-9s %s\n", cursor, field.label(), f.value(field))
+	}
+	for _, p := range f.previews {
+		if len(f.previews) > 1 {
+			b.WriteString(p.ticket.String() + "\n")
+		}
+		b.WriteString(renderPreview(p.preview))
+		b.WriteString("\n")
+	}
+	if f.message != "" {
+		b.WriteString(f.message)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Render the live quote of the form's contract on one line.
+func (m *model) renderOrderQuote() string {
+	f := &m.orderForm
+	if f.spec.Symbol == "" {
+		return "Select this tab and press enter on Symbol to choose a contract."
+	}
+	if f.conID == 0 {
+		return f.spec.String() + "  waiting for quote"
+	}
+	q, ok := m.ibs.Snapshot().Quotes[f.conID]
+	if !ok {
+		return f.spec.String() + "  waiting for quote"
+	}
+	return fmt.Sprintf("%s  Bid %s  Ask %s  Last %s  Chg %s (%s%%)  %s",
+		f.spec.String(),
+		panels.FormatNumber(q.Bid, 2),
+		panels.FormatNumber(q.Ask, 2),
+		panels.FormatNumber(q.Last, 2),
+		panels.FormatNumber(q.Change, 2),
+		panels.FormatNumber(q.ChangePct, 2),
+		q.DataType.Tag(),
+	)
+}
+
+// Render IB's what-if estimate as a small table.
+func renderPreview(p state.Preview) string {
+	header := []string{"What-if", "Change", "After"}
+	rows := [][]string{
+		{"Init margin", panels.FormatNumber(p.InitMarginChange, 2), panels.FormatNumber(p.InitMarginAfter, 2)},
+		{"Maint margin", panels.FormatNumber(p.MaintMarginChange, 2), panels.FormatNumber(p.MaintMarginAfter, 2)},
+		{"Equity w/ loan", panels.FormatNumber(p.EquityWithLoanChange, 2), panels.FormatNumber(p.EquityWithLoanAfter, 2)},
+	}
+	commission := fmt.Sprintf("Commission %s %s (min %s, max %s)",
+		panels.FormatNumber(p.Commission, 2),
+		p.CommissionCurrency,
+		panels.FormatNumber(p.MinCommission, 2),
+		panels.FormatNumber(p.MaxCommission, 2),
+	)
+	s := panels.RenderTable(header, rows, 1) + "\n" + commission
+	if p.Warning != "" {
+		s += "\nWarning: " + p.Warning
+	}
+	return s
+}
+
+// Format order IDs as "#1", or "#1, #2, #3" for a bracket.
+func formatOrderIDs(ids []int64) string {
+	s := make([]string, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, "#"+strconv.FormatInt(id, 10))
+	}
+	return strings.Join(s, ", ")
+}
+
+// Return the choice step places after current, wrapping at either end.
+func cycle[T comparable](choices []T, current T, step int) T {
+	i := slices.Index(choices, current)
+	return choices[((i+step)%len(choices)+len(choices))%len(choices)]
+}
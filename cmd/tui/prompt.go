@@ -0,0 +1,46 @@
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// prompt is a single line text input shown in place of the status line.
+type prompt struct {
+	label    string
+	value    []rune
+	onSubmit func(string) tea.Cmd
+}
+
+// Open a prompt that calls onSubmit with the typed text when Enter is pressed.
+func (m *model) openPrompt(label, initial string, onSubmit func(string) tea.Cmd) {
+	m.prompt = &prompt{
+		label:    label,
+		value:    []rune(initial),
+		onSubmit: onSubmit,
+	}
+}
+
+// Handle a keypress while a prompt is open. Esc discards the prompt.
+func (m *model) updatePrompt(msg tea.KeyMsg) tea.Cmd {
+	p := m.prompt
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		m.prompt = nil
+	case tea.KeyEnter:
+		m.prompt = nil
+		return p.onSubmit(string(p.value))
+	case tea.KeyBackspace:
+		if len(p.value) > 0 {
+			p.value = p.value[:len(p.value)-1]
+		}
+	case tea.KeySpace:
+		p.value = append(p.value, ' ')
+	case tea.KeyRunes:
+		p.value = append(p.value, msg.Runes...)
+	default:
+	}
+	return nil
+}
+
+// Render the prompt with a block cursor at the end.
+func (p *prompt) String() string {
+	return p.label + " " + string(p.value) + "█"
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/panels"
+	"github.com/glenntam/ibtui/internal/state"
+)
+
+// Layout of timeline timestamps, shown in IBTUI_TIMEZONE.
+const timelineLayout = "2006-01-02 15:04:05 MST"
+
+// orderTimeline is the detail view of one order's life, shown in place of
+// the table of the tab it was opened from.
+type orderTimeline struct {
+	orderID   int64
+	permID    int64
+	tab       int
+	events    []state.OrderEvent
+	journaled bool // Events are the journal's fills, as IB doesn't know the order this session
+	err       error
+}
+
+// Handle keys while a timeline is shown. Reports whether the key was used.
+func (m *model) updateTimeline(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if msg.String() != "esc" && msg.String() != "t" {
+		return nil, false
+	}
+	tab := m.timeline.tab
+	m.timeline = nil
+	m.renderTab(tab)
+	return nil, true
+}
+
+// Show the timeline of an order in place of tab's table.
+func (m *model) openTimeline(orderID, permID int64, tab int) {
+	m.timeline = &orderTimeline{orderID: orderID, permID: permID, tab: tab}
+	m.refreshTimeline()
+}
+
+// Re-read the shown timeline from IB's cache. IB only knows this session's
+// orders, so earlier ones show the fills the journal kept instead.
+func (m *model) refreshTimeline() {
+	tl := m.timeline
+	if tl == nil {
+		return
+	}
+	tl.events, tl.err = m.feed.OrderTimeline(tl.orderID, tl.permID)
+	tl.journaled = false
+	if errors.Is(tl.err, state.ErrUnknownOrder) {
+		if fills := m.journalFills(tl.permID); len(fills) > 0 {
+			tl.events, tl.journaled, tl.err = fills, true, nil
+		}
+	}
+	if tl.err != nil {
+		slog.Warn("Couldn't show order timeline", "orderID", tl.orderID, "error", tl.err)
+	}
+	m.renderTab(tl.tab)
+}
+
+// The journal's fills of the order with permID, oldest first.
+func (m *model) journalFills(permID int64) []state.OrderEvent {
+	if permID == 0 {
+		return nil
+	}
+	var events []state.OrderEvent
+	for _, t := range m.tradeHistory {
+		if t.PermID == permID {
+			events = append(events, state.OrderEvent{
+				Time:      t.Time,
+				Status:    "Fill",
+				Message:   t.Exchange,
+				FillQty:   t.Quantity,
+				FillPrice: t.Price,
+			})
+		}
+	}
+	slices.SortStableFunc(events, func(a, b state.OrderEvent) int {
+		return cmp.Compare(a.Time.UnixNano(), b.Time.UnixNano())
+	})
+	return events
+}
+
+// Re-render the panel a timeline can be shown in.
+func (m *model) renderTab(tab int) {
+	switch tab {
+	case orders:
+		m.panels[orders].Content = m.renderOpenOrdersContent()
+	case trades:
+		m.panels[trades].Content = m.renderTradeLogContent()
+	}
+}
+
+// Render the shown timeline as a table of events, oldest first.
+func (m *model) renderTimeline() string {
+	tl := m.timeline
+	title := fmt.Sprintf("Order #%d timeline  t/esc close\n", tl.orderID)
+	if tl.orderID == 0 {
+		title = fmt.Sprintf("Order %d timeline  t/esc close\n", tl.permID)
+	}
+	if tl.journaled {
+		title += "From an earlier session: only its fills were journaled\n"
+	}
+	if tl.err != nil {
+		return title + tl.err.Error()
+	}
+	if len(tl.events) == 0 {
+		return title + "No events yet"
+	}
+	header := []string{"Time", "Status", "Message", "Filled", "Price", "Code"}
+	rows := make([][]string, 0, len(tl.events))
+	for _, e := range tl.events {
+		filled, price, code := "", "", ""
+		if e.FillQty != 0 {
+			filled = panels.FormatNumber(e.FillQty, -1)
+			price = panels.FormatNumber(e.FillPrice, 2)
+		}
+		if e.ErrorCode != 0 {
+			code = strconv.FormatInt(e.ErrorCode, 10)
+		}
+		rows = append(rows, []string{
+			e.Time.Local().Format(timelineLayout),
+			e.Status,
+			e.Message,
+			filled,
+			price,
+			code,
+		})
+	}
+	return title + panels.RenderTable(header, rows, 3)
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/journal"
+	"github.com/glenntam/ibtui/internal/panels"
+	"github.com/glenntam/ibtui/internal/state"
+)
+
+const (
+	dateLayout      = "2006-01-02"
+	tradeTimeLayout = "01-02 15:04:05"
+)
+
+// errBadDateRange occurs when a Trade Log filter isn't a date or two.
+var errBadDateRange = errors.New("expected YYYY-MM-DD or YYYY-MM-DD..YYYY-MM-DD")
+
+// dateRange limits the Trade Log to executions on or after from and before to.
+// A zero from or to leaves that end open.
+type dateRange struct {
+	from time.Time
+	to   time.Time
+}
+
+// executionsReqMsg reports the outcome of requesting earlier executions from IB.
+type executionsReqMsg struct {
+	err error
+}
+
+// journalRecord is what was last written to the journal, so only new or
+// changed rows are written again. Orders are kept without their time.
+type journalRecord struct {
+	execs  map[string]journal.Execution
+	orders map[int64]journal.Order
+}
+
+// journalMsg carries every journal trade and lot pick after a read or write.
+type journalMsg struct {
+	trades []journal.Trade
+	picks  map[string][]string
+	err    error
+}
+
+// journalRecordedMsg reports the executions recordJournal wrote, or why it couldn't.
+type journalRecordedMsg struct {
+	execs []journal.Execution
+	err   error
+}
+
+// Handle keys while the Trade Log panel is selected. Reports whether the key was used.
+func (m *model) updateTradeLog(msg tea.KeyMsg) (tea.Cmd, bool) {
+	if m.showAnalytics {
+		return m.updateAnalytics(msg)
+	}
+	rows := m.tradeLogRows()
+	var cmd tea.Cmd
+	switch msg.String() {
+	case "up", "k":
+		m.tradesCursor = max(m.tradesCursor-1, 0)
+	case "down", "j":
+		m.tradesCursor = min(m.tradesCursor+1, max(len(rows)-1, 0))
+	case "t":
+		if m.tradesCursor < len(rows) {
+			m.openTimeline(rows[m.tradesCursor].OrderID, rows[m.tradesCursor].PermID, trades)
+		}
+	case "r":
+		m.openPrompt("Show executions from (YYYY-MM-DD[..YYYY-MM-DD], empty for all):",
+			m.tradeRange.String(), m.setTradeRange)
+	case "n":
+		if m.tradesCursor < len(rows) {
+			tr := rows[m.tradesCursor]
+			m.openPrompt("Note on "+tr.Symbol+" "+tr.Side+":", tr.Text, m.noteTrade(tr, false))
+		}
+	case "g":
+		if m.tradesCursor < len(rows) {
+			tr := rows[m.tradesCursor]
+			m.openPrompt("Tags on "+tr.Symbol+" "+tr.Side+" (comma separated):",
+				strings.Join(tr.Tags, ","), m.noteTrade(tr, true))
+		}
+	case "a":
+		m.showAnalytics = true
+	case "I":
+		m.openPrompt("Import Flex Query XML statement (times in "+time.Local.String()+"):", "", m.importFlex)
+	case "E":
+		m.openPrompt("Export "+m.tradeRange.String()+" as csv, jsonl or ofx [into dir]:", "csv exports",
+			m.exportTrades)
+	case "L":
+		if m.tradesCursor < len(rows) {
+			tr := rows[m.tradesCursor]
+			m.openPrompt("Lots "+tr.Symbol+" "+tr.Side+" closes (opening exec IDs, comma separated):",
+				strings.Join(m.lotPicks[tr.ExecID], ","), m.pickLots(tr.ExecID))
+		}
+	default:
+		return nil, false
+	}
+	m.panels[trades].Content = m.renderTradeLogContent()
+	return cmd, true
+}
+
+// Filter the Trade Log to a typed date range, asking IB for any earlier executions.
+func (m *model) setTradeRange(s string) tea.Cmd {
+	r, err := parseDateRange(s, time.Local)
+	if err != nil {
+		slog.Warn("Couldn't filter the Trade Log", "input", s, "error", err)
+		return nil
+	}
+	m.tradeRange, m.tradesCursor = r, 0
+	m.panels[trades].Content = m.renderTradeLogContent()
+	if r.from.IsZero() {
+		return nil
+	}
+	feed := m.feed
+	return func() tea.Msg {
+		return executionsReqMsg{err: feed.ReqExecutions(r.from)}
+	}
+}
+
+// Read the journal in the background.
+func (m *model) loadTradeHistory() tea.Cmd {
+	j := m.journal
+	if j == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return readJournal(j)
+	}
+}
+
+// Write this session's executions and finished orders that are new or
+// changed since the last write to the journal in the background. Nothing
+// is written if nothing changed.
+func (m *model) recordJournal() tea.Cmd {
+	j := m.journal
+	if j == nil {
+		return nil
+	}
+	r := &m.recorded
+	if r.execs == nil {
+		r.execs, r.orders = make(map[string]journal.Execution), make(map[int64]journal.Order)
+	}
+	snap := m.ibs.Snapshot()
+	var execs []journal.Execution
+	for _, e := range snap.Executions {
+		if je := journalExecution(e); r.execs[je.ExecID] != je {
+			r.execs[je.ExecID] = je
+			execs = append(execs, je)
+		}
+	}
+	var orders []journal.Order
+	for _, o := range snap.DoneOrders {
+		if jo := journalOrder(o, time.Time{}); r.orders[jo.PermID] != jo {
+			r.orders[jo.PermID] = jo
+			jo.Time = snap.CurrentTime
+			orders = append(orders, jo)
+		}
+	}
+	if len(execs) == 0 && len(orders) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		added, err := j.PutExecutions(execs)
+		if err != nil {
+			return journalRecordedMsg{err: err}
+		}
+		if added > 0 {
+			slog.Info("Recorded executions in the trade journal", "added", added)
+		}
+		if err = j.PutOrders(orders); err != nil {
+			return journalRecordedMsg{err: err}
+		}
+		return journalRecordedMsg{execs: execs}
+	}
+}
+
+// Make a prompt callback that saves a trade's note text, or its tags.
+func (m *model) noteTrade(tr journal.Trade, tags bool) func(string) tea.Cmd {
+	return func(s string) tea.Cmd {
+		j := m.journal
+		if j == nil {
+			slog.Warn("Trade notes need the trade journal, which isn't open")
+			return nil
+		}
+		note := tr.Note
+		if tags {
+			note.Tags = nil
+			for tag := range strings.SplitSeq(s, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					note.Tags = append(note.Tags, tag)
+				}
+			}
+		} else {
+			note.Text = strings.TrimSpace(s)
+		}
+		return func() tea.Msg {
+			if err := j.SetNote(tr.ExecID, note); err != nil {
+				return journalMsg{err: err}
+			}
+			return readJournal(j)
+		}
+	}
+}
+
+// Keep the journal's trades for the Trade Log and tax lots.
+func (m *model) journalReady(msg journalMsg) {
+	if msg.err != nil {
+		slog.Error("Couldn't use the trade journal", "error", msg.err)
+		return
+	}
+	m.tradeHistory, m.lotPicks, m.book = msg.trades, msg.picks, nil
+}
+
+// Merge the executions recordJournal wrote into the journal's trades, keeping
+// their notes, rather than reading the whole journal again. If writing failed,
+// everything is written again next time.
+func (m *model) journalRecorded(msg journalRecordedMsg) {
+	if msg.err != nil {
+		slog.Error("Couldn't record in the trade journal", "error", msg.err)
+		m.recorded = journalRecord{}
+		return
+	}
+	if len(msg.execs) == 0 {
+		return
+	}
+	index := make(map[string]int, len(m.tradeHistory))
+	for i, t := range m.tradeHistory {
+		index[t.ExecID] = i
+	}
+	for _, e := range msg.execs {
+		if i, ok := index[e.ExecID]; ok {
+			m.tradeHistory[i].Execution = e
+			continue
+		}
+		m.tradeHistory = append(m.tradeHistory, journal.Trade{Execution: e})
+	}
+	slices.SortStableFunc(m.tradeHistory, func(a, b journal.Trade) int {
+		return a.Time.Compare(b.Time)
+	})
+	m.book = nil
+}
+
+// Log the outcome of requesting earlier executions. Their rows arrive through the feed.
+func (m *model) executionsReady(msg executionsReqMsg) {
+	if msg.err != nil {
+		slog.Error("Couldn't request earlier executions", "error", msg.err)
+		return
+	}
+	slog.Info("Requested executions", "range", m.tradeRange.String())
+}
+
+// Read every trade and lot pick from the journal. Blocks on disk.
+func readJournal(j *journal.Journal) journalMsg {
+	trades, err := j.Trades(time.Time{}, time.Time{})
+	if err != nil {
+		return journalMsg{err: err}
+	}
+	picks, err := j.LotPicks()
+	return journalMsg{trades: trades, picks: picks, err: err}
+}
+
+// The active account's journal trades and this session's executions within
+// the date range, newest first. An execution already journaled is shown once.
+func (m *model) tradeLogRows() []journal.Trade {
+	snap := m.ibs.Snapshot()
+	rows := make([]journal.Trade, 0, len(m.tradeHistory)+len(snap.Executions))
+	seen := make(map[string]bool)
+	for _, t := range m.tradeHistory {
+		if snap.InActiveAccount(t.Account) && m.tradeRange.contains(t.Time) {
+			rows = append(rows, t)
+			seen[t.ExecID] = true
+		}
+	}
+	for _, e := range snap.ActiveExecutions() {
+		if !seen[e.ExecID] && m.tradeRange.contains(e.Time) {
+			rows = append(rows, journal.Trade{Execution: journalExecution(e)})
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b journal.Trade) int {
+		return b.Time.Compare(a.Time)
+	})
+	return rows
+}
+
+// Render the Trade Log panel into a string for further Bubbletea rendering.
+func (m *model) renderTradeLogContent() string {
+	if m.timeline != nil && m.timeline.tab == trades {
+		return m.renderTimeline()
+	}
+	if m.showAnalytics {
+		return m.renderAnalytics()
+	}
+	title := ""
+	if m.selectedTab == trades {
+		title = "Range " + m.tradeRange.String() +
+			"  ↑↓ select  t timeline  n note  g tags  L pick lots  r range  a analytics  I import  E export\n"
+	}
+	rows := m.tradeLogRows()
+	if len(rows) == 0 {
+		return title + "No executions"
+	}
+	header := []string{" ", "Time", "Symbol", "Side", "Tags", "Note", "Qty", "Price", "Commission", "Realized P&L"}
+	table := make([][]string, 0, len(rows))
+	for i, e := range rows {
+		cursor := " "
+		if i == m.tradesCursor && m.selectedTab == trades {
+			cursor = "›"
+		}
+		table = append(table, []string{
+			cursor,
+			e.Time.Local().Format(tradeTimeLayout),
+			e.Symbol,
+			e.Side,
+			strings.Join(e.Tags, ","),
+			e.Text,
+			panels.FormatNumber(e.Quantity, -1),
+			panels.FormatNumber(e.Price, 2),
+			panels.FormatNumber(e.Commission, 2),
+			panels.FormatNumber(e.RealizedPnL, 2),
+		})
+	}
+	return title + panels.RenderTable(header, table, 6)
+}
+
+// Convert a streamed execution to its journal record.
+func journalExecution(e state.Execution) journal.Execution {
+	return journal.Execution{
+		ExecID:             e.ExecID,
+		OrderID:            e.OrderID,
+		PermID:             e.PermID,
+		Time:               e.Time,
+		Account:            e.Account,
+		ConID:              e.ConID,
+		Symbol:             e.Symbol,
+		SecType:            e.SecType,
+		Exchange:           e.Exchange,
+		Currency:           e.Currency,
+		Side:               e.Side,
+		Quantity:           e.Quantity,
+		Price:              e.Price,
+		Multiplier:         e.Multiplier,
+		Commission:         e.Commission,
+		CommissionCurrency: e.CommissionCurrency,
+		RealizedPnL:        e.RealizedPnL,
+	}
+}
+
+// Convert a finished order to its journal record, seen done at now.
+func journalOrder(o state.OpenOrder, now time.Time) journal.Order {
+	return journal.Order{
+		PermID:     o.PermID,
+		OrderID:    o.OrderID,
+		Account:    o.Account,
+		Symbol:     o.Symbol,
+		SecType:    o.SecType,
+		Side:       o.Side,
+		Quantity:   o.Quantity,
+		Type:       o.Type,
+		LimitPrice: o.LimitPrice,
+		AuxPrice:   o.AuxPrice,
+		TIF:        o.TIF,
+		Status:     o.Status,
+		Filled:     o.Filled,
+		Remaining:  o.Remaining,
+		Time:       now,
+	}
+}
+
+// Parse "YYYY-MM-DD" as that day onwards and "YYYY-MM-DD..YYYY-MM-DD" as both
+// days inclusive, in loc. An empty string is an open range.
+func parseDateRange(s string, loc *time.Location) (dateRange, error) {
+	var r dateRange
+	s = strings.TrimSpace(s)
+	if s == "" || s == "all" {
+		return r, nil
+	}
+	fromText, toText, hasTo := strings.Cut(s, "..")
+	var err error
+	if r.from, err = time.ParseInLocation(dateLayout, strings.TrimSpace(fromText), loc); err != nil {
+		return r, fmt.Errorf("couldn't parse date range %q: %w", s, errBadDateRange)
+	}
+	if !hasTo {
+		return r, nil
+	}
+	if r.to, err = time.ParseInLocation(dateLayout, strings.TrimSpace(toText), loc); err != nil {
+		return r, fmt.Errorf("couldn't parse date range %q: %w", s, errBadDateRange)
+	}
+	r.to = r.to.AddDate(0, 0, 1)
+	if !r.to.After(r.from) {
+		return r, fmt.Errorf("couldn't parse date range %q: %w", s, errBadDateRange)
+	}
+	return r, nil
+}
+
+// Report whether t falls within the range.
+func (r dateRange) contains(t time.Time) bool {
+	return (r.from.IsZero() || !t.Before(r.from)) && (r.to.IsZero() || t.Before(r.to))
+}
+
+// String formats the range the way parseDateRange reads it.
+func (r dateRange) String() string {
+	switch {
+	case r.from.IsZero():
+		return "all"
+	case r.to.IsZero():
+		return r.from.Format(dateLayout)
+	}
+	return r.from.Format(dateLayout) + ".." + r.to.AddDate(0, 0, -1).Format(dateLayout)
+}
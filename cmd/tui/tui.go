@@ -4,20 +4,26 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/glenntam/ibtui/internal/indicator"
+	"github.com/glenntam/ibtui/internal/journal"
+	"github.com/glenntam/ibtui/internal/lots"
 	"github.com/glenntam/ibtui/internal/panels"
 	"github.com/glenntam/ibtui/internal/state"
+	lists "github.com/glenntam/ibtui/internal/watchlist"
 	"github.com/scmhub/ibsync"
 	"golang.org/x/term"
 )
 
 const (
-	millisecondRefreshRate = 30
+	millisecondLogRefreshRate = 250
+	percent                   = 100
 
 	minTermWidth  = 48
 	minTermHeight = 22
@@ -32,18 +38,50 @@ const (
 	algos
 	logs
 	trades
+	charts // Last so adding it kept the other tabs' hotkeys
 )
 
-// Use this type to catch repeated refreshIBState messages in Update().
+// Use this type to catch repeated refreshLog messages in Update().
 type refreshMsg time.Time
 
 // model reflects the current state of the TUI app.
-// model.ibs reflects the state of the IB account via continued polling.
+// model.ibs reflects the state of the IB account as pushed by state.Feed.
 type model struct {
 	ib       *ibsync.IB
 	ibs      *state.IBState
+	feed     *state.Feed
 	timezone string
 
+	prompt *prompt
+
+	orderForm   orderForm
+	whatIfAbove float64 // Orders worth at least this are previewed by IB before placing
+
+	watchlists  *lists.Store
+	watchlist   []watchItem
+	watchCursor int
+
+	ordersCursor int
+	timeline     *orderTimeline   // Order detail shown in place of a table, nil if none
+	completed    *completedOrders // Shown in place of working orders, nil if not
+
+	journal       *journal.Journal // nil if it couldn't be opened
+	tradeHistory  []journal.Trade  // Every journal trade, oldest first
+	recorded      journalRecord    // What this session already wrote to the journal
+	tradesCursor  int
+	tradeRange    dateRange
+	showAnalytics bool // Trade Log shows performance analytics in place of executions
+
+	portfolioCursor int
+	lotMethod       lots.Method
+	lotPicks        map[string][]string // Opening exec IDs picked per closing exec ID
+	book            *lots.Book          // lotBook's last match, nil once anything it uses changed
+	expandedLots    map[lotKey]bool     // Portfolio rows showing their open lots
+	gainsYear       int                 // Year of the realized gains report shown, 0 if none
+
+	chart        chartView
+	chartStudies []indicator.Study // Studies a new chart view starts with
+
 	logFile   *os.File
 	logHeight int
 	logLines  []string
@@ -75,6 +113,10 @@ func (m *model) Init() tea.Cmd {
 	m.screenWidth = termWidth
 	m.screenHeight = termHeight
 
+	watchCmd := m.loadWatchlist()
+	m.orderForm = newOrderForm()
+	m.chart = newChartView(m.chartStudies)
+
 	// Initialize panels:
 	m.panels = append(m.panels, &panels.Panel{
 		Index: nofocus,
@@ -121,18 +163,64 @@ func (m *model) Init() tea.Cmd {
 		Content:  m.renderTradeLogContent(),
 		Revealed: false,
 	})
+	m.panels = append(m.panels, &panels.Panel{
+		Index:    charts,
+		Tab:      "8. Chart",
+		Content:  m.renderChartContent(),
+		Revealed: false,
+	})
 	m.prevSelectedTab = nofocus
 	m.selectedTab = nofocus
 	m.styling = panels.NewStyles()
 	slog.Info("TUI initializing")
-	return m.refreshIBState()
+	return tea.Batch(m.refreshLog(), watchCmd, m.loadTradeHistory())
 }
 
 // Update catches keypresses and screen updates then passes them to View().
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) { //nolint:ireturn
 	var err error
+	var cmd tea.Cmd
 	switch v := msg.(type) {
 	case tea.KeyMsg:
+		if m.prompt != nil {
+			return m, m.updatePrompt(v)
+		}
+		if m.timeline != nil && m.selectedTab == m.timeline.tab {
+			if tabCmd, ok := m.updateTimeline(v); ok {
+				return m, tabCmd
+			}
+		}
+		if m.selectedTab == portfolio {
+			if tabCmd, ok := m.updatePortfolio(v); ok {
+				return m, tabCmd
+			}
+		}
+		if m.selectedTab == watchlist {
+			if tabCmd, ok := m.updateWatchlist(v); ok {
+				return m, tabCmd
+			}
+		}
+		if m.selectedTab == quote {
+			if tabCmd, ok := m.updateOrderEntry(v); ok {
+				return m, tabCmd
+			}
+		}
+		if m.selectedTab == charts {
+			if tabCmd, ok := m.updateChart(v); ok {
+				return m, tabCmd
+			}
+		}
+		if m.selectedTab == orders {
+			if tabCmd, ok := m.updateOpenOrders(v); ok {
+				return m, tabCmd
+			}
+		}
+		if m.selectedTab == trades {
+			if tabCmd, ok := m.updateTradeLog(v); ok {
+				return m, tabCmd
+			}
+		}
+		m.prevSelectedTab = m.selectedTab
 		switch v.String() {
 		case "ctrl+c", "q":
 			return m, tea.Quit
@@ -158,15 +246,28 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) { //nolint:ireturn
 			} else {
 				m.selectedTab = quote
 				m.panels[quote].Revealed = true
+				m.panels[charts].Revealed = false
 				m.panels[orders].Revealed = false
 				m.panels[algos].Revealed = false
 			}
+		case strconv.Itoa(charts):
+			if m.selectedTab == charts {
+				m.selectedTab = nofocus
+			} else {
+				m.selectedTab = charts
+				m.panels[quote].Revealed = false
+				m.panels[charts].Revealed = true
+				m.panels[orders].Revealed = false
+				m.panels[algos].Revealed = false
+				cmd = m.followQuoteSymbol()
+			}
 		case strconv.Itoa(orders):
 			if m.selectedTab == orders {
 				m.selectedTab = nofocus
 			} else {
 				m.selectedTab = orders
 				m.panels[quote].Revealed = false
+				m.panels[charts].Revealed = false
 				m.panels[orders].Revealed = true
 				m.panels[algos].Revealed = false
 			}
@@ -176,6 +277,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) { //nolint:ireturn
 			} else {
 				m.selectedTab = algos
 				m.panels[quote].Revealed = false
+				m.panels[charts].Revealed = false
 				m.panels[orders].Revealed = false
 				m.panels[algos].Revealed = true
 			}
@@ -201,6 +303,7 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) { //nolint:ireturn
 			if err != nil {
 				slog.Error("Error getting previous newline", "error", err)
 			}
+			m.panels[logs].Content = m.renderLogContent()
 		case "down", "j":
 			m.logCursor, err = panels.NextNewline(m.logFile, m.logCursor)
 			if err != nil {
@@ -210,12 +313,20 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) { //nolint:ireturn
 			if err != nil {
 				slog.Error("Couldn't determine if cursor at end of file", "error", err)
 			}
+			m.panels[logs].Content = m.renderLogContent()
+		case "A":
+			m.ibs.CycleAccount()
+			slog.Info("Switched active account", "account", m.ibs.Snapshot().ActiveAccountLabel())
+			m.renderAll()
+		case "m":
+			cmd = m.cycleMarketDataType()
 		case "G":
 			m.logCursor, err = panels.GetFileSize(m.logFile)
 			if err != nil {
 				slog.Error("m.logCursor couldn't retrieve log file size", "error", err)
 			}
 			m.logFollow = true
+			m.panels[logs].Content = m.renderLogContent()
 		case "d":
 			slog.Debug("emit Debug")
 		case "i":
@@ -225,17 +336,95 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) { //nolint:ireturn
 		case "e":
 			slog.Error("emit Error")
 		}
-		return m, nil
+		// Only the panels losing and gaining focus show different help.
+		if m.selectedTab != m.prevSelectedTab {
+			m.renderPanel(m.prevSelectedTab)
+			m.renderPanel(m.selectedTab)
+		}
+		return m, cmd
 	case tea.WindowSizeMsg:
 		m.screenWidth = v.Width
 		m.screenHeight = v.Height
+		m.renderAll()
 		return m, nil
 	case refreshMsg:
-		return m, m.refreshIBState()
+		return m, m.refreshLog()
+	case state.ClockMsg:
+		m.panels[portfolio].Content = m.renderPorfolioContent()
+		if m.chart.stale {
+			m.drawChart()
+		}
+	case state.AccountsMsg, state.SummaryMsg, state.PortfolioMsg, state.PnLMsg:
+		m.panels[portfolio].Content = m.renderPorfolioContent()
+	case state.OrdersMsg:
+		open := m.ibs.Snapshot().ActiveOpenOrders()
+		m.ordersCursor = min(m.ordersCursor, max(len(open)-1, 0))
+		m.panels[orders].Content = m.renderOpenOrdersContent()
+		m.refreshTimeline()
+		return m, m.recordJournal()
+	case state.ExecutionsMsg:
+		m.book = nil
+		execs := m.tradeLogRows()
+		m.tradesCursor = min(m.tradesCursor, max(len(execs)-1, 0))
+		m.panels[trades].Content = m.renderTradeLogContent()
+		m.refreshTimeline()
+		return m, m.recordJournal()
+	case completedOrdersMsg:
+		m.completedOrdersReady(v)
+		m.panels[orders].Content = m.renderOpenOrdersContent()
+	case executionsReqMsg:
+		m.executionsReady(v)
+	case exportedMsg:
+		exported(v)
+	case journalMsg:
+		m.journalReady(v)
+		m.panels[trades].Content = m.renderTradeLogContent()
+		m.panels[portfolio].Content = m.renderPorfolioContent()
+	case journalRecordedMsg:
+		m.journalRecorded(v)
+		m.panels[trades].Content = m.renderTradeLogContent()
+		m.panels[portfolio].Content = m.renderPorfolioContent()
+	case state.QuoteMsg:
+		m.panels[watchlist].Content = m.renderWatchlistContent()
+		m.panels[quote].Content = m.renderOrderEntryContent()
+	case watchAddedMsg:
+		m.watchAdded(v)
+		m.panels[watchlist].Content = m.renderWatchlistContent()
+	case state.MarketDataTypeMsg:
+		m.panels[watchlist].Content = m.renderWatchlistContent()
+		m.panels[quote].Content = m.renderOrderEntryContent()
+	case orderQuoteMsg:
+		m.orderQuoteReady(v)
+		m.panels[quote].Content = m.renderOrderEntryContent()
+	case barsMsg:
+		cmd := m.barsReady(v)
+		m.panels[charts].Content = m.renderChartContent()
+		return m, cmd
+	case state.BarsMsg:
+		m.barsChanged()
+	case whatIfMsg:
+		m.whatIfReady(v)
+		m.panels[quote].Content = m.renderOrderEntryContent()
+	case orderPlacedMsg:
+		m.orderPlaced(v)
+		m.panels[quote].Content = m.renderOrderEntryContent()
 	}
 	return m, nil
 }
 
+// Switch to the next market data type in the background, since every open quote is re-requested.
+func (m *model) cycleMarketDataType() tea.Cmd {
+	next := m.ibs.Snapshot().MarketDataType.Next()
+	feed := m.feed
+	return func() tea.Msg {
+		if err := feed.SetMarketDataType(next); err != nil {
+			slog.Error("Couldn't switch every quote's market data type", "error", err)
+		}
+		slog.Info("Switched market data type", "type", next.String())
+		return state.MarketDataTypeMsg{}
+	}
+}
+
 // View gathers the TUI model state and renders the data to screen.
 func (m *model) View() string {
 	top := panels.RenderHorizontalGroup(
@@ -245,44 +434,109 @@ func (m *model) View() string {
 		m.screenWidth,
 	)
 	mid := panels.RenderHorizontalGroup(
-		m.panels[quote:logs],
+		append(slices.Clone(m.panels[quote:logs]), m.panels[charts]),
 		m.styling,
 		m.selectedTab,
 		m.screenWidth,
 	)
 	bot := panels.RenderHorizontalGroup(
-		m.panels[logs:],
+		m.panels[logs:charts],
 		m.styling,
 		m.selectedTab,
 		m.screenWidth,
 	)
-	status := panels.RenderStatusLine("STATUS LINE", m.styling)
+	status := panels.RenderStatusLine(m.renderStatus(), m.styling)
 
 	return lipgloss.JoinVertical(lipgloss.Left, top, mid, bot, status)
 }
 
-// Render the Portfolio panel into a string for further Bubbletea rendering.
-func (m *model) renderPorfolioContent() string {
+// Render the status line text shown below all panels, or the open prompt.
+func (m *model) renderStatus() string {
+	if m.prompt != nil {
+		return m.prompt.String()
+	}
+	snap := m.ibs.Snapshot()
+	pnl := snap.ActivePnL()
 	return fmt.Sprintf(
-		"%s (%v)",
-		m.ibs.CurrentTime.Format(time.StampMilli),
-		m.ibs.CurrentTime.Location(),
+		"Acct %s │ Data %s │ Daily P&L %s │ Unrlzd %s │ Rlzd %s",
+		snap.ActiveAccountLabel(),
+		snap.MarketDataType,
+		panels.FormatNumber(pnl.Daily, 2),
+		panels.FormatNumber(pnl.Unrealized, 2),
+		panels.FormatNumber(pnl.Realized, 2),
 	)
 }
 
-// Render the Watchlist panel into a string for further Bubbletea rendering.
-func (m *model) renderWatchlistContent() string {
-	return "renderWatchlistTab"
-}
+// Render the Portfolio panel into a string for further Bubbletea rendering.
+func (m *model) renderPorfolioContent() string {
+	snap := m.ibs.Snapshot()
+	clock := fmt.Sprintf(
+		"%s (%v)",
+		snap.CurrentTime.Format(time.StampMilli),
+		snap.CurrentTime.Location(),
+	)
+	summary := renderAccountSummary(snap.ActiveSummary())
+	if m.gainsYear != 0 {
+		return clock + "\n" + summary + "\n" + m.renderGainsReport(m.lotBook(), snap)
+	}
+	items := snap.ActivePortfolio()
+	if len(items) == 0 {
+		return clock + "\n" + summary + "\nNo open positions"
+	}
 
-// Render the Order Entry panel into a string for further Bubbletea rendering.
-func (m *model) renderOrderEntryContent() string {
-	return "renderOrderEntryTab"
+	// Only the aggregate view mixes accounts, so only it needs an account column.
+	showAccount := snap.ActiveAccount == state.AllAccounts && len(snap.Accounts) > 1
+	header := []string{"Symbol", "Type", "Qty", "Avg Cost", "Mkt Price", "Mkt Value", "Daily P&L", "Unrlzd P&L", "Rlzd P&L"}
+	leftCols := 3
+	if showAccount {
+		header = append([]string{"Account"}, header...)
+		leftCols++
+	}
+	header = append([]string{" "}, header...)
+	var book lots.Book
+	if len(m.expandedLots) > 0 {
+		book = m.lotBook()
+	}
+	rows := make([][]string, 0, len(items))
+	for i, p := range items {
+		cursor := " "
+		if i == m.portfolioCursor && m.selectedTab == portfolio {
+			cursor = "›"
+		}
+		row := []string{
+			p.Symbol,
+			p.SecType,
+			panels.FormatNumber(p.Position, -1),
+			panels.FormatNumber(p.AvgCost, 2),
+			panels.FormatNumber(p.MarketPrice, 2),
+			panels.FormatNumber(p.MarketValue, 2),
+			panels.FormatNumber(p.DailyPNL, 2),
+			panels.FormatNumber(p.UnrealizedPNL, 2),
+			panels.FormatNumber(p.RealizedPNL, 2),
+		}
+		if showAccount {
+			row = append([]string{p.Account}, row...)
+		}
+		rows = append(rows, append([]string{cursor}, row...))
+		if m.expandedLots[lotKey{p.Account, p.ConID}] {
+			rows = append(rows, renderLotRows(book.OpenLots(p.Account, p.ConID), snap.CurrentTime, showAccount)...)
+		}
+	}
+	return clock + "\n" + summary + "\n" + panels.RenderTable(header, rows, leftCols)
 }
 
-// Render the Open Orders panel into a string for further Bubbletea rendering.
-func (m *model) renderOpenOrdersContent() string {
-	return "renderOpenOrdersTab"
+// Render the account balances and margin figures shown above the positions table.
+func renderAccountSummary(a state.AccountSummary) string {
+	fields := []string{
+		fmt.Sprintf("NetLiq %s %s", panels.FormatNumber(a.NetLiquidation, 2), a.Currency),
+		"Cash " + panels.FormatNumber(a.TotalCashValue, 2),
+		"BuyPwr " + panels.FormatNumber(a.BuyingPower, 2),
+		"InitMgn " + panels.FormatNumber(a.InitMarginReq, 2),
+		"MaintMgn " + panels.FormatNumber(a.MaintMarginReq, 2),
+		"ExcessLiq " + panels.FormatNumber(a.ExcessLiquidity, 2),
+		"Cushion " + panels.FormatNumber(a.Cushion*percent, 1) + "%",
+	}
+	return strings.Join(fields, " │ ")
 }
 
 // Render the Algo panel into a string for further Bubbletea rendering.
@@ -316,44 +570,54 @@ func (m *model) renderLogContent() string {
 	return str
 }
 
-// Render the completed Trades panel into a string.
-func (m *model) renderTradeLogContent() string {
-	return "renderTradeLogTab"
+// Re-render every panel, e.g. after the screen was resized.
+func (m *model) renderAll() {
+	for tab := portfolio; tab <= charts; tab++ {
+		m.renderPanel(tab)
+	}
 }
 
-// Catchall function to gather IB account state, update
-// TUI model fields, and then set itself to repeat.
-func (m *model) refreshIBState() tea.Cmd {
-	// Portfolio tab:
-	err := m.ibs.ReqCurrentTimeMilli(m.ib)
-	if err != nil {
-		slog.Error("Couldn't get time from IB API", "error", err)
+// Re-render one panel by its tab, or nothing for nofocus.
+func (m *model) renderPanel(tab int) {
+	switch tab {
+	case portfolio:
+		m.panels[portfolio].Content = m.renderPorfolioContent()
+	case watchlist:
+		m.panels[watchlist].Content = m.renderWatchlistContent()
+	case quote:
+		m.panels[quote].Content = m.renderOrderEntryContent()
+	case charts:
+		m.panels[charts].Content = m.renderChartContent()
+	case orders:
+		m.panels[orders].Content = m.renderOpenOrdersContent()
+	case algos:
+		m.panels[algos].Content = m.renderAlgoContent()
+	case logs:
+		m.panels[logs].Content = m.renderLogContent()
+	case trades:
+		m.panels[trades].Content = m.renderTradeLogContent()
 	}
+}
 
-	// Log tab:
+// Follow the log file as it grows, re-rendering the Log panel only when
+// there is something new, and then set itself to repeat.
+func (m *model) refreshLog() tea.Cmd {
+	size, err := panels.GetFileSize(m.logFile)
+	if err != nil {
+		slog.Error("Couldn't retrieve log file size", "error", err)
+	}
 	if m.logFollow {
-		m.logCursor, err = panels.GetFileSize(m.logFile)
-		if err != nil {
-			slog.Error("m.logCursor couldn't retrieve file size", "error", err)
-		}
 		m.panels[logs].Tab = "6. Log "
+		if size != m.logCursor {
+			m.logCursor = size
+			m.panels[logs].Content = m.renderLogContent()
+		}
 	} else {
 		m.panels[logs].Tab = "6. Log*"
 	}
 
-	// Render All tabs:
-	m.panels[portfolio].Content = m.renderPorfolioContent()
-	m.panels[watchlist].Content = m.renderWatchlistContent()
-	m.panels[quote].Content = m.renderOrderEntryContent()
-	m.panels[orders].Content = m.renderOpenOrdersContent()
-	m.panels[algos].Content = m.renderAlgoContent()
-	m.panels[logs].Content = m.renderLogContent()
-	m.panels[trades].Content = m.renderTradeLogContent()
-
 	// Re-run timer:
-	return tea.Batch(
-		tea.Tick(millisecondRefreshRate*time.Millisecond, func(t time.Time) tea.Msg {
-			return refreshMsg(t)
-		}),
-	)
+	return tea.Tick(millisecondLogRefreshRate*time.Millisecond, func(t time.Time) tea.Msg {
+		return refreshMsg(t)
+	})
 }
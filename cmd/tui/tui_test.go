@@ -1,9 +1,28 @@
 package main
 
-import "testing"
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/contract"
+	"github.com/glenntam/ibtui/internal/indicator"
+	"github.com/glenntam/ibtui/internal/journal"
+	"github.com/glenntam/ibtui/internal/lots"
+	"github.com/glenntam/ibtui/internal/order"
+	"github.com/glenntam/ibtui/internal/panels"
+	"github.com/glenntam/ibtui/internal/state"
+	lists "github.com/glenntam/ibtui/internal/watchlist"
+)
 
 func TestRenderTabStrings(t *testing.T) {
-	m := &model{}
+	m := &model{ibs: state.NewIBState()}
 	// Call render functions that should return fixed strings
 	if s := m.renderWatchlistContent(); s == "" {
 		t.Fatalf("renderWatchlistContent returned empty string")
@@ -18,3 +37,508 @@ func TestRenderTabStrings(t *testing.T) {
 		t.Fatalf("renderAlgoContent returned empty string")
 	}
 }
+
+func TestRenderPnL(t *testing.T) {
+	m := &model{ibs: state.NewIBState()}
+	m.ibs.SetAccounts([]string{"U1", "U2"})
+	m.ibs.SetPortfolio([]state.PortfolioItem{{Account: "U1", ConID: 265598, Symbol: "AAPL", SecType: "STK"}})
+	m.ibs.SetPnL("U1", 0, state.PnL{Daily: 100, Unrealized: 40, Realized: -5})
+	m.ibs.SetPnL("U2", 0, state.PnL{Daily: 50, Unrealized: 10})
+	m.ibs.SetPnL("U1", 265598, state.PnL{Daily: 12.5, Unrealized: 40})
+	if s := m.renderStatus(); !strings.Contains(s, "Daily P&L 150.00 │ Unrlzd 50.00 │ Rlzd -5.00") {
+		t.Fatalf("expected the P&L of every account summed in the status line got %q", s)
+	}
+	lines := strings.Split(m.renderPorfolioContent(), "\n")
+	var header, aapl string
+	for _, l := range lines {
+		if strings.Contains(l, "Daily P&L") {
+			header = l
+		}
+		if strings.Contains(l, "AAPL") {
+			aapl = l
+		}
+	}
+	if header == "" || !strings.Contains(aapl, "12.50") {
+		t.Fatalf("expected a Daily P&L column with AAPL's streamed P&L got %q", lines)
+	}
+}
+
+func TestUpdate_rendersFocusedPanels(t *testing.T) {
+	m := &model{ibs: state.NewIBState(), chart: newChartView(nil)}
+	for tab := nofocus; tab <= charts; tab++ {
+		m.panels = append(m.panels, &panels.Panel{Index: tab})
+	}
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(strconv.Itoa(charts))})
+	if !strings.Contains(m.panels[charts].Content, "s symbol") {
+		t.Fatalf("expected the focused chart rendered with its help got %q", m.panels[charts].Content)
+	}
+	if m.panels[trades].Content != "" || m.panels[portfolio].Content != "" {
+		t.Fatalf("expected panels the key didn't change left alone")
+	}
+}
+
+func TestRenderWatchlistContent(t *testing.T) {
+	store, err := lists.Load(filepath.Join(t.TempDir(), "watchlists.json"))
+	if err != nil {
+		t.Fatalf("couldn't load watchlists: %v", err)
+	}
+	m := &model{ibs: state.NewIBState(), watchlists: store, selectedTab: watchlist}
+	if s := m.renderWatchlistContent(); !strings.Contains(s, "empty") || !strings.HasPrefix(s, "default (1/1)") {
+		t.Fatalf("expected empty watchlist hint got %q", s)
+	}
+
+	m.watchlist = []watchItem{
+		{spec: contract.Spec{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"}, conID: 265598},
+		{spec: contract.Spec{Symbol: "MSFT", SecType: "STK", Exchange: "SMART", Currency: "USD"}, conID: 272093},
+	}
+	m.ibs.SetQuote(state.Quote{ConID: 265598, Bid: 189.5, Ask: 189.52, Last: 189.51, Volume: 1200300})
+	lines := strings.Split(m.renderWatchlistContent(), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected title, header and 2 rows got %d lines", len(lines))
+	}
+	if !strings.HasPrefix(lines[2], "›") ||
+		!strings.Contains(lines[2], "189.52") || !strings.Contains(lines[2], "1,200,300") {
+		t.Fatalf("expected selected AAPL row with its quote got %q", lines[2])
+	}
+	if strings.TrimSpace(lines[3]) != "MSFT" {
+		t.Fatalf("expected MSFT row without a quote yet got %q", lines[3])
+	}
+}
+
+func TestOrderTicket(t *testing.T) {
+	m := &model{ibs: state.NewIBState(), orderForm: newOrderForm()}
+	m.ibs.SetAccounts([]string{"U1", "U2"})
+	m.orderForm.spec = contract.Spec{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"}
+	m.orderForm.quantity = "100"
+	m.orderForm.limit = "189.50"
+	if _, err := m.orderTicket(); !errors.Is(err, errNoAccount) {
+		t.Fatalf("expected errNoAccount with several accounts got %v", err)
+	}
+
+	m.ibs.CycleAccount() // U1
+	ticket, err := m.orderTicket()
+	if err != nil {
+		t.Fatalf("orderTicket returned unexpected error: %v", err)
+	}
+	if ticket.Account != "U1" || ticket.Quantity != 100 || ticket.LimitPrice != 189.5 {
+		t.Fatalf("expected 100 @ 189.5 in U1 got %+v", ticket)
+	}
+
+	m.orderForm.limit = ""
+	if _, err = m.orderTicket(); !errors.Is(err, order.ErrBadPrice) {
+		t.Fatalf("expected ErrBadPrice for a limit order without a price got %v", err)
+	}
+}
+
+func TestOrderFormFields(t *testing.T) {
+	f := newOrderForm()
+	f.orderType = order.MarketOnClose
+	fields := f.fields()
+	if slices.Contains(fields, fieldTIF) || slices.Contains(fields, fieldLimit) ||
+		slices.Contains(fields, fieldOutsideRTH) {
+		t.Fatalf("expected MOC to hide TIF, limit and outside RTH got %v", fields)
+	}
+
+	f.orderType = order.TrailLimit
+	f.tif = order.GTD
+	fields = f.fields()
+	for _, want := range []formField{fieldTrail, fieldLimitOffset, fieldGoodTill, fieldOutsideRTH} {
+		if !slices.Contains(fields, want) {
+			t.Fatalf("expected TRAIL LIMIT GTD to show field %v got %v", want, fields)
+		}
+	}
+
+	// Switching to a market order hides the limit the cursor was on.
+	f.orderType, f.cursor = order.Market, fieldLimit
+	if f.move(1); f.cursor != fieldTIF {
+		t.Fatalf("expected moving down from a hidden limit to land on TIF got %v", f.cursor)
+	}
+	f.cursor = fieldLimit
+	if f.move(-1); f.cursor != fieldType {
+		t.Fatalf("expected moving up from a hidden limit to land on the order type got %v", f.cursor)
+	}
+}
+
+func TestNeedsWhatIf(t *testing.T) {
+	m := &model{ibs: state.NewIBState(), whatIfAbove: 10_000}
+	lmt := order.Ticket{Quantity: 100, Type: order.Limit, LimitPrice: 99}
+	if m.needsWhatIf(lmt) {
+		t.Fatalf("expected a 9,900 order under the 10,000 threshold to skip the preview")
+	}
+	lmt.Quantity = 200
+	if !m.needsWhatIf(lmt) {
+		t.Fatalf("expected a 19,800 order over the threshold to be previewed")
+	}
+	if !m.needsWhatIf(order.Ticket{Quantity: 1, Type: order.Market}) {
+		t.Fatalf("expected a market order without a quote to be previewed")
+	}
+
+	m.orderForm.conID = 265598
+	m.ibs.SetQuote(state.Quote{ConID: 265598, Last: 50})
+	if m.needsWhatIf(order.Ticket{Quantity: 100, Type: order.Market}) {
+		t.Fatalf("expected a 5,000 market order valued at the last price to skip the preview")
+	}
+	m.ibs.SetQuote(state.Quote{ConID: 265598, Last: 50, Multiplier: 100})
+	if !m.needsWhatIf(order.Ticket{Quantity: 2, Type: order.Market}) {
+		t.Fatalf("expected 2 contracts of 100 at 50 to be valued at 10,000 and previewed")
+	}
+}
+
+func TestPendingOrderTickets(t *testing.T) {
+	entry := order.Ticket{Side: order.Buy, Quantity: 100, Type: order.Limit, LimitPrice: 99}
+	p := pendingOrder{
+		ticket:    entry,
+		bracket:   order.Bracket{Entry: entry, TakeProfit: 110, StopLoss: 90},
+		isBracket: true,
+	}
+	if got := len(p.tickets()); got != 3 {
+		t.Fatalf("expected a bracket to preview its entry and both exits got %d orders", got)
+	}
+	p.modifyID = 7
+	if got := len(p.tickets()); got != 1 {
+		t.Fatalf("expected a modification to preview one order got %d", got)
+	}
+}
+
+func TestRenderOpenOrdersContent(t *testing.T) {
+	m := &model{ibs: state.NewIBState(), selectedTab: orders}
+	m.ibs.SetOpenOrders([]state.OpenOrder{
+		{OrderID: 1, Symbol: "AAPL", Side: "BUY", Quantity: 100, Type: "LMT", LimitPrice: 189.5, Status: "Submitted"},
+		{OrderID: 2, ParentID: 1, Symbol: "AAPL", Side: "SELL", Quantity: 100, Type: "STP", AuxPrice: 180,
+			Status: "PreSubmitted", WhyHeld: "child"},
+	})
+	m.ordersCursor = 1
+	lines := strings.Split(m.renderOpenOrdersContent(), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header, 2 rows and help got %d lines", len(lines))
+	}
+	if strings.HasPrefix(lines[1], "›") || !strings.HasPrefix(lines[2], "›") {
+		t.Fatalf("expected the cursor on the second row got %q", lines[1:3])
+	}
+	if !strings.Contains(lines[2], "└ 2") || !strings.Contains(lines[2], "child") {
+		t.Fatalf("expected the stop nested under its entry with why held got %q", lines[2])
+	}
+}
+
+func TestRenderCompletedOrders(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	m := &model{ibs: state.NewIBState(), selectedTab: orders, completed: &completedOrders{loading: true}}
+	if !strings.Contains(m.renderOpenOrdersContent(), "Requesting") {
+		t.Fatalf("expected a loading note before IB answers")
+	}
+	m.completedOrdersReady(completedOrdersMsg{orders: []state.CompletedOrder{
+		{OpenOrder: state.OpenOrder{PermID: 12, Symbol: "AAPL", Side: "BUY", Quantity: 100, Type: "LMT",
+			LimitPrice: 189.5, Status: "Cancelled", Filled: 40}, AvgPrice: 189.4, Time: t0, Reason: "Cancelled by Trader"},
+		{OpenOrder: state.OpenOrder{PermID: 11, Symbol: "MSFT", Side: "SELL", Quantity: 10, Type: "MKT",
+			Status: "Filled", Filled: 10}, AvgPrice: 410, Time: t0.Add(-time.Hour)},
+	}})
+	lines := strings.Split(m.renderOpenOrdersContent(), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[2], "›") {
+		t.Fatalf("expected title, header and 2 rows with the cursor on the newest got %q", lines)
+	}
+	if !strings.Contains(lines[2], "Cancelled by Trader") || !strings.Contains(lines[2], "189.40") {
+		t.Fatalf("expected the cancel reason and average fill price got %q", lines[2])
+	}
+	for i := nofocus; i <= charts; i++ {
+		m.panels = append(m.panels, &panels.Panel{Index: i})
+	}
+	if _, ok := m.updateOpenOrders(tea.KeyMsg{Type: tea.KeyEsc}); !ok || m.completed != nil {
+		t.Fatalf("expected esc to go back to working orders")
+	}
+}
+
+func TestModifyOpenOrder(t *testing.T) {
+	m := &model{ibs: state.NewIBState(), orderForm: newOrderForm()}
+	for i := nofocus; i <= charts; i++ {
+		m.panels = append(m.panels, &panels.Panel{Index: i})
+	}
+	_ = m.modifyOpenOrder(state.OpenOrder{
+		OrderID: 7, Account: "U1", Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD",
+		Side: "SELL", Quantity: 50, Type: "TRAIL", TrailPercent: 1.5, TIF: "GTC",
+	})
+	f := m.orderForm
+	if f.modifyID != 7 || f.side != order.Sell || f.quantity != "50" || f.trail != "1.5%" || f.tif != order.GTC {
+		t.Fatalf("expected order 7 loaded into the form got %+v", f)
+	}
+	if m.selectedTab != quote || slices.Contains(f.fields(), fieldTakeProfit) {
+		t.Fatalf("expected the order entry tab without bracket fields got tab %d fields %v", m.selectedTab, f.fields())
+	}
+}
+
+func TestRenderTimeline(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	m := &model{ibs: state.NewIBState(), timeline: &orderTimeline{orderID: 5, tab: orders, events: []state.OrderEvent{
+		{Time: t0, Status: "Submitted"},
+		{Time: t0.Add(time.Second), Status: "Fill", FillQty: 40, FillPrice: 189.5},
+		{Time: t0.Add(2 * time.Second), Status: "Cancelled", Message: "Order canceled", ErrorCode: 202},
+	}}}
+	lines := strings.Split(m.renderOpenOrdersContent(), "\n")
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "Order #5 timeline") {
+		t.Fatalf("expected title, header and 3 events got %q", lines)
+	}
+	if !strings.Contains(lines[3], "189.50") || !strings.Contains(lines[4], "202") {
+		t.Fatalf("expected the fill price and the error code got %q", lines[3:])
+	}
+}
+
+func TestJournalFills(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	m := &model{ibs: state.NewIBState(), tradeHistory: []journal.Trade{
+		{Execution: journal.Execution{PermID: 9, Time: t0.Add(time.Minute), Quantity: 60, Price: 190}},
+		{Execution: journal.Execution{PermID: 8, Time: t0, Quantity: 10, Price: 50}},
+		{Execution: journal.Execution{PermID: 9, Time: t0, Quantity: 40, Price: 189.5}},
+	}}
+	fills := m.journalFills(9)
+	if len(fills) != 2 || fills[0].FillQty != 40 || fills[1].FillQty != 60 {
+		t.Fatalf("expected order 9's two fills oldest first got %+v", fills)
+	}
+	m.timeline = &orderTimeline{permID: 9, tab: trades, events: fills, journaled: true}
+	if got := m.renderTradeLogContent(); !strings.Contains(got, "only its fills were journaled") {
+		t.Fatalf("expected the timeline to say it only has journaled fills got %q", got)
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	r, err := parseDateRange("2026-03-02..2026-03-04", time.UTC)
+	if err != nil {
+		t.Fatalf("parseDateRange returned unexpected error: %v", err)
+	}
+	if !r.contains(time.Date(2026, 3, 4, 23, 59, 0, 0, time.UTC)) ||
+		r.contains(time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)) ||
+		r.contains(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected both days to be included and no others got %+v", r)
+	}
+	if r.String() != "2026-03-02..2026-03-04" {
+		t.Fatalf("expected the range to format as typed got %q", r.String())
+	}
+	if r, _ = parseDateRange("", time.UTC); !r.contains(time.Time{}) || r.String() != "all" {
+		t.Fatalf("expected an empty range to be open got %+v", r)
+	}
+	for _, s := range []string{"yesterday", "2026-03-04..2026-03-02", "2026-03-02..soon"} {
+		if _, err = parseDateRange(s, time.UTC); !errors.Is(err, errBadDateRange) {
+			t.Fatalf("expected errBadDateRange for %q got %v", s, err)
+		}
+	}
+}
+
+func TestRenderTradeLogContent(t *testing.T) {
+	m := &model{ibs: state.NewIBState(), selectedTab: trades}
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.Local)
+	m.ibs.SetExecutions([]state.Execution{
+		{ExecID: "e2", Time: t0.AddDate(0, 0, 1), Symbol: "MSFT", Side: "SLD", Quantity: 10, Price: 410},
+		{ExecID: "e1", Time: t0, Symbol: "AAPL", Side: "BOT", Quantity: 40, Price: 189.5, Commission: 1.2},
+	})
+	m.tradeRange, _ = parseDateRange("2026-03-02..2026-03-02", time.Local)
+	lines := strings.Split(m.renderTradeLogContent(), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected title, header and 1 row got %q", lines)
+	}
+	if !strings.HasPrefix(lines[2], "›") || !strings.Contains(lines[2], "AAPL") || !strings.Contains(lines[2], "1.20") {
+		t.Fatalf("expected the selected AAPL fill with its commission got %q", lines[2])
+	}
+}
+
+func TestTradeLogRows(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	m := &model{ibs: state.NewIBState()}
+	m.tradeHistory = []journal.Trade{
+		{Execution: journal.Execution{ExecID: "old", Time: t0.AddDate(0, -1, 0)}},
+		{Execution: journal.Execution{ExecID: "e1", Time: t0}, Note: journal.Note{Text: "breakout"}},
+	}
+	m.ibs.SetExecutions([]state.Execution{{ExecID: "e2", Time: t0.Add(time.Hour)}, {ExecID: "e1", Time: t0}})
+	rows := m.tradeLogRows()
+	if len(rows) != 3 || rows[0].ExecID != "e2" || rows[1].Text != "breakout" || rows[2].ExecID != "old" {
+		t.Fatalf("expected journal and live executions merged newest first got %+v", rows)
+	}
+}
+
+func TestRecordJournal_only_changes(t *testing.T) {
+	j, err := journal.Open(filepath.Join(t.TempDir(), "journal.db"))
+	if err != nil {
+		t.Fatalf("expected to open a journal got %v", err)
+	}
+	t.Cleanup(func() { _ = j.Close() })
+	m := &model{ibs: state.NewIBState(), journal: j}
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	m.ibs.SetExecutions([]state.Execution{{ExecID: "e1", Time: t0, Quantity: 40, Price: 189.5}})
+	cmd := m.recordJournal()
+	if cmd == nil {
+		t.Fatalf("expected a new execution to be written")
+	}
+	msg, ok := cmd().(journalRecordedMsg)
+	if !ok || msg.err != nil || len(msg.execs) != 1 {
+		t.Fatalf("expected the execution recorded got %+v", msg)
+	}
+	m.journalRecorded(msg)
+	if len(m.tradeHistory) != 1 || m.tradeHistory[0].ExecID != "e1" {
+		t.Fatalf("expected the recorded execution merged into the trade history got %+v", m.tradeHistory)
+	}
+	if m.recordJournal() != nil {
+		t.Fatalf("expected nothing written when nothing changed")
+	}
+	m.ibs.SetExecutions([]state.Execution{{ExecID: "e1", Time: t0, Quantity: 40, Price: 189.5, Commission: 1}})
+	if m.recordJournal() == nil {
+		t.Fatalf("expected an execution whose commission arrived to be written again")
+	}
+}
+
+func TestLotBook_cached(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	m := &model{ibs: state.NewIBState()}
+	buy := journal.Execution{ExecID: "b1", Account: "U1", ConID: 1, Symbol: "AAPL", Side: "BOT", Quantity: 10, Time: t0}
+	m.journalReady(journalMsg{trades: []journal.Trade{{Execution: buy}}})
+	if got := len(m.lotBook().Open); got != 1 {
+		t.Fatalf("expected 1 open lot got %d", got)
+	}
+	m.tradeHistory = nil
+	if got := len(m.lotBook().Open); got != 1 {
+		t.Fatalf("expected the cached book until the journal changes got %d open lots", got)
+	}
+	sell := buy
+	sell.ExecID, sell.Side, sell.Time = "s1", "SLD", t0.Add(time.Hour)
+	m.journalRecorded(journalRecordedMsg{execs: []journal.Execution{buy, sell}})
+	if book := m.lotBook(); len(book.Open) != 0 || len(book.Realized) != 1 {
+		t.Fatalf("expected the book rebuilt after a journal write got %+v", book)
+	}
+}
+
+func TestRenderPortfolioLots(t *testing.T) {
+	m := &model{ibs: state.NewIBState(), selectedTab: portfolio}
+	m.ibs.SetPortfolio([]state.PortfolioItem{
+		{Account: "U1", ConID: 265598, Symbol: "AAPL", SecType: "STK", Position: 150},
+	})
+	m.tradeHistory = []journal.Trade{
+		{Execution: journal.Execution{ExecID: "b1", Account: "U1", ConID: 265598, Symbol: "AAPL", Side: "BOT",
+			Quantity: 100, Price: 10, Time: time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)}},
+		{Execution: journal.Execution{ExecID: "b2", Account: "U1", ConID: 265598, Symbol: "AAPL", Side: "BOT",
+			Quantity: 50, Price: 30, Time: time.Now().Add(-time.Hour)}},
+	}
+	if s := m.renderPorfolioContent(); strings.Contains(s, "└") {
+		t.Fatalf("expected lots hidden until the row is expanded got %q", s)
+	}
+	m.toggleLots(lotKey{"U1", 265598})
+	lines := strings.Split(m.renderPorfolioContent(), "\n")
+	lotLines := slices.DeleteFunc(lines, func(l string) bool { return !strings.Contains(l, "└") })
+	if len(lotLines) != 2 || !strings.Contains(lotLines[0], "2024-01-02") || !strings.Contains(lotLines[0], "LT") ||
+		!strings.Contains(lotLines[1], "ST") {
+		t.Fatalf("expected a long-term and a short-term lot under AAPL got %q", lotLines)
+	}
+}
+
+func TestRenderAnalytics(t *testing.T) {
+	m := &model{ibs: state.NewIBState(), selectedTab: trades, showAnalytics: true, lotMethod: lots.FIFO}
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 15, 0, 0, 0, time.UTC) }
+	m.tradeHistory = []journal.Trade{
+		{Execution: journal.Execution{ExecID: "b1", Account: "U1", ConID: 265598, Symbol: "AAPL", Side: "BOT",
+			Quantity: 100, Price: 10, Time: day(2)}},
+		{Execution: journal.Execution{ExecID: "s1", Account: "U1", ConID: 265598, Symbol: "AAPL", Side: "SLD",
+			Quantity: 50, Price: 12, Time: day(3)}, Note: journal.Note{Tags: []string{"momentum"}}},
+		{Execution: journal.Execution{ExecID: "s2", Account: "U1", ConID: 265598, Symbol: "AAPL", Side: "SLD",
+			Quantity: 50, Price: 9, Time: day(4)}},
+	}
+	lines := strings.Split(m.renderTradeLogContent(), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[0], "Max drawdown 50.00") {
+		t.Fatalf("expected title, header, All, AAPL and #momentum got %q", lines)
+	}
+	if !strings.HasPrefix(lines[2], "All") || !strings.Contains(lines[2], "50.0%") || !strings.Contains(lines[2], "2.00") {
+		t.Fatalf("expected one win in two and a profit factor of 2 got %q", lines[2])
+	}
+	if !strings.HasPrefix(lines[4], "#momentum") || !strings.Contains(lines[4], "∞") {
+		t.Fatalf("expected the momentum tag to have only a win got %q", lines[4])
+	}
+}
+
+func TestBarsChanged(t *testing.T) {
+	m := &model{ibs: state.NewIBState(), chart: newChartView(nil)}
+	for tab := nofocus; tab <= charts; tab++ {
+		m.panels = append(m.panels, &panels.Panel{Index: tab})
+	}
+	m.Update(state.BarsMsg{})
+	if m.panels[charts].Content == "" || m.chart.stale {
+		t.Fatalf("expected the first bars drawn straight away")
+	}
+	m.panels[charts].Content = ""
+	m.Update(state.BarsMsg{})
+	if m.panels[charts].Content != "" || !m.chart.stale {
+		t.Fatalf("expected bars within a second left for the clock")
+	}
+	m.Update(state.ClockMsg{})
+	if m.panels[charts].Content == "" || m.chart.stale {
+		t.Fatalf("expected the clock to draw the stale chart")
+	}
+}
+
+func TestRenderChartContent(t *testing.T) {
+	studies, err := indicator.ParseStudies("sma2 rsi2 macd2,3,2")
+	if err != nil {
+		t.Fatalf("ParseStudies returned unexpected error: %v", err)
+	}
+	m := &model{ibs: state.NewIBState(), selectedTab: charts, chart: newChartView(studies),
+		screenWidth: 80, screenHeight: 30}
+	if s := m.renderChartContent(); !strings.HasPrefix(s, "No symbol") {
+		t.Fatalf("expected a hint to pick a symbol got %q", s)
+	}
+	m.chart.Spec = contract.Spec{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"}
+	other := m.chart.ChartKey
+	other.Timeframe = state.OneMinute
+	m.ibs.SetChart(other)
+	m.ibs.AddBars(other, state.Bar{Time: time.Now(), Open: 1, High: 1, Low: 1, Close: 1})
+	if s := m.renderChartContent(); !strings.HasSuffix(s, "Waiting for bars…") {
+		t.Fatalf("expected another timeframe's bars left out got %q", s)
+	}
+
+	m.ibs.SetChart(m.chart.ChartKey)
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	for i, c := range []float64{10, 11, 10.5, 12} {
+		m.ibs.AddBars(m.chart.ChartKey, state.Bar{Time: t0.Add(time.Duration(i) * 5 * time.Minute),
+			Open: c, High: c + 1, Low: c - 1, Close: c, Volume: 100})
+	}
+	lines := strings.Split(m.renderChartContent(), "\n")
+	if !strings.HasPrefix(lines[0], "AAPL") || !strings.Contains(lines[0], "5m  5 D") {
+		t.Fatalf("expected the symbol, timeframe and duration in the title got %q", lines[0])
+	}
+	if len(lines) != 1+15 {
+		t.Fatalf("expected a title and a chart half the screen high got %d lines", len(lines))
+	}
+	if lines[1] != "SMA2 11.25" {
+		t.Fatalf("expected the SMA legend with its latest value got %q", lines[1])
+	}
+	var legends []string
+	for _, l := range lines {
+		if strings.HasPrefix(l, "RSI2") || strings.HasPrefix(l, "MACD2,3,2") {
+			legends = append(legends, l)
+		}
+	}
+	if len(legends) != 2 || !strings.Contains(legends[1], "Signal") || !strings.Contains(legends[1], "Hist") {
+		t.Fatalf("expected an RSI and a MACD pane got %q", legends)
+	}
+
+	if cmd := m.barsReady(barsMsg{key: other}); cmd != nil {
+		t.Fatalf("expected no restart for a stream that didn't replace the current one")
+	}
+	m.ibs.SetChart(other)
+	if cmd := m.barsReady(barsMsg{key: other}); cmd == nil {
+		t.Fatalf("expected the current chart restarted after another's stream replaced it")
+	}
+}
+
+func TestRefreshLog_tab(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "log")
+	if err != nil {
+		t.Fatalf("couldn't create log file: %v", err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	m := &model{logFile: f, logFollow: true, logHeight: 3, screenWidth: 80}
+	for tab := nofocus; tab <= charts; tab++ {
+		m.panels = append(m.panels, &panels.Panel{Index: tab})
+	}
+	_ = m.refreshLog()
+	if tab := m.panels[logs].Tab; tab != strconv.Itoa(logs)+". Log " {
+		t.Fatalf("expected the Log tab to keep its hotkey %d got %q", logs, tab)
+	}
+	m.logFollow = false
+	_ = m.refreshLog()
+	if tab := m.panels[logs].Tab; tab != strconv.Itoa(logs)+". Log*" {
+		t.Fatalf("expected the Log tab marked when not following got %q", tab)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/glenntam/ibtui/internal/contract"
+	"github.com/glenntam/ibtui/internal/panels"
+	lists "github.com/glenntam/ibtui/internal/watchlist"
+)
+
+// watchItem is one row of the active watchlist. conID stays 0 until IB resolves the spec.
+type watchItem struct {
+	spec  contract.Spec
+	conID int64
+}
+
+// watchAddedMsg reports the outcome of resolving and subscribing a watchlist symbol.
+// typed is set when the user just added the symbol, rather than it being loaded from disk.
+type watchAddedMsg struct {
+	spec  contract.Spec
+	conID int64
+	typed bool
+	err   error
+}
+
+// Handle keys while the Watchlist panel is selected. Reports whether the key was used.
+func (m *model) updateWatchlist(msg tea.KeyMsg) (tea.Cmd, bool) {
+	var cmd tea.Cmd
+	switch msg.String() {
+	case "up", "k":
+		m.watchCursor = max(m.watchCursor-1, 0)
+	case "down", "j":
+		m.watchCursor = min(m.watchCursor+1, max(len(m.watchlist)-1, 0))
+	case "a":
+		m.openPrompt("Add symbol (SYMBOL[:SECTYPE[:EXCHANGE[:CURRENCY]]]):", "", m.addWatchItem)
+	case "d", "x", "delete":
+		m.removeWatchItem()
+	case "[":
+		cmd = m.switchWatchlist(-1)
+	case "]":
+		cmd = m.switchWatchlist(1)
+	case "n":
+		m.openPrompt("New watchlist name:", "", m.createWatchlist)
+	case "I":
+		m.openPrompt("Import CSV into "+m.watchlists.Active+" from:", "", m.importWatchlist)
+	case "E":
+		m.openPrompt("Export "+m.watchlists.Active+" to CSV:", m.watchlists.Active+".csv", m.exportWatchlist)
+	default:
+		return nil, false
+	}
+	m.panels[watchlist].Content = m.renderWatchlistContent()
+	return cmd, true
+}
+
+// Fill the rows from the active watchlist and subscribe to all of their quotes.
+func (m *model) loadWatchlist() tea.Cmd {
+	specs := m.watchlists.Specs()
+	m.watchlist = make([]watchItem, 0, len(specs))
+	m.watchCursor = 0
+	cmds := make([]tea.Cmd, 0, len(specs))
+	for _, spec := range specs {
+		m.watchlist = append(m.watchlist, watchItem{spec: spec})
+		cmds = append(cmds, m.reqWatchQuote(spec, false))
+	}
+	return tea.Batch(cmds...)
+}
+
+// Subscribe to a watchlist symbol's quote in the background.
+func (m *model) reqWatchQuote(spec contract.Spec, typed bool) tea.Cmd {
+	feed := m.feed
+	return func() tea.Msg {
+		conID, err := feed.ReqQuote(spec)
+		return watchAddedMsg{spec: spec, conID: conID, typed: typed, err: err}
+	}
+}
+
+// Release the quotes of every row that IB has resolved.
+func (m *model) cancelWatchQuotes() {
+	for _, w := range m.watchlist {
+		if w.conID != 0 {
+			m.feed.CancelQuote(w.conID)
+		}
+	}
+}
+
+// Parse a typed symbol, save it to the active watchlist and subscribe to its quote.
+func (m *model) addWatchItem(s string) tea.Cmd {
+	spec, err := contract.Parse(s)
+	if err != nil {
+		slog.Warn("Couldn't add watchlist symbol", "input", s, "error", err)
+		return nil
+	}
+	if !m.watchlists.Add(spec) {
+		slog.Warn("Symbol is already in the watchlist", "symbol", spec.String(), "watchlist", m.watchlists.Active)
+		return nil
+	}
+	m.saveWatchlists()
+	m.watchlist = append(m.watchlist, watchItem{spec: spec})
+	m.watchCursor = len(m.watchlist) - 1
+	m.panels[watchlist].Content = m.renderWatchlistContent()
+	return m.reqWatchQuote(spec, true)
+}
+
+// Attach a resolved conID to the first row still waiting on it. A reply for a row
+// that has since been removed, or for a list no longer shown, is cancelled.
+func (m *model) watchAdded(msg watchAddedMsg) {
+	i := slices.IndexFunc(m.watchlist, func(w watchItem) bool {
+		return w.spec == msg.spec && w.conID == 0
+	})
+	if msg.err != nil {
+		slog.Error("Couldn't subscribe to watchlist symbol", "symbol", msg.spec.String(), "error", msg.err)
+		if msg.typed && i >= 0 {
+			// Don't keep a typo on disk
+			m.watchlists.Remove(msg.spec)
+			m.saveWatchlists()
+			m.watchlist = slices.Delete(m.watchlist, i, i+1)
+			m.watchCursor = min(m.watchCursor, max(len(m.watchlist)-1, 0))
+		}
+		return
+	}
+	if i < 0 {
+		m.feed.CancelQuote(msg.conID)
+		return
+	}
+	m.watchlist[i].conID = msg.conID
+	if msg.typed {
+		slog.Info("Added watchlist symbol", "symbol", msg.spec.String(), "conID", msg.conID)
+	}
+}
+
+// Drop the selected row, its market data subscription and its place on disk.
+func (m *model) removeWatchItem() {
+	if m.watchCursor >= len(m.watchlist) {
+		return
+	}
+	w := m.watchlist[m.watchCursor]
+	if w.conID != 0 {
+		m.feed.CancelQuote(w.conID)
+	}
+	m.watchlist = slices.Delete(m.watchlist, m.watchCursor, m.watchCursor+1)
+	m.watchCursor = min(m.watchCursor, max(len(m.watchlist)-1, 0))
+	m.watchlists.Remove(w.spec)
+	m.saveWatchlists()
+	slog.Info("Removed watchlist symbol", "symbol", w.spec.String())
+}
+
+// Show the next (step 1) or previous (step -1) watchlist and move subscriptions to it.
+func (m *model) switchWatchlist(step int) tea.Cmd {
+	if len(m.watchlists.Lists) <= 1 {
+		return nil
+	}
+	m.cancelWatchQuotes()
+	m.watchlists.Cycle(step)
+	m.saveWatchlists()
+	return m.loadWatchlist()
+}
+
+// Create an empty watchlist and switch to it.
+func (m *model) createWatchlist(name string) tea.Cmd {
+	if err := m.watchlists.Create(name); err != nil {
+		slog.Warn("Couldn't create watchlist", "error", err)
+		return nil
+	}
+	m.cancelWatchQuotes()
+	m.saveWatchlists()
+	slog.Info("Created watchlist", "watchlist", m.watchlists.Active)
+	cmd := m.loadWatchlist()
+	m.panels[watchlist].Content = m.renderWatchlistContent()
+	return cmd
+}
+
+// Append the contracts of a CSV file to the active watchlist.
+func (m *model) importWatchlist(path string) tea.Cmd {
+	data, err := os.ReadFile(filepath.Clean(strings.TrimSpace(path)))
+	if err != nil {
+		slog.Error("Couldn't read watchlist CSV", "error", err)
+		return nil
+	}
+	specs, err := lists.ImportCSV(bytes.NewReader(data))
+	if err != nil {
+		slog.Error("Couldn't import watchlist CSV", "error", err)
+		return nil
+	}
+	cmds := make([]tea.Cmd, 0, len(specs))
+	for _, spec := range specs {
+		if !m.watchlists.Add(spec) {
+			continue
+		}
+		m.watchlist = append(m.watchlist, watchItem{spec: spec})
+		cmds = append(cmds, m.reqWatchQuote(spec, false))
+	}
+	m.saveWatchlists()
+	slog.Info("Imported watchlist CSV", "path", path, "added", len(cmds), "watchlist", m.watchlists.Active)
+	m.panels[watchlist].Content = m.renderWatchlistContent()
+	return tea.Batch(cmds...)
+}
+
+// Write the active watchlist to a CSV file.
+func (m *model) exportWatchlist(path string) tea.Cmd {
+	path = filepath.Clean(strings.TrimSpace(path))
+	f, err := os.Create(path)
+	if err != nil {
+		slog.Error("Couldn't create watchlist CSV", "error", err)
+		return nil
+	}
+	err = lists.ExportCSV(f, m.watchlists.Specs())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		slog.Error("Couldn't export watchlist CSV", "error", err)
+		return nil
+	}
+	slog.Info("Exported watchlist CSV", "path", path, "watchlist", m.watchlists.Active)
+	return nil
+}
+
+// Persist the watchlists, logging rather than failing so the TUI keeps running.
+func (m *model) saveWatchlists() {
+	if err := m.watchlists.Save(); err != nil {
+		slog.Error("Couldn't save watchlists", "error", err)
+	}
+}
+
+// Render the Watchlist panel into a string for further Bubbletea rendering.
+func (m *model) renderWatchlistContent() string {
+	title := ""
+	if m.watchlists != nil {
+		names := m.watchlists.Names()
+		title = fmt.Sprintf("%v (%d/%d)  [ ] switch  n new  I import  E export\n",
+			m.watchlists.Active, slices.Index(names, m.watchlists.Active)+1, len(names))
+	}
+	if len(m.watchlist) == 0 {
+		return title + "Watchlist is empty. Select this tab and press a to add a symbol."
+	}
+	quotes := m.ibs.Snapshot().Quotes
+	header := []string{" ", "Symbol", "Bid", "Ask", "Last", "Chg", "Chg%", "Volume", "High", "Low", "Data"}
+	rows := make([][]string, 0, len(m.watchlist))
+	for i, w := range m.watchlist {
+		cursor := " "
+		if i == m.watchCursor && m.selectedTab == watchlist {
+			cursor = "›"
+		}
+		q, ok := quotes[w.conID]
+		if !ok {
+			rows = append(rows, []string{cursor, w.spec.String()})
+			continue
+		}
+		rows = append(rows, []string{
+			cursor,
+			w.spec.String(),
+			panels.FormatNumber(q.Bid, 2),
+			panels.FormatNumber(q.Ask, 2),
+			panels.FormatNumber(q.Last, 2),
+			panels.FormatNumber(q.Change, 2),
+			panels.FormatNumber(q.ChangePct, 2),
+			panels.FormatNumber(q.Volume, 0),
+			panels.FormatNumber(q.High, 2),
+			panels.FormatNumber(q.Low, 2),
+			q.DataType.Tag(),
+		})
+	}
+	return title + panels.RenderTable(header, rows, 2)
+}
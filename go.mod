@@ -8,6 +8,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/rs/zerolog v1.34.0
 	github.com/scmhub/ibsync v0.10.40
+	go.etcd.io/bbolt v1.4.3
 	golang.org/x/term v0.35.0
 )
 
@@ -0,0 +1,199 @@
+// Package analytics sums up trading performance from the gains realized by
+// closing tax lots: win rate, average win and loss, profit factor,
+// expectancy, drawdown and a Sharpe-like ratio, overall and per symbol or tag.
+package analytics
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/journal"
+	"github.com/glenntam/ibtui/internal/lots"
+)
+
+// Trading days in a year, to annualize the Sharpe-like ratio of daily P&L.
+const tradingDays = 252
+
+// Result is what one closing execution realized over every lot it closed.
+type Result struct {
+	ExecID  string
+	Account string
+	Symbol  string
+	Tags    []string // From the notes of every execution in the round trip
+	Closed  time.Time
+	PnL     float64
+}
+
+// Stats sums up a set of results. A result of exactly 0 is neither a win nor a loss.
+type Stats struct {
+	Trades      int
+	Wins        int
+	Losses      int
+	NetPnL      float64
+	GrossProfit float64
+	GrossLoss   float64 // Negative, or 0 without losses
+	MaxDrawdown float64 // Largest fall of cumulative P&L from a peak, positive
+	Sharpe      float64 // Annualized mean over standard deviation of daily P&L, 0 with under 2 days
+}
+
+// Group is the stats of the results sharing a symbol or tag.
+type Group struct {
+	Key string
+	Stats
+}
+
+// Report is the stats of every result, and broken down by symbol and by tag,
+// each breakdown best net P&L first.
+type Report struct {
+	Stats
+	BySymbol []Group
+	ByTag    []Group
+}
+
+// Results sums gains by the execution that realized them, in time order, and
+// tags each with the notes of its closing execution and of every execution
+// that opened the lots it closed.
+func Results(gains []lots.Gain, notes map[string]journal.Note) []Result {
+	results := make([]Result, 0, len(gains))
+	byExec := make(map[string]int)
+	for _, g := range gains {
+		i, ok := byExec[g.ExecID]
+		if !ok || g.ExecID == "" {
+			i = len(results)
+			byExec[g.ExecID] = i
+			results = append(results, Result{
+				ExecID:  g.ExecID,
+				Account: g.Account,
+				Symbol:  g.Symbol,
+				Tags:    addTags(nil, notes[g.ExecID].Tags),
+				Closed:  g.Closed,
+			})
+		}
+		results[i].PnL += g.Amount()
+		results[i].Tags = addTags(results[i].Tags, notes[g.OpenedBy].Tags)
+	}
+	slices.SortStableFunc(results, func(a, b Result) int {
+		return a.Closed.Compare(b.Closed)
+	})
+	return results
+}
+
+// Add the tags not already in tags.
+func addTags(tags, more []string) []string {
+	for _, tag := range more {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Summarize reports on results, in time order. Daily P&L is summed by
+// calendar day in loc.
+func Summarize(results []Result, loc *time.Location) Report {
+	bySymbol := make(map[string][]Result)
+	byTag := make(map[string][]Result)
+	for _, r := range results {
+		bySymbol[r.Symbol] = append(bySymbol[r.Symbol], r)
+		for _, tag := range r.Tags {
+			byTag[tag] = append(byTag[tag], r)
+		}
+	}
+	return Report{
+		Stats:    summarize(results, loc),
+		BySymbol: groups(bySymbol, loc),
+		ByTag:    groups(byTag, loc),
+	}
+}
+
+// WinRate is the share of trades that made money, from 0 to 1.
+func (s Stats) WinRate() float64 {
+	return ratio(float64(s.Wins), float64(s.Trades))
+}
+
+// AvgWin is the mean gain of winning trades.
+func (s Stats) AvgWin() float64 {
+	return ratio(s.GrossProfit, float64(s.Wins))
+}
+
+// AvgLoss is the mean loss of losing trades, negative.
+func (s Stats) AvgLoss() float64 {
+	return ratio(s.GrossLoss, float64(s.Losses))
+}
+
+// ProfitFactor is gross profit over gross loss, +Inf when there are wins but no losses.
+func (s Stats) ProfitFactor() float64 {
+	if s.GrossLoss == 0 && s.GrossProfit > 0 {
+		return math.Inf(1)
+	}
+	return ratio(s.GrossProfit, -s.GrossLoss)
+}
+
+// Expectancy is the mean P&L per trade.
+func (s Stats) Expectancy() float64 {
+	return ratio(s.NetPnL, float64(s.Trades))
+}
+
+// Sum up results, in time order.
+func summarize(results []Result, loc *time.Location) Stats {
+	var s Stats
+	var peak float64
+	daily := make(map[string]float64)
+	for _, r := range results {
+		s.Trades++
+		s.NetPnL += r.PnL
+		switch {
+		case r.PnL > 0:
+			s.Wins++
+			s.GrossProfit += r.PnL
+		case r.PnL < 0:
+			s.Losses++
+			s.GrossLoss += r.PnL
+		}
+		peak = math.Max(peak, s.NetPnL)
+		s.MaxDrawdown = math.Max(s.MaxDrawdown, peak-s.NetPnL)
+		daily[r.Closed.In(loc).Format(time.DateOnly)] += r.PnL
+	}
+	s.Sharpe = sharpe(daily)
+	return s
+}
+
+// Stats of each group, best net P&L first, then by key.
+func groups(byKey map[string][]Result, loc *time.Location) []Group {
+	g := make([]Group, 0, len(byKey))
+	for key, results := range byKey {
+		g = append(g, Group{Key: key, Stats: summarize(results, loc)})
+	}
+	slices.SortFunc(g, func(a, b Group) int {
+		return cmp.Or(cmp.Compare(b.NetPnL, a.NetPnL), cmp.Compare(a.Key, b.Key))
+	})
+	return g
+}
+
+// Annualized mean over sample standard deviation of the P&L of days with closed trades.
+func sharpe(daily map[string]float64) float64 {
+	n := float64(len(daily))
+	if n < 2 {
+		return 0
+	}
+	var sum float64
+	for _, pnl := range daily {
+		sum += pnl
+	}
+	mean := sum / n
+	var squares float64
+	for _, pnl := range daily {
+		squares += (pnl - mean) * (pnl - mean)
+	}
+	return ratio(mean, math.Sqrt(squares/(n-1))) * math.Sqrt(tradingDays)
+}
+
+// Divide, giving 0 rather than NaN or Inf for an empty set.
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return a / b
+}
@@ -0,0 +1,94 @@
+package analytics
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/journal"
+	"github.com/glenntam/ibtui/internal/lots"
+)
+
+// A closing execution's result on the given day of March 2026.
+func result(symbol string, day int, pnl float64, tags ...string) Result {
+	return Result{Symbol: symbol, Tags: tags, Closed: time.Date(2026, 3, day, 15, 0, 0, 0, time.UTC), PnL: pnl}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestResults(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	gains := []lots.Gain{
+		{ExecID: "s2", Symbol: "MSFT", Closed: t0.Add(time.Hour), Cost: 100, Proceeds: 90},
+		{ExecID: "s1", Symbol: "AAPL", Closed: t0, Cost: 100, Proceeds: 150},
+		{ExecID: "s1", Symbol: "AAPL", Closed: t0, Cost: 200, Proceeds: 180},
+	}
+	notes := map[string]journal.Note{"s1": {Tags: []string{"momentum"}}}
+	got := Results(gains, notes)
+	if len(got) != 2 || got[0].ExecID != "s1" || !near(got[0].PnL, 30) || got[0].Tags[0] != "momentum" {
+		t.Fatalf("expected s1's two lots summed to 30 first got %+v", got)
+	}
+	if got[1].ExecID != "s2" || !near(got[1].PnL, -10) {
+		t.Fatalf("expected s2 to lose 10 got %+v", got[1])
+	}
+}
+
+func TestResults_round_trip_tags(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	gains := []lots.Gain{
+		{ExecID: "s1", OpenedBy: "b1", Symbol: "AAPL", Closed: t0, Cost: 100, Proceeds: 150},
+		{ExecID: "s1", OpenedBy: "b2", Symbol: "AAPL", Closed: t0, Cost: 100, Proceeds: 120},
+	}
+	notes := map[string]journal.Note{
+		"b1": {Tags: []string{"breakout"}},
+		"b2": {Tags: []string{"breakout", "add"}},
+		"s1": {Tags: []string{"target"}},
+	}
+	got := Results(gains, notes)
+	if len(got) != 1 || !slices.Equal(got[0].Tags, []string{"target", "breakout", "add"}) {
+		t.Fatalf("expected the exit's and both entries' tags once each got %+v", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []Result{
+		result("AAPL", 2, 100, "momentum"),
+		result("AAPL", 2, -50, "momentum", "fade"),
+		result("MSFT", 3, -100, "fade"),
+		result("MSFT", 4, 0),
+		result("AAPL", 5, 200, "momentum"),
+	}
+	r := Summarize(results, time.UTC)
+	if r.Trades != 5 || r.Wins != 2 || r.Losses != 2 || !near(r.NetPnL, 150) {
+		t.Fatalf("expected 5 trades, 2 wins, 2 losses netting 150 got %+v", r.Stats)
+	}
+	if !near(r.WinRate(), 0.4) || !near(r.AvgWin(), 150) || !near(r.AvgLoss(), -75) {
+		t.Fatalf("expected 40%% wins averaging 150 and losses averaging -75 got %+v", r.Stats)
+	}
+	if !near(r.ProfitFactor(), 2) || !near(r.Expectancy(), 30) || !near(r.MaxDrawdown, 150) {
+		t.Fatalf("expected profit factor 2, expectancy 30 and drawdown 150 got %+v", r.Stats)
+	}
+	// Daily P&L of 50, -100, 0 and 200: mean 37.5, sample deviation 125.
+	if !near(r.Sharpe, 0.3*math.Sqrt(tradingDays)) {
+		t.Fatalf("expected an annualized Sharpe-like ratio of daily P&L got %v", r.Sharpe)
+	}
+	if len(r.BySymbol) != 2 || r.BySymbol[0].Key != "AAPL" || !near(r.BySymbol[0].NetPnL, 250) {
+		t.Fatalf("expected AAPL to lead the symbols got %+v", r.BySymbol)
+	}
+	if len(r.ByTag) != 2 || r.ByTag[1].Key != "fade" || r.ByTag[1].Trades != 2 || !near(r.ByTag[1].NetPnL, -150) {
+		t.Fatalf("expected fade to count both its trades got %+v", r.ByTag)
+	}
+}
+
+func TestStats_empty(t *testing.T) {
+	var s Stats
+	if s.WinRate() != 0 || s.AvgLoss() != 0 || s.ProfitFactor() != 0 || s.Expectancy() != 0 {
+		t.Fatalf("expected zeros rather than NaN without trades got %+v", s)
+	}
+	if s = (Stats{Wins: 1, GrossProfit: 10}); !math.IsInf(s.ProfitFactor(), 1) {
+		t.Fatalf("expected an infinite profit factor without losses got %v", s.ProfitFactor())
+	}
+}
@@ -0,0 +1,436 @@
+// Package chart draws OHLC candles over volume bars with Unicode box and
+// block characters, sized to fit a panel, with indicator lines in braille
+// dots over the candles or in panes below.
+package chart
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/glenntam/ibtui/internal/panels"
+)
+
+const (
+	colorUp   = lipgloss.Color("2") // Green
+	colorDown = lipgloss.Color("1") // Red
+
+	minPriceRows  = 3
+	volumeShare   = 5  // Volume takes a fifth of the rows
+	halvesPerRow  = 2  // Candles are drawn to half a row
+	eighthsPerRow = 8  // Volume is drawn to an eighth of a row
+	smallPrice    = 10 // Prices under this get more decimals
+	priceDecimals = 2
+	pipDecimals   = 4
+	volumeBlocks  = " ▁▂▃▄▅▆▇█"
+
+	paneShare      = 6 // Each pane takes a sixth of the rows
+	minPaneRows    = 2
+	quartersPerRow = 4      // Lines are drawn to a quarter of a row
+	brailleDots    = "⡀⠄⠂⠁" // Left column dots, bottom quarter first
+	brailleBlank   = '\u2800'
+)
+
+// What a half row of a candle shows.
+const (
+	empty = iota
+	wick
+	body
+)
+
+// Chart is what Render draws: candles with optional indicator lines over
+// them and indicator panes below the volume.
+type Chart struct {
+	Bars       []Bar
+	Overlays   []Line // Drawn over the candles, on the price scale
+	Panes      []Pane
+	TimeLayout string         // How the time axis shows bar times
+	Location   *time.Location // Where the time axis shows bar times; nil for local time
+}
+
+// Bar is one period's prices and volume.
+type Bar struct {
+	Time   time.Time // Start of the period
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
+
+// Line is an indicator with one value per bar, NaN where it has none.
+// A Line without a Label is drawn but left out of the legend.
+type Line struct {
+	Label  string
+	Values []float64
+	Color  lipgloss.Color
+}
+
+// Pane is a strip of indicator lines, and optionally a histogram from
+// zero, on a scale of its own.
+type Pane struct {
+	Lines     []Line
+	Histogram Line
+	Min, Max  float64 // A fixed scale, e.g. 0 to 100 for RSI, if Max > Min
+}
+
+// Render draws the newest bars that fit in width columns, one per bar,
+// with prices labelled on the right, volume below, then as many panes as
+// fit and the first and last bar times along the bottom. Overlays and
+// panes get a legend of their latest values above them. It returns "" if
+// nothing fits.
+func Render(c Chart, width, height int) string {
+	legendRows := min(len(c.Overlays), 1)
+	volumeRows := max(height/volumeShare, 1)
+	paneRows := max(height/paneShare, minPaneRows)
+	panes := c.Panes
+	priceRows := height - legendRows - volumeRows - 1 - len(panes)*(paneRows+1)
+	for priceRows < minPriceRows && len(panes) > 0 {
+		panes = panes[:len(panes)-1]
+		priceRows += paneRows + 1
+	}
+	if len(c.Bars) == 0 || priceRows < minPriceRows {
+		return ""
+	}
+	lo, hi := priceRange(c.Bars, c.Overlays)
+	labelWidth := max(len(formatPrice(hi, hi)), len(formatPrice(lo, hi)))
+	for _, p := range panes {
+		lo, hi := p.scale(0)
+		labelWidth = max(labelWidth, len(formatValue(hi, lo, hi)), len(formatValue(lo, lo, hi)))
+	}
+	cols := width - labelWidth - 1
+	if cols < 1 {
+		return ""
+	}
+	from := max(len(c.Bars)-cols, 0)
+	bars := c.Bars[from:]
+	pad := strings.Repeat(" ", cols-len(bars)+1)
+
+	lines := make([]string, 0, height)
+	lo, hi = priceRange(bars, visible(c.Overlays, from))
+	if legendRows > 0 {
+		lines = append(lines, legend(c.Overlays, width, hi))
+	}
+	for r := range priceRows {
+		var line strings.Builder
+		for i, b := range bars {
+			if cell := candleCell(b, r, priceRows, lo, hi); cell != " " {
+				line.WriteString(paint(b, cell))
+				continue
+			}
+			line.WriteString(dotCell(c.Overlays, from+i, r, priceRows, lo, hi))
+		}
+		line.WriteString(pad)
+		line.WriteString(priceLabel(r, priceRows, lo, hi, labelWidth))
+		lines = append(lines, line.String())
+	}
+	lines = append(lines, volumeLines(bars, volumeRows)...)
+	for _, p := range panes {
+		lines = append(lines, p.render(from, len(bars), paneRows, width, labelWidth, pad)...)
+	}
+	lines = append(lines, timeAxis(bars, c.TimeLayout, c.Location))
+	return strings.Join(lines, "\n")
+}
+
+// Draw a pane's legend and rows for the n bars from index from, with its
+// top and bottom rows labelled like prices.
+func (p Pane) render(from, n, rows, width, labelWidth int, pad string) []string {
+	lo, hi := p.scale(from)
+	out := make([]string, 0, rows+1)
+	out = append(out, legend(append(p.Lines[:len(p.Lines):len(p.Lines)], p.Histogram), width,
+		math.Max(math.Abs(lo), math.Abs(hi))))
+	for r := range rows {
+		var line strings.Builder
+		for i := from; i < from+n; i++ {
+			cell := dotCell(p.Lines, i, r, rows, lo, hi)
+			if cell == " " {
+				cell = histogramCell(value(p.Histogram.Values, i), r, rows, lo, hi)
+			}
+			line.WriteString(cell)
+		}
+		line.WriteString(pad)
+		if r == 0 || r == rows-1 {
+			s := formatValue(hi-(float64(r)+0.5)/float64(rows)*(hi-lo), lo, hi)
+			line.WriteString(strings.Repeat(" ", max(labelWidth-len(s), 0)) + s)
+		}
+		out = append(out, line.String())
+	}
+	return out
+}
+
+// The pane's fixed scale, or else the range of its values from index from
+// on, taking in zero if it has a histogram.
+func (p Pane) scale(from int) (float64, float64) {
+	if p.Max > p.Min {
+		return p.Min, p.Max
+	}
+	lo, hi := valueRange(math.Inf(1), math.Inf(-1), visible(p.Lines, from))
+	if p.Histogram.Values != nil {
+		lo, hi = valueRange(math.Min(lo, 0), math.Max(hi, 0), visible([]Line{p.Histogram}, from))
+	}
+	switch {
+	case math.IsInf(lo, 0):
+		return -1, 1
+	case hi <= lo:
+		return lo - 1, hi + 1
+	}
+	return lo, hi
+}
+
+// The lowest low and highest high, taking in the overlays and widened if
+// every price is the same.
+func priceRange(bars []Bar, overlays []Line) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, b := range bars {
+		lo, hi = math.Min(lo, b.Low), math.Max(hi, b.High)
+	}
+	lo, hi = valueRange(lo, hi, overlays)
+	if hi <= lo {
+		lo, hi = lo-1, hi+1
+	}
+	return lo, hi
+}
+
+// Widen lo and hi to take in every value of lines.
+func valueRange(lo, hi float64, lines []Line) (float64, float64) {
+	for _, l := range lines {
+		for _, v := range l.Values {
+			if !math.IsNaN(v) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+	}
+	return lo, hi
+}
+
+// Lines cut to their values from index from on.
+func visible(lines []Line, from int) []Line {
+	out := make([]Line, 0, len(lines))
+	for _, l := range lines {
+		l.Values = l.Values[min(from, len(l.Values)):]
+		out = append(out, l)
+	}
+	return out
+}
+
+// The value at index i, or NaN past the end.
+func value(values []float64, i int) float64 {
+	if i >= len(values) {
+		return math.NaN()
+	}
+	return values[i]
+}
+
+// Draw row r, counted from the top, of one candle: a heavy body from open
+// to close and a light wick from low to high, each to the nearest half row.
+func candleCell(b Bar, r, rows int, lo, hi float64) string {
+	halves := float64(rows * halvesPerRow)
+	scale := func(p float64) float64 { return (p - lo) / (hi - lo) * halves }
+	bodyLo, bodyHi := scale(math.Min(b.Open, b.Close)), scale(math.Max(b.Open, b.Close))
+	wickLo, wickHi := scale(b.Low), scale(b.High)
+	show := func(half int) int {
+		switch {
+		case covers(half, bodyLo, bodyHi, rows):
+			return body
+		case covers(half, wickLo, wickHi, rows):
+			return wick
+		}
+		return empty
+	}
+	lower := (rows - 1 - r) * halvesPerRow
+	return glyph(show(lower+1), show(lower))
+}
+
+// Draw row r, counted from the top, of a histogram bar from zero to v as a
+// light line to the nearest half row, green above zero and red below.
+func histogramCell(v float64, r, rows int, lo, hi float64) string {
+	if math.IsNaN(v) {
+		return " "
+	}
+	halves := float64(rows * halvesPerRow)
+	zero, top := -lo/(hi-lo)*halves, (v-lo)/(hi-lo)*halves
+	show := func(half int) int {
+		if covers(half, math.Min(zero, top), math.Max(zero, top), rows) {
+			return wick
+		}
+		return empty
+	}
+	lower := (rows - 1 - r) * halvesPerRow
+	s := glyph(show(lower+1), show(lower))
+	if s == " " {
+		return s
+	}
+	color := colorUp
+	if v < 0 {
+		color = colorDown
+	}
+	return lipgloss.NewStyle().Foreground(color).Render(s)
+}
+
+// Whether a stroke from lo to hi, in half rows, is drawn in the given half.
+// A stroke too short to reach the middle of any half still shows in the
+// half it sits in, so doji and flat bars don't vanish.
+func covers(half int, lo, hi float64, rows int) bool {
+	mid := float64(half) + 0.5
+	if lo <= mid && mid <= hi {
+		return true
+	}
+	return half == min(int((lo+hi)/2), rows*halvesPerRow-1)
+}
+
+// The box drawing character with the given top and bottom halves.
+func glyph(top, bottom int) string {
+	switch [2]int{top, bottom} {
+	case [2]int{body, body}:
+		return "┃"
+	case [2]int{body, wick}:
+		return "╿"
+	case [2]int{wick, body}:
+		return "╽"
+	case [2]int{wick, wick}:
+		return "│"
+	case [2]int{body, empty}:
+		return "╹"
+	case [2]int{empty, body}:
+		return "╻"
+	case [2]int{wick, empty}:
+		return "╵"
+	case [2]int{empty, wick}:
+		return "╷"
+	}
+	return " "
+}
+
+// Draw row r, counted from the top, of every line's value at bar i as
+// braille dots to the nearest quarter row, in the color of the first line
+// there. It returns a space if no line passes through the row.
+func dotCell(lines []Line, i, r, rows int, lo, hi float64) string {
+	quarters := rows * quartersPerRow
+	lower := (rows - 1 - r) * quartersPerRow
+	dots := []rune(brailleDots)
+	cell := brailleBlank
+	var color lipgloss.Color
+	for _, l := range lines {
+		v := value(l.Values, i)
+		if math.IsNaN(v) {
+			continue
+		}
+		q := min(max(int((v-lo)/(hi-lo)*float64(quarters)), 0), quarters-1)
+		if q < lower || q >= lower+quartersPerRow {
+			continue
+		}
+		if cell == brailleBlank {
+			color = l.Color
+		}
+		cell |= dots[q-lower]
+	}
+	if cell == brailleBlank {
+		return " "
+	}
+	return lipgloss.NewStyle().Foreground(color).Render(string(cell))
+}
+
+// Color s green for a bar that closed at or above its open, red otherwise.
+func paint(b Bar, s string) string {
+	if s == " " {
+		return s
+	}
+	color := colorUp
+	if b.Close < b.Open {
+		color = colorDown
+	}
+	return lipgloss.NewStyle().Foreground(color).Render(s)
+}
+
+// Label the top, middle and bottom price rows with the price at their middle.
+func priceLabel(r, rows int, lo, hi float64, width int) string {
+	if r != 0 && r != rows/2 && r != rows-1 {
+		return ""
+	}
+	p := hi - (float64(r)+0.5)/float64(rows)*(hi-lo)
+	s := formatPrice(p, hi)
+	return strings.Repeat(" ", max(width-len(s), 0)) + s
+}
+
+// Draw each labelled line's latest value in its color, as many as fit in width.
+func legend(lines []Line, width int, scale float64) string {
+	var s strings.Builder
+	used := 0
+	for _, l := range lines {
+		if l.Label == "" {
+			continue
+		}
+		text := l.Label + " " + formatPrice(latest(l.Values), scale)
+		if used > 0 {
+			text = "  " + text
+		}
+		if used+len(text) > width {
+			break
+		}
+		used += len(text)
+		s.WriteString(lipgloss.NewStyle().Foreground(l.Color).Render(text))
+	}
+	return s.String()
+}
+
+// The last value that isn't NaN, or NaN if there's none.
+func latest(values []float64) float64 {
+	for i := len(values) - 1; i >= 0; i-- {
+		if !math.IsNaN(values[i]) {
+			return values[i]
+		}
+	}
+	return math.NaN()
+}
+
+// Draw each bar's volume as a column of blocks, scaled to the largest.
+func volumeLines(bars []Bar, rows int) []string {
+	top := 0.0
+	for _, b := range bars {
+		top = math.Max(top, b.Volume)
+	}
+	blocks := []rune(volumeBlocks)
+	lines := make([]string, rows)
+	for r := range rows {
+		var line strings.Builder
+		for _, b := range bars {
+			eighths := 0
+			if top > 0 {
+				eighths = int(math.Round(b.Volume / top * float64(rows*eighthsPerRow)))
+			}
+			level := min(max(eighths-(rows-1-r)*eighthsPerRow, 0), eighthsPerRow)
+			line.WriteString(paint(b, string(blocks[level])))
+		}
+		lines[r] = line.String()
+	}
+	return lines
+}
+
+// Show the first bar's time on the left and the last's on the right, or
+// only the last's if both don't fit. Times are shown in loc, or local time if nil.
+func timeAxis(bars []Bar, layout string, loc *time.Location) string {
+	if loc == nil {
+		loc = time.Local
+	}
+	first := bars[0].Time.In(loc).Format(layout)
+	last := bars[len(bars)-1].Time.In(loc).Format(layout)
+	end := max(len(bars), len(last))
+	if len(first)+len(last)+1 > end {
+		return strings.Repeat(" ", max(end-len(last), 0)) + last
+	}
+	return first + strings.Repeat(" ", end-len(first)-len(last)) + last
+}
+
+// Prices under 10, e.g. forex, get 4 decimals; the rest 2.
+func formatPrice(p, scale float64) string {
+	if scale < smallPrice {
+		return panels.FormatNumber(p, pipDecimals)
+	}
+	return panels.FormatNumber(p, priceDecimals)
+}
+
+// Format a pane value with as many decimals as its scale from lo to hi needs.
+func formatValue(v, lo, hi float64) string {
+	return formatPrice(v, math.Max(math.Abs(lo), math.Abs(hi)))
+}
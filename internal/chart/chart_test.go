@@ -0,0 +1,118 @@
+package chart
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Bars a minute apart from 14:30 UTC on 2 March 2026.
+func bars(ohlcv ...[5]float64) []Bar {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	out := make([]Bar, 0, len(ohlcv))
+	for i, v := range ohlcv {
+		out = append(out, Bar{Time: t0.Add(time.Duration(i) * time.Minute),
+			Open: v[0], High: v[1], Low: v[2], Close: v[3], Volume: v[4]})
+	}
+	return out
+}
+
+func TestRender(t *testing.T) {
+	got := Render(Chart{Bars: bars(
+		[5]float64{100, 110, 90, 108, 50}, // Spans the whole range
+		[5]float64{108, 108, 104, 104, 100},
+		[5]float64{104, 105, 95, 104, 0}, // Doji
+	), TimeLayout: "15:04", Location: time.UTC}, 40, 10)
+	lines := strings.Split(got, "\n")
+	if len(lines) != 10 {
+		t.Fatalf("expected 7 price rows, 2 volume rows and a time axis got %d lines:\n%s", len(lines), got)
+	}
+	column := func(i int) string {
+		var s strings.Builder
+		for _, l := range lines[:7] {
+			s.WriteRune([]rune(l)[i])
+		}
+		return s.String()
+	}
+	// 20 points over 14 half rows: a body from 7 to 12.6 half rows with a wick from 0 to 14
+	if c := column(0); c != "╽┃┃╿│││" {
+		t.Fatalf("expected the first candle's body with a wick each end got %q", c)
+	}
+	if c := column(1); c != "╻┃     " {
+		t.Fatalf("expected the second candle's body in the upper rows got %q", c)
+	}
+	if c := column(2); c != " ╷╿││╵ " {
+		t.Fatalf("expected the doji to still show its body got %q", c)
+	}
+	if !strings.HasSuffix(lines[0], "108.57") || !strings.HasSuffix(lines[6], "91.43") {
+		t.Fatalf("expected the top and bottom rows labelled got %q and %q", lines[0], lines[6])
+	}
+	if lines[7] != " █ " || lines[8] != "██ " {
+		t.Fatalf("expected volume scaled to the busiest bar got %q", lines[7:9])
+	}
+	if lines[9] != "14:32" {
+		t.Fatalf("expected only the last bar time when both don't fit got %q", lines[9])
+	}
+}
+
+func TestRender_tooSmall(t *testing.T) {
+	c := Chart{Bars: bars([5]float64{1, 2, 0.5, 1.5, 10}), TimeLayout: "15:04", Location: time.UTC}
+	if Render(c, 40, 4) != "" || Render(c, 5, 20) != "" || Render(Chart{}, 40, 20) != "" {
+		t.Fatalf("expected nothing drawn when the chart can't fit")
+	}
+}
+
+func TestRender_newestFit(t *testing.T) {
+	many := make([][5]float64, 0, 60)
+	for i := range 60 {
+		p := float64(100 + i)
+		many = append(many, [5]float64{p, p + 1, p - 1, p + 0.5, 10})
+	}
+	lines := strings.Split(Render(Chart{Bars: bars(many...), TimeLayout: "15:04", Location: time.UTC}, 40, 10), "\n")
+	// 40 columns less a space and the 6 wide price labels leaves room for 33 bars.
+	if axis := lines[len(lines)-1]; axis != "14:57"+strings.Repeat(" ", 23)+"15:29" {
+		t.Fatalf("expected the newest 33 bars from 14:57 to 15:29 got %q", axis)
+	}
+}
+
+func TestRender_indicators(t *testing.T) {
+	nan := math.NaN()
+	got := Render(Chart{
+		Bars: bars(
+			[5]float64{100, 110, 100, 105, 10},
+			[5]float64{100, 110, 100, 105, 10},
+			[5]float64{100, 110, 100, 105, 10}),
+		Overlays:   []Line{{Label: "MA", Values: []float64{120, 120, 120}}},
+		Panes:      []Pane{{Histogram: Line{Label: "Hist", Values: []float64{1, -1, nan}}}},
+		TimeLayout: "15:04",
+		Location:   time.UTC,
+	}, 40, 20)
+	lines := strings.Split(got, "\n")
+	if len(lines) != 20 {
+		t.Fatalf("expected a legend, 10 price rows, 4 volume rows, a 3 row pane with legend and an axis got %d:\n%s",
+			len(lines), got)
+	}
+	if lines[0] != "MA 120.00" {
+		t.Fatalf("expected the overlay legend got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "⠁⠁⠁") {
+		t.Fatalf("expected the overlay dotted along the top quarter row got %q", lines[1])
+	}
+	if lines[15] != "Hist -1.0000" {
+		t.Fatalf("expected the pane legend with the latest histogram value got %q", lines[15])
+	}
+	column := func(i int) string {
+		var s strings.Builder
+		for _, l := range lines[16:19] {
+			s.WriteRune([]rune(l)[i])
+		}
+		return s.String()
+	}
+	if column(0) != "│╵ " || column(1) != " ╷│" || column(2) != "   " {
+		t.Fatalf("expected histogram bars up and down from zero got %q %q %q", column(0), column(1), column(2))
+	}
+	if !strings.HasSuffix(lines[16], " 0.6667") || !strings.HasSuffix(lines[18], "-0.6667") {
+		t.Fatalf("expected the pane's top and bottom rows labelled got %q and %q", lines[16], lines[18])
+	}
+}
@@ -0,0 +1,76 @@
+// Package contract describes tradable instruments the way a user types them.
+package contract
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Defaults used when a user only types a symbol.
+const (
+	DefaultSecType  = "STK"
+	DefaultExchange = "SMART"
+	DefaultCurrency = "USD"
+
+	specFields = 4
+)
+
+// ErrEmptySymbol occurs when a contract is described without a symbol.
+var ErrEmptySymbol = errors.New("contract symbol can't be empty")
+
+// ErrTooManyFields occurs when a contract description has more than 4 fields.
+var ErrTooManyFields = errors.New("contract takes at most symbol:secType:exchange:currency")
+
+// Spec is enough to ask IB to resolve a unique contract.
+type Spec struct {
+	Symbol   string `json:"symbol"`
+	SecType  string `json:"secType"`
+	Exchange string `json:"exchange"`
+	Currency string `json:"currency"`
+}
+
+// Parse reads "SYMBOL[:SECTYPE[:EXCHANGE[:CURRENCY]]]", e.g. "AAPL" or
+// "EUR:CASH:IDEALPRO:USD". Missing fields get the STK/SMART/USD defaults.
+func Parse(s string) (Spec, error) {
+	fields := strings.Split(strings.ToUpper(strings.TrimSpace(s)), ":")
+	if len(fields) > specFields {
+		return Spec{}, fmt.Errorf("couldn't parse contract %q: %w", s, ErrTooManyFields)
+	}
+	fields = append(fields, make([]string, specFields-len(fields))...)
+	spec := Spec{
+		Symbol:   strings.TrimSpace(fields[0]),
+		SecType:  strings.TrimSpace(fields[1]),
+		Exchange: strings.TrimSpace(fields[2]),
+		Currency: strings.TrimSpace(fields[3]),
+	}
+	if spec.Symbol == "" {
+		return Spec{}, fmt.Errorf("couldn't parse contract %q: %w", s, ErrEmptySymbol)
+	}
+	return spec.WithDefaults(), nil
+}
+
+// WithDefaults fills any empty field with the STK/SMART/USD defaults.
+func (s Spec) WithDefaults() Spec {
+	if s.SecType == "" {
+		s.SecType = DefaultSecType
+	}
+	if s.Exchange == "" {
+		s.Exchange = DefaultExchange
+	}
+	if s.Currency == "" {
+		s.Currency = DefaultCurrency
+	}
+	return s
+}
+
+// String formats s the same way Parse reads it, omitting trailing defaults.
+func (s Spec) String() string {
+	fields := []string{s.Symbol, s.SecType, s.Exchange, s.Currency}
+	defaults := []string{"", DefaultSecType, DefaultExchange, DefaultCurrency}
+	n := len(fields)
+	for n > 1 && (fields[n-1] == defaults[n-1] || fields[n-1] == "") {
+		n--
+	}
+	return strings.Join(fields[:n], ":")
+}
@@ -0,0 +1,46 @@
+package contract
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Spec
+	}{
+		{"aapl", Spec{"AAPL", "STK", "SMART", "USD"}},
+		{" EUR:CASH:IDEALPRO ", Spec{"EUR", "CASH", "IDEALPRO", "USD"}},
+		{"ES:FUT:CME:USD", Spec{"ES", "FUT", "CME", "USD"}},
+		{"SAP::IBIS:EUR", Spec{"SAP", "STK", "IBIS", "EUR"}},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.in)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned unexpected error: %v", c.in, err)
+		}
+		if got != c.want {
+			t.Fatalf("Parse(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+		if again, _ := Parse(got.String()); again != got {
+			t.Fatalf("Parse(%q.String()) = %+v, want %+v", got, again, got)
+		}
+	}
+
+	if _, err := Parse(" :STK"); !errors.Is(err, ErrEmptySymbol) {
+		t.Fatalf("expected ErrEmptySymbol got %v", err)
+	}
+	if _, err := Parse("A:B:C:D:E"); !errors.Is(err, ErrTooManyFields) {
+		t.Fatalf("expected ErrTooManyFields got %v", err)
+	}
+}
+
+func TestSpecString(t *testing.T) {
+	if got := (Spec{"AAPL", "STK", "SMART", "USD"}).String(); got != "AAPL" {
+		t.Fatalf("expected defaults to be omitted got %q", got)
+	}
+	if got := (Spec{"SAP", "STK", "IBIS", "EUR"}).String(); got != "SAP:STK:IBIS:EUR" {
+		t.Fatalf("expected all fields got %q", got)
+	}
+}
@@ -2,13 +2,20 @@
 package env
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+
+	"github.com/glenntam/ibtui/internal/risk"
 
 	"github.com/joho/godotenv"
 )
 
+// ErrBadNumber occurs when a numeric setting, e.g. a risk limit, is set but isn't a non-negative number.
+var ErrBadNumber = errors.New("not a non-negative number")
+
 // Config contains parsed .env variables specific to the app.
 type Config struct {
 	Host          string
@@ -16,6 +23,13 @@ type Config struct {
 	ClientID      int64
 	Timezone      string
 	LogFile       string
+	WatchlistFile string
+	JournalFile   string  // Empty for journal.db in the user's data directory
+	LotMethod     string  // fifo, lifo, hifo or specific
+	MarketData    string  // live, frozen, delayed or delayed-frozen
+	WhatIfAbove   float64 // Orders worth at least this are previewed before placing
+	ChartStudies  string  // Space separated, e.g. "sma20 vwap rsi14"
+	Risk          risk.Limits
 	SMTPHost      string
 	SMTPPort      int
 	SMTPUsername  string
@@ -27,8 +41,9 @@ type Config struct {
 // ParseDotEnv gets environment variables from .env file.
 // A file called ".env" must be located where the binary is run.
 // If .env exists but required configs variables are not found,
-// then reasonable default values are used.
-func ParseDotEnv() *Config {
+// then reasonable default values are used. Numeric settings that are set but
+// can't be read are returned as an error rather than quietly turned off.
+func ParseDotEnv() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
 		fmt.Printf(".env file not found! Please create one.\n" +
@@ -63,12 +78,47 @@ func ParseDotEnv() *Config {
 		logFile = "logfile.json"
 	}
 
+	watchlistFile := os.Getenv("IBTUI_WATCHLIST_FILE")
+	if watchlistFile == "" {
+		watchlistFile = "watchlists.json"
+	}
+
+	marketData := os.Getenv("IBTUI_MARKET_DATA_TYPE")
+	if marketData == "" {
+		marketData = "live"
+	}
+
+	lotMethod := os.Getenv("IBTUI_LOT_METHOD")
+	if lotMethod == "" {
+		lotMethod = "fifo"
+	}
+
+	var errs []error
+	number := func(key string) float64 {
+		v, err := parseFloat(key)
+		errs = append(errs, err)
+		return v
+	}
 	cfg := &Config{
-		Host:     host,
-		Port:     port,
-		ClientID: clientID,
-		Timezone: timezone,
-		LogFile:  logFile,
+		Host:          host,
+		Port:          port,
+		ClientID:      clientID,
+		Timezone:      timezone,
+		LogFile:       logFile,
+		WatchlistFile: watchlistFile,
+		JournalFile:   os.Getenv("IBTUI_JOURNAL_FILE"),
+		LotMethod:     lotMethod,
+		MarketData:    marketData,
+		WhatIfAbove:   number("IBTUI_WHATIF_THRESHOLD"),
+		ChartStudies:  os.Getenv("IBTUI_CHART_STUDIES"),
+		Risk: risk.Limits{
+			MaxNotional:      number("IBTUI_RISK_MAX_NOTIONAL"),
+			MaxQuantity:      number("IBTUI_RISK_MAX_QUANTITY"),
+			PriceBandPct:     number("IBTUI_RISK_PRICE_BAND_PCT"),
+			MaxOpenPerSymbol: int(number("IBTUI_RISK_MAX_OPEN_PER_SYMBOL")),
+			AllowedSymbols:   parseList("IBTUI_RISK_ALLOWED_SYMBOLS"),
+			AllowedSecTypes:  parseList("IBTUI_RISK_ALLOWED_SECTYPES"),
+		},
 	}
 
 	smtpTo := os.Getenv("IBTUI_SMTP_TO")
@@ -83,5 +133,29 @@ func ParseDotEnv() *Config {
 		cfg.SMTPSender = os.Getenv("IBTUI_SMTP_SENDER_EMAIL")
 		cfg.SMTPRecipient = os.Getenv("IBTUI_SMTP_RECIPIENT_EMAIL")
 	}
-	return cfg
+	return cfg, errors.Join(errs...)
+}
+
+// Read a non-negative number, or 0 if the variable is unset.
+func parseFloat(key string) (float64, error) {
+	s := strings.TrimSpace(os.Getenv(key))
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("%v=%q: %w", key, s, ErrBadNumber)
+	}
+	return v, nil
+}
+
+// Read a comma separated list, or nil if the variable is unset.
+func parseList(key string) []string {
+	var list []string
+	for item := range strings.SplitSeq(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
 }
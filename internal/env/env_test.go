@@ -1,7 +1,10 @@
 package env
 
 import (
+	"errors"
 	"os"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -13,13 +16,34 @@ func TestParseDotEnv_defaults_and_smtp_detection(t *testing.T) {
 	t.Setenv("IBTUI_CLIENT_ID", "42")
 	t.Setenv("IBTUI_TIMEZONE", "UTC")
 	// Ensure SMTP is not enabled by default
-	cfg := ParseDotEnv()
+	cfg, err := ParseDotEnv()
+	if err != nil {
+		t.Fatalf("ParseDotEnv returned unexpected error: %v", err)
+	}
 	if cfg.Host != "localhost" {
 		t.Fatalf("expected host localhost got %s", cfg.Host)
 	}
 	if cfg.Port != 8080 {
 		t.Fatalf("expected port 8080 got %d", cfg.Port)
 	}
+	if cfg.WatchlistFile != "watchlists.json" {
+		t.Fatalf("expected default watchlist file got %s", cfg.WatchlistFile)
+	}
+	if cfg.JournalFile != "" {
+		t.Fatalf("expected the journal in the user's data directory by default got %s", cfg.JournalFile)
+	}
+	if cfg.LotMethod != "fifo" {
+		t.Fatalf("expected FIFO tax lots by default got %s", cfg.LotMethod)
+	}
+	if cfg.MarketData != "live" {
+		t.Fatalf("expected live market data by default got %s", cfg.MarketData)
+	}
+	if cfg.Risk.MaxNotional != 0 || cfg.Risk.AllowedSymbols != nil {
+		t.Fatalf("expected no risk limits by default got %+v", cfg.Risk)
+	}
+	if cfg.WhatIfAbove != 0 {
+		t.Fatalf("expected every order to be previewed by default got threshold %v", cfg.WhatIfAbove)
+	}
 	// Now set SMTP recipient to enable SMTP parsing
 	t.Setenv("IBTUI_SMTP_TO", "ops@example.com")
 	t.Setenv("IBTUI_SMTP_HOST", "smtp.example.com")
@@ -29,7 +53,16 @@ func TestParseDotEnv_defaults_and_smtp_detection(t *testing.T) {
 	t.Setenv("IBTUI_SMTP_SENDER_EMAIL", "sender@example.com")
 	t.Setenv("IBTUI_SMTP_RECIPIENT_EMAIL", "recipient@example.com")
 
-	cfg2 := ParseDotEnv()
+	t.Setenv("IBTUI_RISK_MAX_NOTIONAL", "25000")
+	t.Setenv("IBTUI_RISK_ALLOWED_SYMBOLS", "AAPL, MSFT,")
+
+	cfg2, err := ParseDotEnv()
+	if err != nil {
+		t.Fatalf("ParseDotEnv returned unexpected error: %v", err)
+	}
+	if cfg2.Risk.MaxNotional != 25000 || !slices.Equal(cfg2.Risk.AllowedSymbols, []string{"AAPL", "MSFT"}) {
+		t.Fatalf("expected risk limits from env got %+v", cfg2.Risk)
+	}
 	if cfg2.SMTPHost != "smtp.example.com" {
 		t.Fatalf("expected smtp host smtp.example.com got %s", cfg2.SMTPHost)
 	}
@@ -37,3 +70,14 @@ func TestParseDotEnv_defaults_and_smtp_detection(t *testing.T) {
 		t.Fatalf("expected smtp port 2525 got %d", cfg2.SMTPPort)
 	}
 }
+
+func TestParseDotEnv_bad_risk_limit(t *testing.T) {
+	os.Clearenv()
+	t.Setenv("IBTUI_RISK_MAX_NOTIONAL", "100,000")
+	t.Setenv("IBTUI_RISK_MAX_QUANTITY", "-5")
+	_, err := ParseDotEnv()
+	if !errors.Is(err, ErrBadNumber) || !strings.Contains(err.Error(), "IBTUI_RISK_MAX_NOTIONAL") ||
+		!strings.Contains(err.Error(), "IBTUI_RISK_MAX_QUANTITY") {
+		t.Fatalf("expected ErrBadNumber naming both limits got %v", err)
+	}
+}
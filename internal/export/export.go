@@ -0,0 +1,243 @@
+// Package export writes journaled trades, positions and account summaries to
+// files other tools import: CSV, JSON Lines or OFX.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/journal"
+	"github.com/glenntam/ibtui/internal/state"
+)
+
+// Format is a file format to export to.
+type Format string
+
+// Export formats.
+const (
+	CSV   Format = "csv"
+	JSONL Format = "jsonl"
+	OFX   Format = "ofx"
+)
+
+const (
+	filePermission = 0o600 // RW for owner only
+	dirPermission  = 0o700 // RWX for owner only
+	dateLayout     = "20060102"
+)
+
+// ErrUnknownFormat occurs when parsing an export format that doesn't exist.
+var ErrUnknownFormat = errors.New("unknown export format, expected csv, jsonl or ofx")
+
+// Data is everything one export writes. Trades are those between From and To;
+// positions and summaries are as of Generated.
+type Data struct {
+	From      time.Time // Zero for the journal's start
+	To        time.Time // Zero for Generated
+	Generated time.Time
+	Trades    []journal.Trade
+	Positions []state.PortfolioItem
+	Summaries map[string]state.AccountSummary
+}
+
+// ParseFormat reads a format name, e.g. "csv".
+func ParseFormat(s string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimSpace(s)))
+	if !slices.Contains([]Format{CSV, JSONL, OFX}, f) {
+		return "", fmt.Errorf("couldn't parse export format %q: %w", s, ErrUnknownFormat)
+	}
+	return f, nil
+}
+
+// Export writes d into dir, creating it if needed, and returns the paths written.
+// CSV and JSONL write one file each for trades, positions and summaries;
+// OFX writes a single statement.
+func Export(dir string, f Format, d Data) ([]string, error) {
+	if err := os.MkdirAll(dir, dirPermission); err != nil {
+		return nil, fmt.Errorf("couldn't create export directory: %w", err)
+	}
+	prefix := filepath.Join(dir, "ibtui-"+d.period()+"-")
+	switch f {
+	case CSV:
+		return writeFiles(map[string]func(io.Writer) error{
+			prefix + "trades.csv":    func(w io.Writer) error { return writeTradesCSV(w, d.Trades) },
+			prefix + "positions.csv": func(w io.Writer) error { return writePositionsCSV(w, d.Positions) },
+			prefix + "summary.csv":   func(w io.Writer) error { return writeSummaryCSV(w, d.Summaries) },
+		})
+	case JSONL:
+		return writeFiles(map[string]func(io.Writer) error{
+			prefix + "trades.jsonl":    func(w io.Writer) error { return writeJSONL(w, d.Trades) },
+			prefix + "positions.jsonl": func(w io.Writer) error { return writeJSONL(w, d.Positions) },
+			prefix + "summary.jsonl":   func(w io.Writer) error { return writeJSONL(w, summaryList(d.Summaries)) },
+		})
+	case OFX:
+		return writeFiles(map[string]func(io.Writer) error{
+			prefix + "statement.ofx": func(w io.Writer) error { return WriteOFX(w, d) },
+		})
+	}
+	return nil, fmt.Errorf("couldn't export to %q: %w", f, ErrUnknownFormat)
+}
+
+// Name the period covered, e.g. "20260101-20260331".
+func (d Data) period() string {
+	from := "start"
+	if !d.From.IsZero() {
+		from = d.From.Format(dateLayout)
+	}
+	to := d.Generated
+	if !d.To.IsZero() {
+		to = d.To.Add(-time.Nanosecond) // To is exclusive
+	}
+	return from + "-" + to.Format(dateLayout)
+}
+
+// Create each file and fill it with its writer. Returns the paths in name order.
+func writeFiles(files map[string]func(io.Writer) error) ([]string, error) {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		f, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, filePermission)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create export file: %w", err)
+		}
+		err = files[path](f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't write %v: %w", path, err)
+		}
+	}
+	return paths, nil
+}
+
+// Write trades as CSV rows under a header.
+func writeTradesCSV(w io.Writer, trades []journal.Trade) error {
+	rows := [][]string{{
+		"time", "account", "execId", "orderId", "conId", "symbol", "secType", "exchange", "currency",
+		"side", "quantity", "price", "multiplier", "commission", "commissionCurrency", "realizedPnl",
+		"note", "tags",
+	}}
+	for _, t := range trades {
+		rows = append(rows, []string{
+			t.Time.UTC().Format(time.RFC3339),
+			t.Account,
+			t.ExecID,
+			strconv.FormatInt(t.OrderID, 10),
+			strconv.FormatInt(t.ConID, 10),
+			t.Symbol,
+			t.SecType,
+			t.Exchange,
+			t.Currency,
+			t.Side,
+			formatFloat(t.Quantity),
+			formatFloat(t.Price),
+			formatFloat(t.Multiplier),
+			formatFloat(t.Commission),
+			t.CommissionCurrency,
+			formatFloat(t.RealizedPnL),
+			t.Text,
+			strings.Join(t.Tags, ";"),
+		})
+	}
+	return writeCSV(w, rows)
+}
+
+// Write positions as CSV rows under a header.
+func writePositionsCSV(w io.Writer, positions []state.PortfolioItem) error {
+	rows := [][]string{{
+		"account", "conId", "symbol", "secType", "currency", "position", "avgCost",
+		"marketPrice", "marketValue", "unrealizedPnl", "realizedPnl",
+	}}
+	for _, p := range positions {
+		rows = append(rows, []string{
+			p.Account,
+			strconv.FormatInt(p.ConID, 10),
+			p.Symbol,
+			p.SecType,
+			p.Currency,
+			formatFloat(p.Position),
+			formatFloat(p.AvgCost),
+			formatFloat(p.MarketPrice),
+			formatFloat(p.MarketValue),
+			formatFloat(p.UnrealizedPNL),
+			formatFloat(p.RealizedPNL),
+		})
+	}
+	return writeCSV(w, rows)
+}
+
+// Write account summaries as CSV rows under a header, one per account.
+func writeSummaryCSV(w io.Writer, summaries map[string]state.AccountSummary) error {
+	rows := [][]string{{
+		"account", "currency", "netLiquidation", "totalCashValue", "buyingPower",
+		"initMarginReq", "maintMarginReq", "excessLiquidity", "cushion",
+	}}
+	for _, s := range summaryList(summaries) {
+		rows = append(rows, []string{
+			s.Account,
+			s.Currency,
+			formatFloat(s.NetLiquidation),
+			formatFloat(s.TotalCashValue),
+			formatFloat(s.BuyingPower),
+			formatFloat(s.InitMarginReq),
+			formatFloat(s.MaintMarginReq),
+			formatFloat(s.ExcessLiquidity),
+			formatFloat(s.Cushion),
+		})
+	}
+	return writeCSV(w, rows)
+}
+
+// Write rows as CSV.
+func writeCSV(w io.Writer, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("couldn't write CSV: %w", err)
+	}
+	return nil
+}
+
+// Write each item as one line of JSON.
+func writeJSONL[T any](w io.Writer, items []T) error {
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return fmt.Errorf("couldn't write JSON line: %w", err)
+		}
+	}
+	return nil
+}
+
+// List summaries in account order.
+func summaryList(summaries map[string]state.AccountSummary) []state.AccountSummary {
+	list := make([]state.AccountSummary, 0, len(summaries))
+	for _, s := range summaries {
+		list = append(list, s)
+	}
+	slices.SortFunc(list, func(a, b state.AccountSummary) int {
+		return strings.Compare(a.Account, b.Account)
+	})
+	return list
+}
+
+// Format a number without exponent or trailing zeros. A value IB never
+// sent is left empty.
+func formatFloat(v float64) string {
+	if v == state.Unset {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
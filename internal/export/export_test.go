@@ -0,0 +1,160 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/journal"
+	"github.com/glenntam/ibtui/internal/state"
+)
+
+// Two trades and a position in one account, with its balances.
+func testData() Data {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	return Data{
+		From:      time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:        time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		Generated: t0.AddDate(0, 1, 0),
+		Trades: []journal.Trade{
+			{Execution: journal.Execution{
+				ExecID: "e1", Time: t0, Account: "U1", ConID: 265598, Symbol: "AAPL", SecType: "STK",
+				Side: "BOT", Quantity: 100, Price: 189.5, Commission: 1,
+			}, Note: journal.Note{Text: "breakout, retest", Tags: []string{"momentum", "daily"}}},
+			{Execution: journal.Execution{
+				ExecID: "e2", Time: t0.Add(time.Hour), Account: "U1", ConID: 265598, Symbol: "AAPL", SecType: "STK",
+				Side: "SLD", Quantity: 40, Price: 190, Commission: 1,
+			}},
+		},
+		Positions: []state.PortfolioItem{
+			{Account: "U1", ConID: 265598, Symbol: "AAPL", SecType: "STK", Position: 60, MarketPrice: 191, MarketValue: 11460},
+		},
+		Summaries: map[string]state.AccountSummary{
+			"U1": {Account: "U1", Currency: "USD", TotalCashValue: 5000, BuyingPower: 20000},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat(" OFX "); err != nil || f != OFX {
+		t.Fatalf("expected ofx got %v, %v", f, err)
+	}
+	if _, err := ParseFormat("xlsx"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("expected ErrUnknownFormat got %v", err)
+	}
+}
+
+func TestExport_CSV(t *testing.T) {
+	paths, err := Export(t.TempDir(), CSV, testData())
+	if err != nil {
+		t.Fatalf("Export returned unexpected error: %v", err)
+	}
+	if len(paths) != 3 || !strings.HasSuffix(paths[2], "ibtui-20260301-20260331-trades.csv") {
+		t.Fatalf("expected positions, summary and trades files named by period got %v", paths)
+	}
+	data, _ := os.ReadFile(paths[2])
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("couldn't read exported CSV: %v", err)
+	}
+	if len(rows) != 3 || rows[1][2] != "e1" || rows[1][16] != "breakout, retest" || rows[1][17] != "momentum;daily" {
+		t.Fatalf("expected a header and 2 trades with notes got %q", rows)
+	}
+}
+
+func TestExport_JSONL(t *testing.T) {
+	paths, err := Export(t.TempDir(), JSONL, testData())
+	if err != nil {
+		t.Fatalf("Export returned unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Clean(paths[0]))
+	var p state.PortfolioItem
+	if err = json.Unmarshal(bytes.TrimSpace(data), &p); err != nil || p.Position != 60 {
+		t.Fatalf("expected one position per line got %q, %v", data, err)
+	}
+	data, _ = os.ReadFile(filepath.Clean(paths[2]))
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"execId":"e1"`) ||
+		!strings.Contains(lines[0], `"tags":["momentum"`) {
+		t.Fatalf("expected one trade per line got %q", lines)
+	}
+}
+
+func TestWriteOFX(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteOFX(&b, testData()); err != nil {
+		t.Fatalf("WriteOFX returned unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(b.String(), "<?xml") || !strings.Contains(b.String(), `OFXHEADER="200"`) {
+		t.Fatalf("expected OFX 2 headers got %q", b.String()[:100])
+	}
+	var doc ofxDoc
+	body := b.String()[strings.Index(b.String(), "<OFX>"):]
+	if err := xml.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatalf("couldn't parse exported OFX: %v", err)
+	}
+	if len(doc.Stmts) != 1 || doc.Stmts[0].Stmt.AcctID != "U1" || doc.Stmts[0].Stmt.Bal.AvailCash != "5000" {
+		t.Fatalf("expected one statement for U1 with its cash got %+v", doc.Stmts)
+	}
+	list := doc.Stmts[0].Stmt.TranList
+	if len(list.Buys) != 1 || list.Buys[0].InvBuy.Total != "-18951" ||
+		list.Buys[0].InvBuy.InvTran.DTTrade != "20260302143000.000[0:GMT]" {
+		t.Fatalf("expected the buy to take its cost and commission got %+v", list.Buys)
+	}
+	if len(list.Sells) != 1 || list.Sells[0].InvSell.Units != "-40" || list.Sells[0].InvSell.Total != "7599" {
+		t.Fatalf("expected the sale to bring in its proceeds less commission got %+v", list.Sells)
+	}
+	if len(doc.Stocks) != 1 || doc.Stocks[0].Ticker != "AAPL" || doc.Stocks[0].SecID.UniqueID != "265598" {
+		t.Fatalf("expected AAPL listed once by contract ID got %+v", doc.Stocks)
+	}
+}
+
+func TestWriteOFX_short_and_other(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	d := Data{
+		Generated: t0.AddDate(0, 0, 1),
+		Trades: []journal.Trade{
+			{Execution: journal.Execution{ExecID: "s1", Time: t0, Account: "U1", ConID: 1, Symbol: "TSLA",
+				SecType: "STK", Side: "SLD", Quantity: 10, Price: 200}},
+			{Execution: journal.Execution{ExecID: "b1", Time: t0.Add(time.Hour), Account: "U1", ConID: 1, Symbol: "TSLA",
+				SecType: "STK", Side: "BOT", Quantity: 10, Price: 190}},
+			{Execution: journal.Execution{ExecID: "f1", Time: t0, Account: "U1", ConID: 2, Symbol: "ES",
+				SecType: "FUT", Side: "BOT", Quantity: 1, Price: 5000, Multiplier: 50}},
+		},
+		Positions: []state.PortfolioItem{
+			{Account: "U1", ConID: 2, Symbol: "ES", SecType: "FUT", Position: 1, MarketPrice: state.Unset},
+		},
+	}
+	var b bytes.Buffer
+	if err := WriteOFX(&b, d); err != nil {
+		t.Fatalf("WriteOFX returned unexpected error: %v", err)
+	}
+	var doc ofxDoc
+	if err := xml.Unmarshal([]byte(b.String()[strings.Index(b.String(), "<OFX>"):]), &doc); err != nil {
+		t.Fatalf("couldn't parse exported OFX: %v", err)
+	}
+	stmt := doc.Stmts[0].Stmt
+	list := stmt.TranList
+	if len(list.Sells) != 1 || list.Sells[0].SellType != "SELLSHORT" {
+		t.Fatalf("expected a sale from flat to be a short sale got %+v", list.Sells)
+	}
+	if len(list.Buys) != 1 || list.Buys[0].BuyType != "BUYTOCOVER" {
+		t.Fatalf("expected the buy back to cover the short got %+v", list.Buys)
+	}
+	if len(list.OtherBuys) != 1 || list.OtherBuys[0].InvBuy.Total != "-250000" {
+		t.Fatalf("expected the future as an other buy worth its multiplier got %+v", list.OtherBuys)
+	}
+	if len(stmt.OtherPos) != 1 || len(stmt.PosList) != 0 || stmt.OtherPos[0].UnitPrice != "" {
+		t.Fatalf("expected the future as an other position without a price got %+v", stmt)
+	}
+	if len(doc.Stocks) != 1 || len(doc.Others) != 1 || doc.Others[0].Ticker != "ES" {
+		t.Fatalf("expected TSLA listed as a stock and ES as other got %+v, %+v", doc.Stocks, doc.Others)
+	}
+}
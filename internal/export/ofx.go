@@ -0,0 +1,327 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/journal"
+)
+
+const (
+	ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n" +
+		`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	ofxTimeLayout = "20060102150405.000[0:GMT]"
+	ofxBrokerID   = "interactivebrokers.com"
+	ofxSecIDType  = "CONID" // IB contract ID, since executions don't carry a CUSIP
+	stockSecType  = "STK"
+)
+
+// OFX buy and sell types of stocks, by whether they open or close a short position.
+const (
+	ofxTypeBuy        = "BUY"
+	ofxTypeBuyToCover = "BUYTOCOVER"
+	ofxTypeSell       = "SELL"
+	ofxTypeSellShort  = "SELLSHORT"
+)
+
+// OFX 2.2 investment statement elements, only as much as accounting imports read.
+type (
+	ofxDoc struct {
+		XMLName xml.Name    `xml:"OFX"`
+		SignOn  ofxSignOn   `xml:"SIGNONMSGSRSV1>SONRS"`
+		Stmts   []ofxStmtTx `xml:"INVSTMTMSGSRSV1>INVSTMTTRNRS"`
+		Stocks  []ofxSecInf `xml:"SECLISTMSGSRSV1>SECLIST>STOCKINFO"`
+		Others  []ofxSecInf `xml:"SECLISTMSGSRSV1>SECLIST>OTHERINFO"`
+	}
+	ofxStatus struct {
+		Code     int    `xml:"CODE"`
+		Severity string `xml:"SEVERITY"`
+	}
+	ofxSignOn struct {
+		Status   ofxStatus `xml:"STATUS"`
+		DTServer string    `xml:"DTSERVER"`
+		Language string    `xml:"LANGUAGE"`
+	}
+	ofxStmtTx struct {
+		TrnUID string    `xml:"TRNUID"`
+		Status ofxStatus `xml:"STATUS"`
+		Stmt   ofxStmt   `xml:"INVSTMTRS"`
+	}
+	ofxStmt struct {
+		DTAsOf   string     `xml:"DTASOF"`
+		CurDef   string     `xml:"CURDEF"`
+		BrokerID string     `xml:"INVACCTFROM>BROKERID"`
+		AcctID   string     `xml:"INVACCTFROM>ACCTID"`
+		TranList ofxTrans   `xml:"INVTRANLIST"`
+		PosList  []ofxPos   `xml:"INVPOSLIST>POSSTOCK"`
+		OtherPos []ofxPos   `xml:"INVPOSLIST>POSOTHER"`
+		Bal      *ofxInvBal `xml:"INVBAL,omitempty"`
+	}
+	ofxTrans struct {
+		DTStart    string     `xml:"DTSTART"`
+		DTEnd      string     `xml:"DTEND"`
+		Buys       []ofxBuy   `xml:"BUYSTOCK"`
+		Sells      []ofxSell  `xml:"SELLSTOCK"`
+		OtherBuys  []ofxOther `xml:"BUYOTHER"`
+		OtherSells []ofxOther `xml:"SELLOTHER"`
+	}
+	ofxSecID struct {
+		UniqueID     string `xml:"UNIQUEID"`
+		UniqueIDType string `xml:"UNIQUEIDTYPE"`
+	}
+	ofxInvTx struct {
+		InvTran struct {
+			FITID   string `xml:"FITID"`
+			DTTrade string `xml:"DTTRADE"`
+			Memo    string `xml:"MEMO,omitempty"`
+		} `xml:"INVTRAN"`
+		SecID       ofxSecID `xml:"SECID"`
+		Units       string   `xml:"UNITS"`
+		UnitPrice   string   `xml:"UNITPRICE"`
+		Commission  string   `xml:"COMMISSION"`
+		Total       string   `xml:"TOTAL"`
+		SubAcctSec  string   `xml:"SUBACCTSEC"`
+		SubAcctFund string   `xml:"SUBACCTFUND"`
+	}
+	ofxBuy struct {
+		InvBuy  ofxInvTx `xml:"INVBUY"`
+		BuyType string   `xml:"BUYTYPE"`
+	}
+	ofxSell struct {
+		InvSell  ofxInvTx `xml:"INVSELL"`
+		SellType string   `xml:"SELLTYPE"`
+	}
+	ofxOther struct {
+		InvBuy  *ofxInvTx `xml:"INVBUY,omitempty"`
+		InvSell *ofxInvTx `xml:"INVSELL,omitempty"`
+	}
+	ofxPos struct {
+		SecID       ofxSecID `xml:"INVPOS>SECID"`
+		HeldInAcct  string   `xml:"INVPOS>HELDINACCT"`
+		PosType     string   `xml:"INVPOS>POSTYPE"`
+		Units       string   `xml:"INVPOS>UNITS"`
+		UnitPrice   string   `xml:"INVPOS>UNITPRICE"`
+		MktVal      string   `xml:"INVPOS>MKTVAL"`
+		DTPriceAsOf string   `xml:"INVPOS>DTPRICEASOF"`
+	}
+	ofxInvBal struct {
+		AvailCash     string `xml:"AVAILCASH"`
+		MarginBalance string `xml:"MARGINBALANCE"`
+		ShortBalance  string `xml:"SHORTBALANCE"`
+		BuyPower      string `xml:"BUYPOWER"`
+	}
+	ofxSecInf struct {
+		SecID   ofxSecID `xml:"SECINFO>SECID"`
+		SecName string   `xml:"SECINFO>SECNAME"`
+		Ticker  string   `xml:"SECINFO>TICKER"`
+	}
+)
+
+// WriteOFX writes d as an OFX 2.2 investment statement with one statement
+// per account. Stocks are written as stocks, with short sales and covers
+// told apart, and every other contract as OFX's "other" security, as its
+// option and bond aggregates need terms executions don't carry. Contracts
+// are identified by IB contract ID.
+func WriteOFX(w io.Writer, d Data) error {
+	doc := ofxDoc{
+		SignOn: ofxSignOn{
+			Status:   ofxStatus{Code: 0, Severity: "INFO"},
+			DTServer: ofxTime(d.Generated),
+			Language: "ENG",
+		},
+	}
+	secs := make(map[int64]ofxSecInf)
+	stocks := make(map[int64]bool)
+	held := openingPositions(d)
+	for i, account := range ofxAccounts(d) {
+		stmt := ofxStmt{
+			DTAsOf:   ofxTime(d.Generated),
+			CurDef:   "USD",
+			BrokerID: ofxBrokerID,
+			AcctID:   account,
+			TranList: ofxTrans{DTStart: ofxTime(d.From), DTEnd: ofxTime(d.Generated)},
+			PosList:  make([]ofxPos, 0),
+		}
+		if !d.To.IsZero() {
+			stmt.TranList.DTEnd = ofxTime(d.To)
+		}
+		if s, ok := d.Summaries[account]; ok {
+			stmt.CurDef = s.Currency
+			stmt.Bal = &ofxInvBal{
+				AvailCash:     formatFloat(s.TotalCashValue),
+				MarginBalance: "0",
+				ShortBalance:  "0",
+				BuyPower:      formatFloat(s.BuyingPower),
+			}
+		}
+		for _, t := range d.Trades {
+			if t.Account != account {
+				continue
+			}
+			secs[t.ConID] = ofxSecInf{SecID: ofxSecurity(t.ConID), SecName: t.Symbol, Ticker: t.Symbol}
+			stocks[t.ConID] = t.SecType == stockSecType
+			key := position{t.Account, t.ConID}
+			addOFXTrade(&stmt.TranList, t, held[key])
+			held[key] += signedQuantity(t)
+		}
+		for _, p := range d.Positions {
+			if p.Account != account {
+				continue
+			}
+			secs[p.ConID] = ofxSecInf{SecID: ofxSecurity(p.ConID), SecName: p.Symbol, Ticker: p.Symbol}
+			posType := "LONG"
+			if p.Position < 0 {
+				posType = "SHORT"
+			}
+			pos := ofxPos{
+				SecID:       ofxSecurity(p.ConID),
+				HeldInAcct:  "CASH",
+				PosType:     posType,
+				Units:       formatFloat(p.Position),
+				UnitPrice:   formatFloat(p.MarketPrice),
+				MktVal:      formatFloat(p.MarketValue),
+				DTPriceAsOf: ofxTime(d.Generated),
+			}
+			stocks[p.ConID] = p.SecType == stockSecType
+			if stocks[p.ConID] {
+				stmt.PosList = append(stmt.PosList, pos)
+			} else {
+				stmt.OtherPos = append(stmt.OtherPos, pos)
+			}
+		}
+		doc.Stmts = append(doc.Stmts, ofxStmtTx{
+			TrnUID: strconv.Itoa(i + 1),
+			Status: ofxStatus{Code: 0, Severity: "INFO"},
+			Stmt:   stmt,
+		})
+	}
+	for _, id := range slices.Sorted(maps.Keys(secs)) {
+		if stocks[id] {
+			doc.Stocks = append(doc.Stocks, secs[id])
+		} else {
+			doc.Others = append(doc.Others, secs[id])
+		}
+	}
+
+	if _, err := io.WriteString(w, ofxHeader); err != nil {
+		return fmt.Errorf("couldn't write OFX header: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("couldn't write OFX: %w", err)
+	}
+	return nil
+}
+
+// Add an execution to the transaction list as a buy or a sell, given the
+// position held before it. Totals are the cash moved: negative for a buy,
+// positive for a sale, net of commission.
+func addOFXTrade(list *ofxTrans, t journal.Trade, held float64) {
+	mult := t.Multiplier
+	if mult == 0 {
+		mult = 1
+	}
+	value := t.Quantity * t.Price * mult
+	tx := ofxInvTx{
+		SecID:       ofxSecurity(t.ConID),
+		UnitPrice:   formatFloat(t.Price),
+		Commission:  formatFloat(math.Abs(t.Commission)),
+		SubAcctSec:  "CASH",
+		SubAcctFund: "CASH",
+	}
+	tx.InvTran.FITID = t.ExecID
+	tx.InvTran.DTTrade = ofxTime(t.Time)
+	tx.InvTran.Memo = t.Text
+	stock := t.SecType == stockSecType
+	if t.Side == "SLD" {
+		tx.Units = formatFloat(-t.Quantity)
+		tx.Total = formatFloat(value - math.Abs(t.Commission))
+		if !stock {
+			list.OtherSells = append(list.OtherSells, ofxOther{InvSell: &tx})
+			return
+		}
+		sellType := ofxTypeSell
+		if held-t.Quantity < 0 {
+			sellType = ofxTypeSellShort
+		}
+		list.Sells = append(list.Sells, ofxSell{InvSell: tx, SellType: sellType})
+		return
+	}
+	tx.Units = formatFloat(t.Quantity)
+	tx.Total = formatFloat(-value - math.Abs(t.Commission))
+	if !stock {
+		list.OtherBuys = append(list.OtherBuys, ofxOther{InvBuy: &tx})
+		return
+	}
+	buyType := ofxTypeBuy
+	if held < 0 {
+		buyType = ofxTypeBuyToCover
+	}
+	list.Buys = append(list.Buys, ofxBuy{InvBuy: tx, BuyType: buyType})
+}
+
+// position is one account's holding of one contract.
+type position struct {
+	account string
+	conID   int64
+}
+
+// The position held before the first exported trade of each contract. It
+// can only be worked back from the current positions when the trades run
+// up to now; otherwise positions start flat.
+func openingPositions(d Data) map[position]float64 {
+	held := make(map[position]float64)
+	if !d.To.IsZero() {
+		return held
+	}
+	for _, p := range d.Positions {
+		held[position{p.Account, p.ConID}] = p.Position
+	}
+	for _, t := range d.Trades {
+		held[position{t.Account, t.ConID}] -= signedQuantity(t)
+	}
+	return held
+}
+
+// The change an execution makes to the position.
+func signedQuantity(t journal.Trade) float64 {
+	if t.Side == "SLD" {
+		return -t.Quantity
+	}
+	return t.Quantity
+}
+
+// Every account with a trade, position or summary, in order.
+func ofxAccounts(d Data) []string {
+	seen := make(map[string]bool)
+	for _, t := range d.Trades {
+		seen[t.Account] = true
+	}
+	for _, p := range d.Positions {
+		seen[p.Account] = true
+	}
+	for account := range d.Summaries {
+		seen[account] = true
+	}
+	return slices.Sorted(maps.Keys(seen))
+}
+
+// Identify a contract in OFX.
+func ofxSecurity(conID int64) ofxSecID {
+	return ofxSecID{UniqueID: strconv.FormatInt(conID, 10), UniqueIDType: ofxSecIDType}
+}
+
+// Format t as an OFX date time in GMT, or the Unix epoch for an open range.
+func ofxTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(ofxTimeLayout)
+}
@@ -0,0 +1,251 @@
+// Package flex reads executions from IB Flex Query XML statements, both
+// Activity statements' Trades and Trade Confirmation statements, so history
+// from before ibtui, or from other platforms, can be added to the journal.
+package flex
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/glenntam/ibtui/internal/journal"
+)
+
+// Prefix of the IDs made from trade IDs for rows without an exec ID.
+const tradeIDPrefix = "flex:"
+
+// Layouts of a Flex date and date-time once separators are stripped.
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102150405"
+)
+
+// ErrNotFlexQuery occurs when reading XML that isn't a Flex Query statement.
+var ErrNotFlexQuery = errors.New("not a Flex Query statement")
+
+// trade holds the attributes of an Activity statement's <Trade> or a Trade
+// Confirmation's <TradeConfirm>. The two name some attributes differently.
+type trade struct {
+	LevelOfDetail        string `xml:"levelOfDetail,attr"`
+	AccountID            string `xml:"accountId,attr"`
+	AssetCategory        string `xml:"assetCategory,attr"`
+	Symbol               string `xml:"symbol,attr"`
+	ConID                string `xml:"conid,attr"`
+	Currency             string `xml:"currency,attr"`
+	Multiplier           string `xml:"multiplier,attr"`
+	Exchange             string `xml:"exchange,attr"`
+	IBExecID             string `xml:"ibExecID,attr"`
+	ExecID               string `xml:"execID,attr"`
+	TradeID              string `xml:"tradeID,attr"`
+	OrigTradeID          string `xml:"origTradeID,attr"`
+	IBOrderID            string `xml:"ibOrderID,attr"`
+	DateTime             string `xml:"dateTime,attr"`
+	TradeDate            string `xml:"tradeDate,attr"`
+	TradeTime            string `xml:"tradeTime,attr"`
+	BuySell              string `xml:"buySell,attr"`
+	Quantity             string `xml:"quantity,attr"`
+	TradePrice           string `xml:"tradePrice,attr"`
+	Price                string `xml:"price,attr"`
+	IBCommission         string `xml:"ibCommission,attr"`
+	IBCommissionCurrency string `xml:"ibCommissionCurrency,attr"`
+	Commission           string `xml:"commission,attr"`
+	CommissionCurrency   string `xml:"commissionCurrency,attr"`
+	FifoPnlRealized      string `xml:"fifoPnlRealized,attr"`
+}
+
+// ReadFile reads the executions in a Flex Query XML file. See Parse.
+func ReadFile(path string, loc *time.Location) ([]journal.Execution, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open Flex Query statement: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	execs, err := Parse(f, loc)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't import %v: %w", path, err)
+	}
+	return execs, nil
+}
+
+// Parse reads every execution in a Flex Query statement, in file order.
+// Flex times carry no zone, so they are read in loc, the zone the query was
+// set up to report in. Summary rows are left out, as are cancelled trades
+// along with the rows cancelling them.
+func Parse(r io.Reader, loc *time.Location) ([]journal.Execution, error) {
+	d := xml.NewDecoder(r)
+	var trades []trade
+	cancelled := make(map[string]bool)
+	isFlex := false
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read Flex Query XML: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "FlexQueryResponse", "FlexStatements":
+			isFlex = true
+		case "Trade", "TradeConfirm":
+			var t trade
+			if err = d.DecodeElement(&t, &start); err != nil {
+				return nil, fmt.Errorf("couldn't read Flex Query trade: %w", err)
+			}
+			if t.isCancellation() {
+				cancelled[t.OrigTradeID] = true
+				continue
+			}
+			trades = append(trades, t)
+		}
+	}
+	if !isFlex {
+		return nil, ErrNotFlexQuery
+	}
+	var execs []journal.Execution
+	for _, t := range trades {
+		if t.TradeID != "" && cancelled[t.TradeID] {
+			continue
+		}
+		e, ok, err := t.execution(loc)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			execs = append(execs, e)
+		}
+	}
+	return execs, nil
+}
+
+// HasExecID reports whether e was read with IB's exec ID. Without one it
+// can't match executions captured live by ID, so match it with
+// journal.HasFill instead.
+func HasExecID(e journal.Execution) bool {
+	return !strings.HasPrefix(e.ExecID, tradeIDPrefix)
+}
+
+// Report whether the row cancels an earlier trade, which is listed too.
+func (t trade) isCancellation() bool {
+	return t.isExecution() && strings.Contains(strings.ToUpper(t.BuySell), "(CA.)")
+}
+
+// Report whether the row is one execution rather than a summary.
+func (t trade) isExecution() bool {
+	return t.LevelOfDetail == "" || strings.EqualFold(t.LevelOfDetail, "EXECUTION")
+}
+
+// Convert a trade to an execution. Reports false for rows that aren't one
+// execution, e.g. order or symbol summaries.
+func (t trade) execution(loc *time.Location) (journal.Execution, bool, error) {
+	if !t.isExecution() {
+		return journal.Execution{}, false, nil
+	}
+	side := ""
+	switch buySell := strings.ToUpper(t.BuySell); {
+	case strings.HasPrefix(buySell, "BUY"):
+		side = "BOT"
+	case strings.HasPrefix(buySell, "SELL"):
+		side = "SLD"
+	default:
+		return journal.Execution{}, false, nil
+	}
+	execID := firstOf(t.IBExecID, t.ExecID)
+	if execID == "" && t.TradeID != "" {
+		execID = tradeIDPrefix + t.TradeID // Only seen in older or other platforms' statements
+	}
+	if execID == "" {
+		return journal.Execution{}, false, nil
+	}
+	at, err := t.time(loc)
+	if err != nil {
+		return journal.Execution{}, false, fmt.Errorf("couldn't read time of trade %v: %w", execID, err)
+	}
+	return journal.Execution{
+		ExecID:             execID,
+		PermID:             parseInt(t.IBOrderID),
+		Time:               at,
+		Account:            t.AccountID,
+		ConID:              parseInt(t.ConID),
+		Symbol:             t.Symbol,
+		SecType:            t.AssetCategory,
+		Exchange:           t.Exchange,
+		Currency:           t.Currency,
+		Side:               side,
+		Quantity:           math.Abs(parseFloat(t.Quantity)),
+		Price:              parseFloat(firstOf(t.TradePrice, t.Price)),
+		Multiplier:         parseFloat(t.Multiplier),
+		Commission:         -parseFloat(firstOf(t.IBCommission, t.Commission)), // Flex shows costs as negative
+		CommissionCurrency: firstOf(t.IBCommissionCurrency, t.CommissionCurrency, t.Currency),
+		RealizedPnL:        parseFloat(t.FifoPnlRealized),
+	}, true, nil
+}
+
+// Read when the trade happened, from its date-time or its separate date and time.
+// Flex formats vary with the query's settings, e.g. "20250110;093000" or
+// "2025-01-10, 09:30:00", so only the digits are read.
+func (t trade) time(loc *time.Location) (time.Time, error) {
+	s := digits(t.DateTime)
+	if s == "" {
+		s = digits(t.TradeDate) + digits(t.TradeTime)
+	}
+	layout := dateTimeLayout
+	if len(s) == len(dateLayout) {
+		layout = dateLayout
+	}
+	at, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("couldn't parse Flex date %q: %w", t.DateTime+t.TradeDate+t.TradeTime, err)
+	}
+	return at, nil
+}
+
+// Keep only the digits of s.
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// Return the first of ss that isn't blank.
+func firstOf(ss ...string) string {
+	for _, s := range ss {
+		if s = strings.TrimSpace(s); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// Flex leaves numbers blank when they don't apply.
+func parseFloat(s string) float64 {
+	v, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// Flex leaves IDs blank when they don't apply.
+func parseInt(s string) int64 {
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,72 @@
+package flex
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadFile_activity(t *testing.T) {
+	execs, err := ReadFile("testdata/activity.xml", time.UTC)
+	if err != nil {
+		t.Fatalf("ReadFile returned unexpected error: %v", err)
+	}
+	if len(execs) != 3 {
+		t.Fatalf("expected 3 executions without the order summary and cancelled trade got %+v", execs)
+	}
+	e := execs[0]
+	if e.ExecID != "0000e0d5.6781a2b3.01.01" || e.PermID != 4011 || e.ConID != 265598 || e.Side != "BOT" ||
+		e.Quantity != 60 || e.Price != 189.5 || e.Commission != 0.6 || e.Exchange != "ARCA" {
+		t.Fatalf("expected the first AAPL fill got %+v", e)
+	}
+	if !e.Time.Equal(time.Date(2025, 1, 10, 9, 30, 12, 0, time.UTC)) {
+		t.Fatalf("expected 2025-01-10 09:30:12 got %v", e.Time)
+	}
+	e = execs[2]
+	if e.Side != "SLD" || e.Quantity != 1 || e.Price != 6010.25 || e.Multiplier != 50 || e.RealizedPnL != 312.5 {
+		t.Fatalf("expected the ES sale with its multiplier and realized P&L got %+v", e)
+	}
+	for _, e := range execs {
+		if e.Symbol == "MSFT" {
+			t.Fatalf("expected the cancelled MSFT trade left out with its cancellation got %+v", e)
+		}
+	}
+}
+
+func TestReadFile_confirms(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	execs, err := ReadFile("testdata/confirms.xml", ny)
+	if err != nil {
+		t.Fatalf("ReadFile returned unexpected error: %v", err)
+	}
+	if len(execs) != 2 {
+		t.Fatalf("expected 2 trade confirmations got %+v", execs)
+	}
+	e := execs[0]
+	if e.ExecID != "0000f1a2.67a0b1c2.01.01" || e.Side != "SLD" || e.Price != 240.1 || e.CommissionCurrency != "EUR" ||
+		!e.Time.Equal(time.Date(2025, 1, 20, 19, 5, 6, 0, time.UTC)) {
+		t.Fatalf("expected the SAP sale at 14:05:06 New York time got %+v", e)
+	}
+	if !HasExecID(e) {
+		t.Fatalf("expected the SAP sale to have its exec ID")
+	}
+	if e = execs[1]; e.ExecID != "flex:900" || e.CommissionCurrency != "EUR" || e.Time.Hour() != 0 {
+		t.Fatalf("expected a trade ID and a date-only time to stand in got %+v", e)
+	}
+	if HasExecID(e) {
+		t.Fatalf("expected a trade ID standing in to not count as an exec ID")
+	}
+}
+
+func TestParse_notFlex(t *testing.T) {
+	if _, err := Parse(strings.NewReader("<OFX><SIGNONMSGSRSV1/></OFX>"), time.UTC); !errors.Is(err, ErrNotFlexQuery) {
+		t.Fatalf("expected ErrNotFlexQuery got %v", err)
+	}
+	if _, err := Parse(strings.NewReader("<FlexQueryResponse><Trades><Trade"), time.UTC); err == nil {
+		t.Fatalf("expected an error for truncated XML")
+	}
+}
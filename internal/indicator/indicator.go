@@ -0,0 +1,186 @@
+// Package indicator computes technical indicators over price series. Each
+// function returns one value per input, NaN until enough inputs have been
+// seen, so results line up with the bars they came from and can simply be
+// recomputed as bars arrive. It depends on nothing else in ibtui, so algos
+// can use it as well as the chart.
+package indicator
+
+import "math"
+
+const (
+	rsiScale      = 100 // RSI runs from 0 to this
+	typicalPrices = 3   // High, low and close
+)
+
+// Bands are a moving average with an upper and lower band around it.
+type Bands struct {
+	Middle []float64
+	Upper  []float64
+	Lower  []float64
+}
+
+// MACDLines are the MACD line, its signal line and the histogram of their difference.
+type MACDLines struct {
+	MACD      []float64
+	Signal    []float64
+	Histogram []float64
+}
+
+// SMA is the simple moving average of the last period values.
+func SMA(values []float64, period int) []float64 {
+	out := nans(len(values))
+	if period < 1 {
+		return out
+	}
+	sum, n := 0.0, 0
+	for i, v := range values {
+		if math.IsNaN(v) {
+			sum, n = 0, 0 // Restart after a gap, e.g. the lead-in of another indicator
+			continue
+		}
+		sum += v
+		n++
+		if n > period {
+			sum -= values[i-period]
+			n = period
+		}
+		if n == period {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+// EMA is the exponential moving average with smoothing 2/(period+1), seeded
+// with the SMA of its first period values. Leading NaNs are skipped, so an
+// EMA can be taken of another indicator.
+func EMA(values []float64, period int) []float64 {
+	out := nans(len(values))
+	if period < 1 {
+		return out
+	}
+	seed := SMA(values, period)
+	k := 2 / float64(period+1)
+	prev := math.NaN()
+	for i, v := range values {
+		switch {
+		case !math.IsNaN(prev) && !math.IsNaN(v):
+			prev += k * (v - prev)
+		case math.IsNaN(prev):
+			prev = seed[i]
+		}
+		out[i] = prev
+	}
+	return out
+}
+
+// VWAP is the volume weighted average price, restarting wherever
+// newSession is true, e.g. at each day's first bar. A nil newSession never
+// restarts. price is usually each bar's Typical price.
+func VWAP(price, volume []float64, newSession []bool) []float64 {
+	out := nans(len(price))
+	var pv, v float64
+	for i := range price {
+		if i < len(newSession) && newSession[i] {
+			pv, v = 0, 0
+		}
+		pv += price[i] * volume[i]
+		v += volume[i]
+		if v > 0 {
+			out[i] = pv / v
+		}
+	}
+	return out
+}
+
+// Typical is each bar's (high + low + close) / 3.
+func Typical(high, low, closes []float64) []float64 {
+	out := make([]float64, len(closes))
+	for i := range closes {
+		out[i] = (high[i] + low[i] + closes[i]) / typicalPrices
+	}
+	return out
+}
+
+// Bollinger is the SMA of period values with bands k population standard
+// deviations above and below it.
+func Bollinger(values []float64, period int, k float64) Bands {
+	b := Bands{Middle: SMA(values, period), Upper: nans(len(values)), Lower: nans(len(values))}
+	for i, mid := range b.Middle {
+		if math.IsNaN(mid) {
+			continue
+		}
+		var squares float64
+		for _, v := range values[i-period+1 : i+1] {
+			squares += (v - mid) * (v - mid)
+		}
+		d := k * math.Sqrt(squares/float64(period))
+		b.Upper[i], b.Lower[i] = mid+d, mid-d
+	}
+	return b
+}
+
+// RSI is Wilder's relative strength index over period changes, from 0 to 100.
+func RSI(values []float64, period int) []float64 {
+	out := nans(len(values))
+	if period < 1 || len(values) <= period {
+		return out
+	}
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		g, l := change(values[i-1], values[i])
+		gain += g
+		loss += l
+	}
+	gain, loss = gain/float64(period), loss/float64(period)
+	out[period] = strength(gain, loss)
+	for i := period + 1; i < len(values); i++ {
+		g, l := change(values[i-1], values[i])
+		gain = (gain*float64(period-1) + g) / float64(period)
+		loss = (loss*float64(period-1) + l) / float64(period)
+		out[i] = strength(gain, loss)
+	}
+	return out
+}
+
+// MACD is the fast EMA less the slow EMA, with a signal EMA of that over signal values.
+func MACD(values []float64, fast, slow, signal int) MACDLines {
+	f, s := EMA(values, fast), EMA(values, slow)
+	m := MACDLines{MACD: make([]float64, len(values)), Histogram: make([]float64, len(values))}
+	for i := range values {
+		m.MACD[i] = f[i] - s[i]
+	}
+	m.Signal = EMA(m.MACD, signal)
+	for i := range values {
+		m.Histogram[i] = m.MACD[i] - m.Signal[i]
+	}
+	return m
+}
+
+// Split the move from a to b into a gain and a loss, both positive.
+func change(a, b float64) (float64, float64) {
+	if b > a {
+		return b - a, 0
+	}
+	return 0, a - b
+}
+
+// RSI from average gain and loss. With no losses it is 100, or 50 if nothing moved.
+func strength(gain, loss float64) float64 {
+	if loss == 0 {
+		if gain == 0 {
+			return rsiScale / 2 // Nothing moved
+		}
+		return rsiScale
+	}
+	return rsiScale - rsiScale/(1+gain/loss)
+}
+
+// A series of n NaNs.
+func nans(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+// Compare got to want, where a NaN in want expects a NaN.
+func check(t *testing.T, name string, got, want []float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %d values got %v", name, len(want), got)
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) != math.IsNaN(got[i]) || !math.IsNaN(want[i]) && !near(got[i], want[i]) {
+			t.Fatalf("%s: value %d = %v, want %v (all %v)", name, i, got[i], want[i], got)
+		}
+	}
+}
+
+func TestSMA(t *testing.T) {
+	nan := math.NaN()
+	check(t, "SMA", SMA([]float64{1, 2, 3, 4, 5}, 3), []float64{nan, nan, 2, 3, 4})
+	check(t, "SMA after a gap", SMA([]float64{nan, 2, 4, 6}, 2), []float64{nan, nan, 3, 5})
+	check(t, "SMA of period 0", SMA([]float64{1, 2}, 0), []float64{nan, nan})
+}
+
+func TestEMA(t *testing.T) {
+	nan := math.NaN()
+	// Seeded with the SMA of 1, 2 and 3, then smoothed by 2/(3+1).
+	check(t, "EMA", EMA([]float64{1, 2, 3, 4, 5}, 3), []float64{nan, nan, 2, 3, 4})
+	check(t, "EMA of a jump", EMA([]float64{2, 2, 2, 10}, 3), []float64{nan, nan, 2, 6})
+	check(t, "EMA of a lead-in", EMA([]float64{nan, 4, 6, 8}, 2), []float64{nan, nan, 5, 7})
+}
+
+func TestVWAP(t *testing.T) {
+	price := []float64{10, 20, 30, 40}
+	volume := []float64{1, 3, 0, 2}
+	check(t, "VWAP", VWAP(price, volume, nil), []float64{10, 17.5, 17.5, 25})
+	check(t, "VWAP by session", VWAP(price, volume, []bool{true, false, true, false}),
+		[]float64{10, 17.5, math.NaN(), 40})
+	check(t, "Typical", Typical([]float64{12}, []float64{9}, []float64{12}), []float64{11})
+}
+
+func TestBollinger(t *testing.T) {
+	b := Bollinger([]float64{2, 4, 4, 4, 5, 5, 7, 9}, 8, 2)
+	// Mean 5 with a population standard deviation of 2.
+	if !near(b.Middle[7], 5) || !near(b.Upper[7], 9) || !near(b.Lower[7], 1) || !math.IsNaN(b.Upper[6]) {
+		t.Fatalf("expected bands of 1, 5 and 9 on the last value only got %+v", b)
+	}
+}
+
+func TestRSI(t *testing.T) {
+	nan := math.NaN()
+	// Average gain 2/3 and loss 1/3, then Wilder smoothed with a gain of 2 to 10/9 and 2/9.
+	check(t, "RSI", RSI([]float64{10, 11, 10, 11, 13}, 3),
+		[]float64{nan, nan, nan, 66.666666667, 83.333333333})
+	check(t, "RSI rising", RSI([]float64{1, 2, 3}, 2), []float64{nan, nan, 100})
+	check(t, "RSI flat", RSI([]float64{1, 1, 1}, 2), []float64{nan, nan, 50})
+	check(t, "RSI too short", RSI([]float64{1, 2}, 2), []float64{nan, nan})
+}
+
+func TestMACD(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6}
+	m := MACD(values, 2, 3, 2)
+	// EMA2 is 1.5, 2.5, ... and EMA3 is 2, 3, ... from their seeds, so MACD is 0.5 throughout.
+	check(t, "MACD", m.MACD, []float64{math.NaN(), math.NaN(), 0.5, 0.5, 0.5, 0.5})
+	check(t, "MACD signal", m.Signal, []float64{math.NaN(), math.NaN(), math.NaN(), 0.5, 0.5, 0.5})
+	if !near(m.Histogram[5], 0) || !math.IsNaN(m.Histogram[2]) {
+		t.Fatalf("expected a flat histogram once the signal starts got %v", m.Histogram)
+	}
+}
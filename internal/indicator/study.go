@@ -0,0 +1,114 @@
+package indicator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnknownStudy occurs when a study names no indicator this package has.
+	ErrUnknownStudy = errors.New("unknown study")
+	// ErrBadSetting occurs when a study has too many settings, or one that isn't a positive number.
+	ErrBadSetting = errors.New("bad study setting")
+)
+
+// Kind names an indicator.
+type Kind string
+
+// Indicators a Study can name.
+const (
+	KindSMA       Kind = "sma"
+	KindEMA       Kind = "ema"
+	KindVWAP      Kind = "vwap"
+	KindBollinger Kind = "bb"
+	KindRSI       Kind = "rsi"
+	KindMACD      Kind = "macd"
+)
+
+// Study is an indicator and its settings, written as the kind followed by
+// comma separated settings, e.g. "sma50", "bb20,2" or "macd12,26,9".
+// Settings left off take their defaults.
+type Study struct {
+	Kind     Kind
+	Settings []float64
+}
+
+// Kinds lists every indicator a Study can name.
+func Kinds() []Kind {
+	return []Kind{KindSMA, KindEMA, KindVWAP, KindBollinger, KindRSI, KindMACD}
+}
+
+// Defaults returns the settings a study of kind k takes when left off:
+// periods, then for Bollinger Bands the width in standard deviations.
+func (k Kind) Defaults() []float64 {
+	switch k {
+	case KindSMA, KindEMA:
+		return []float64{20}
+	case KindBollinger:
+		return []float64{20, 2}
+	case KindRSI:
+		return []float64{14}
+	case KindMACD:
+		return []float64{12, 26, 9}
+	case KindVWAP:
+	}
+	return nil
+}
+
+// ParseStudies reads space separated studies, e.g. "sma20 ema50 rsi".
+func ParseStudies(s string) ([]Study, error) {
+	fields := strings.Fields(s)
+	studies := make([]Study, 0, len(fields))
+	for _, f := range fields {
+		study, err := ParseStudy(f)
+		if err != nil {
+			return nil, err
+		}
+		studies = append(studies, study)
+	}
+	return studies, nil
+}
+
+// ParseStudy reads one study, e.g. "bb20,2".
+func ParseStudy(s string) (Study, error) {
+	lower := strings.ToLower(s)
+	name := strings.TrimRight(lower, "0123456789.,")
+	k := Kind(name)
+	defaults := k.Defaults()
+	if defaults == nil && k != KindVWAP {
+		return Study{}, fmt.Errorf("couldn't parse study %q: %w", s, ErrUnknownStudy)
+	}
+	study := Study{Kind: k, Settings: defaults}
+	rest := lower[len(name):]
+	if rest == "" {
+		return study, nil
+	}
+	settings := strings.Split(rest, ",")
+	if len(settings) > len(defaults) {
+		return Study{}, fmt.Errorf("couldn't parse study %q: %w", s, ErrBadSetting)
+	}
+	for i, setting := range settings {
+		v, err := strconv.ParseFloat(setting, 64)
+		if err != nil || v <= 0 {
+			return Study{}, fmt.Errorf("couldn't parse study %q: %w", s, ErrBadSetting)
+		}
+		study.Settings[i] = v
+	}
+	return study, nil
+}
+
+// Period returns the study's i-th setting as a whole number of bars.
+func (s Study) Period(i int) int {
+	return max(int(s.Settings[i]), 1)
+}
+
+// String writes the study the way ParseStudy reads it, e.g. "bb20,2".
+func (s Study) String() string {
+	settings := make([]string, 0, len(s.Settings))
+	for _, v := range s.Settings {
+		settings = append(settings, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return string(s.Kind) + strings.Join(settings, ",")
+}
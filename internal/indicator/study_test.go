@@ -0,0 +1,35 @@
+package indicator
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseStudies(t *testing.T) {
+	studies, err := ParseStudies(" SMA50 bb20,2.5  vwap macd ")
+	if err != nil {
+		t.Fatalf("ParseStudies returned unexpected error: %v", err)
+	}
+	var got []string
+	for _, s := range studies {
+		got = append(got, s.String())
+	}
+	if want := []string{"sma50", "bb20,2.5", "vwap", "macd12,26,9"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v got %v", want, got)
+	}
+	if studies[1].Period(0) != 20 {
+		t.Fatalf("expected a Bollinger period of 20 got %d", studies[1].Period(0))
+	}
+	if studies, err = ParseStudies("ema9,"); !errors.Is(err, ErrBadSetting) {
+		t.Fatalf("expected ErrBadSetting for an empty setting got %v, %v", studies, err)
+	}
+	for _, s := range []string{"rsi14,3", "vwap5", "sma0"} {
+		if _, err = ParseStudy(s); !errors.Is(err, ErrBadSetting) {
+			t.Fatalf("expected ErrBadSetting for %q got %v", s, err)
+		}
+	}
+	if _, err = ParseStudy("adx14"); !errors.Is(err, ErrUnknownStudy) {
+		t.Fatalf("expected ErrUnknownStudy got %v", err)
+	}
+}
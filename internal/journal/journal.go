@@ -0,0 +1,400 @@
+// Package journal keeps every execution and completed order in a local bbolt
+// database, so trade history outlives the few days IB returns it for.
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	dirPermission  = 0o700 // RWX for owner only
+	filePermission = 0o600 // RW for owner only
+	openTimeout    = time.Second
+	timeKeyLayout  = "20060102T150405.000000000Z"
+
+	// Executions are keyed by time then ID so a range is one cursor scan;
+	// executionIDs maps each ID to that key.
+	bucketExecutions   = "executions"
+	bucketExecutionIDs = "executionIDs"
+	bucketOrders       = "orders"
+	bucketNotes        = "notes"
+	bucketLotPicks     = "lotPicks"
+)
+
+// ErrUnknownExecution occurs when noting an execution the journal doesn't have.
+var ErrUnknownExecution = errors.New("execution isn't in the journal")
+
+// ErrInUse occurs when another process, such as a running ibtui, has the journal open.
+var ErrInUse = errors.New("journal is in use by another process, e.g. a running ibtui")
+
+// Execution is one fill with its commission report, as stored.
+type Execution struct {
+	ExecID             string    `json:"execId"`
+	OrderID            int64     `json:"orderId"`
+	PermID             int64     `json:"permId"`
+	Time               time.Time `json:"time"`
+	Account            string    `json:"account"`
+	ConID              int64     `json:"conId"`
+	Symbol             string    `json:"symbol"`
+	SecType            string    `json:"secType"`
+	Exchange           string    `json:"exchange"`
+	Currency           string    `json:"currency"`
+	Side               string    `json:"side"` // BOT or SLD
+	Quantity           float64   `json:"quantity"`
+	Price              float64   `json:"price"`
+	Multiplier         float64   `json:"multiplier,omitempty"` // 0 for contracts without one, e.g. stocks
+	Commission         float64   `json:"commission"`
+	CommissionCurrency string    `json:"commissionCurrency"`
+	RealizedPnL        float64   `json:"realizedPnl"`
+}
+
+// Order is an order that finished working, as stored.
+type Order struct {
+	PermID     int64     `json:"permId"`
+	OrderID    int64     `json:"orderId"`
+	Account    string    `json:"account"`
+	Symbol     string    `json:"symbol"`
+	SecType    string    `json:"secType"`
+	Side       string    `json:"side"`
+	Quantity   float64   `json:"quantity"`
+	Type       string    `json:"type"`
+	LimitPrice float64   `json:"limitPrice"`
+	AuxPrice   float64   `json:"auxPrice"`
+	TIF        string    `json:"tif"`
+	Status     string    `json:"status"`
+	Filled     float64   `json:"filled"`
+	Remaining  float64   `json:"remaining"`
+	Reason     string    `json:"reason"`
+	Time       time.Time `json:"time"` // When it was recorded as done
+}
+
+// Note is the user's free text and tags on an execution.
+type Note struct {
+	Text string   `json:"text"`
+	Tags []string `json:"tags"`
+}
+
+// Trade is a stored execution together with its note.
+type Trade struct {
+	Execution
+	Note
+}
+
+// Journal is an open trade journal database.
+type Journal struct {
+	db *bolt.DB
+}
+
+// DefaultPath is journal.db in the user's data directory: $XDG_DATA_HOME/ibtui,
+// or ~/.local/share/ibtui when that isn't set.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("couldn't find the user's data directory: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "ibtui", "journal.db"), nil
+}
+
+// Open opens the journal at path, creating it and its directory if needed.
+// Only one process can have it open at a time.
+func Open(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), dirPermission); err != nil {
+		return nil, fmt.Errorf("couldn't create journal directory: %w", err)
+	}
+	db, err := bolt.Open(path, filePermission, &bolt.Options{Timeout: openTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("couldn't open journal %v: %w", path, ErrInUse)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open journal %v: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{
+			bucketExecutions, bucketExecutionIDs, bucketOrders, bucketNotes, bucketLotPicks,
+		} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return fmt.Errorf("couldn't create journal bucket %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("couldn't set up journal: %w", err)
+	}
+	return &Journal{db: db}, nil
+}
+
+// Close releases the database file.
+func (j *Journal) Close() error {
+	if err := j.db.Close(); err != nil {
+		return fmt.Errorf("couldn't close journal: %w", err)
+	}
+	return nil
+}
+
+// PutExecutions stores executions, replacing any already stored with the same
+// ID, e.g. once its commission report has arrived. It reports how many were new.
+func (j *Journal) PutExecutions(execs []Execution) (int, error) {
+	return j.putExecutions(execs, true)
+}
+
+// AddExecutions stores only the executions the journal doesn't have yet,
+// leaving those it has untouched, e.g. when importing statements that overlap
+// what was captured live. It reports how many were added.
+func (j *Journal) AddExecutions(execs []Execution) (int, error) {
+	return j.putExecutions(execs, false)
+}
+
+// HasExecution reports whether an execution with execID is stored.
+func (j *Journal) HasExecution(execID string) (bool, error) {
+	found := false
+	err := j.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket([]byte(bucketExecutionIDs)).Get([]byte(execID)) != nil
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("couldn't read journal: %w", err)
+	}
+	return found, nil
+}
+
+// HasFill reports whether an execution at e's time, for the same account,
+// contract, side, quantity and price, is stored under any ID. Use it for
+// executions whose source gave no exec ID to match them by.
+func (j *Journal) HasFill(e Execution) (bool, error) {
+	found := false
+	err := j.db.View(func(tx *bolt.Tx) error {
+		c, prefix := tx.Bucket([]byte(bucketExecutions)).Cursor(), []byte(timeKey(e.Time)+"/")
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var stored Execution
+			if err := json.Unmarshal(v, &stored); err != nil {
+				return fmt.Errorf("couldn't decode execution %s: %w", k, err)
+			}
+			if stored.Account == e.Account && stored.ConID == e.ConID && stored.Side == e.Side &&
+				stored.Quantity == e.Quantity && stored.Price == e.Price {
+				found = true
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("couldn't read journal: %w", err)
+	}
+	return found, nil
+}
+
+// Trades returns the executions on or after from and before to, oldest first,
+// with their notes. A zero from or to leaves that end open.
+func (j *Journal) Trades(from, to time.Time) ([]Trade, error) {
+	trades := make([]Trade, 0)
+	err := j.db.View(func(tx *bolt.Tx) error {
+		c, notes := tx.Bucket([]byte(bucketExecutions)).Cursor(), tx.Bucket([]byte(bucketNotes))
+		k, v := c.First()
+		if !from.IsZero() {
+			k, v = c.Seek([]byte(timeKey(from)))
+		}
+		for ; k != nil; k, v = c.Next() {
+			var t Trade
+			if err := json.Unmarshal(v, &t.Execution); err != nil {
+				return fmt.Errorf("couldn't decode execution %s: %w", k, err)
+			}
+			if !to.IsZero() && !t.Time.Before(to) {
+				break
+			}
+			if n := notes.Get([]byte(t.ExecID)); n != nil {
+				if err := json.Unmarshal(n, &t.Note); err != nil {
+					return fmt.Errorf("couldn't decode note on %v: %w", t.ExecID, err)
+				}
+			}
+			trades = append(trades, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read trades from journal: %w", err)
+	}
+	return trades, nil
+}
+
+// SetNote replaces the note on a stored execution. An empty note removes it.
+func (j *Journal) SetNote(execID string, n Note) error {
+	err := j.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(bucketExecutionIDs)).Get([]byte(execID)) == nil {
+			return fmt.Errorf("couldn't note %v: %w", execID, ErrUnknownExecution)
+		}
+		notes := tx.Bucket([]byte(bucketNotes))
+		if n.Text == "" && len(n.Tags) == 0 {
+			if err := notes.Delete([]byte(execID)); err != nil {
+				return fmt.Errorf("couldn't delete note on %v: %w", execID, err)
+			}
+			return nil
+		}
+		data, err := json.Marshal(n)
+		if err != nil {
+			return fmt.Errorf("couldn't encode note: %w", err)
+		}
+		if err = notes.Put([]byte(execID), data); err != nil {
+			return fmt.Errorf("couldn't store note on %v: %w", execID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't write note to journal: %w", err)
+	}
+	return nil
+}
+
+// SetLotPicks records which lots a closing execution sells, as the IDs of the
+// executions that opened them. No IDs removes the choice.
+func (j *Journal) SetLotPicks(execID string, openIDs []string) error {
+	err := j.db.Update(func(tx *bolt.Tx) error {
+		picks := tx.Bucket([]byte(bucketLotPicks))
+		if len(openIDs) == 0 {
+			if err := picks.Delete([]byte(execID)); err != nil {
+				return fmt.Errorf("couldn't delete lot picks of %v: %w", execID, err)
+			}
+			return nil
+		}
+		data, err := json.Marshal(openIDs)
+		if err != nil {
+			return fmt.Errorf("couldn't encode lot picks: %w", err)
+		}
+		if err = picks.Put([]byte(execID), data); err != nil {
+			return fmt.Errorf("couldn't store lot picks of %v: %w", execID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't write lot picks to journal: %w", err)
+	}
+	return nil
+}
+
+// LotPicks returns every recorded lot choice, keyed by closing execution ID.
+func (j *Journal) LotPicks() (map[string][]string, error) {
+	picks := make(map[string][]string)
+	err := j.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(bucketLotPicks)).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var ids []string
+			if err := json.Unmarshal(v, &ids); err != nil {
+				return fmt.Errorf("couldn't decode lot picks of %s: %w", k, err)
+			}
+			picks[string(k)] = ids
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read lot picks from journal: %w", err)
+	}
+	return picks, nil
+}
+
+// PutOrders stores completed orders keyed by their permanent ID. An order
+// already stored keeps the time it was first recorded.
+func (j *Journal) PutOrders(orders []Order) error {
+	err := j.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketOrders))
+		for _, o := range orders {
+			key := []byte(fmt.Sprintf("%020d", o.PermID))
+			if old := b.Get(key); old != nil {
+				var prev Order
+				if err := json.Unmarshal(old, &prev); err == nil {
+					o.Time = prev.Time
+				}
+			}
+			data, err := json.Marshal(o)
+			if err != nil {
+				return fmt.Errorf("couldn't encode order %v: %w", o.PermID, err)
+			}
+			if err = b.Put(key, data); err != nil {
+				return fmt.Errorf("couldn't store order %v: %w", o.PermID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't write orders to journal: %w", err)
+	}
+	return nil
+}
+
+// Orders returns every stored completed order, oldest permanent ID first.
+func (j *Journal) Orders() ([]Order, error) {
+	orders := make([]Order, 0)
+	err := j.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(bucketOrders)).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var o Order
+			if err := json.Unmarshal(v, &o); err != nil {
+				return fmt.Errorf("couldn't decode order %s: %w", k, err)
+			}
+			orders = append(orders, o)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read orders from journal: %w", err)
+	}
+	return orders, nil
+}
+
+// Store executions, replacing or skipping those already stored. Returns how many were new.
+func (j *Journal) putExecutions(execs []Execution, replace bool) (int, error) {
+	added := 0
+	err := j.db.Update(func(tx *bolt.Tx) error {
+		b, ids := tx.Bucket([]byte(bucketExecutions)), tx.Bucket([]byte(bucketExecutionIDs))
+		for _, e := range execs {
+			if old := ids.Get([]byte(e.ExecID)); old != nil {
+				if !replace {
+					continue
+				}
+				if err := b.Delete(old); err != nil {
+					return fmt.Errorf("couldn't replace execution %v: %w", e.ExecID, err)
+				}
+			} else {
+				added++
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				return fmt.Errorf("couldn't encode execution %v: %w", e.ExecID, err)
+			}
+			key := executionKey(e)
+			if err = b.Put(key, data); err != nil {
+				return fmt.Errorf("couldn't store execution %v: %w", e.ExecID, err)
+			}
+			if err = ids.Put([]byte(e.ExecID), key); err != nil {
+				return fmt.Errorf("couldn't index execution %v: %w", e.ExecID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't write executions to journal: %w", err)
+	}
+	return added, nil
+}
+
+// Key an execution so keys sort by time, with the ID keeping them unique.
+func executionKey(e Execution) []byte {
+	return []byte(timeKey(e.Time) + "/" + e.ExecID)
+}
+
+// Format t in UTC so its text sorts in time order.
+func timeKey(t time.Time) string {
+	return t.UTC().Format(timeKeyLayout)
+}
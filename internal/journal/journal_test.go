@@ -0,0 +1,165 @@
+package journal
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTemp(t *testing.T) *Journal {
+	t.Helper()
+	j, err := Open(filepath.Join(t.TempDir(), "ibtui", "journal.db"))
+	if err != nil {
+		t.Fatalf("couldn't open journal: %v", err)
+	}
+	t.Cleanup(func() { _ = j.Close() })
+	return j
+}
+
+func TestJournal_PutExecutions(t *testing.T) {
+	j := openTemp(t)
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	execs := []Execution{
+		{ExecID: "e2", Time: t0.AddDate(0, 0, 1), Symbol: "MSFT", Quantity: 10},
+		{ExecID: "e1", Time: t0, Symbol: "AAPL", Quantity: 40},
+	}
+	if added, err := j.PutExecutions(execs); err != nil || added != 2 {
+		t.Fatalf("expected 2 new executions got %d, %v", added, err)
+	}
+	execs[1].Commission = 1.2 // Its commission report arrived
+	if added, err := j.PutExecutions(execs[1:]); err != nil || added != 0 {
+		t.Fatalf("expected a replaced execution to not count as new got %d, %v", added, err)
+	}
+
+	all, err := j.Trades(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Trades returned unexpected error: %v", err)
+	}
+	if len(all) != 2 || all[0].ExecID != "e1" || all[0].Commission != 1.2 || all[1].ExecID != "e2" {
+		t.Fatalf("expected both executions oldest first with the updated commission got %+v", all)
+	}
+	day, err := j.Trades(t0.Truncate(24*time.Hour), t0.Truncate(24*time.Hour).AddDate(0, 0, 1))
+	if err != nil || len(day) != 1 || day[0].ExecID != "e1" {
+		t.Fatalf("expected only the first day's execution got %+v, %v", day, err)
+	}
+	if ok, _ := j.HasExecution("e2"); !ok {
+		t.Fatalf("expected e2 to be stored")
+	}
+}
+
+func TestJournal_AddExecutions(t *testing.T) {
+	j := openTemp(t)
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	if _, err := j.PutExecutions([]Execution{{ExecID: "e1", Time: t0, OrderID: 7, Quantity: 40}}); err != nil {
+		t.Fatalf("PutExecutions returned unexpected error: %v", err)
+	}
+	imported := []Execution{
+		{ExecID: "e1", Time: t0, Quantity: 40}, // Captured live, with more detail
+		{ExecID: "e0", Time: t0.AddDate(0, 0, -1), Quantity: 10},
+	}
+	if added, err := j.AddExecutions(imported); err != nil || added != 1 {
+		t.Fatalf("expected only e0 added got %d, %v", added, err)
+	}
+	all, err := j.Trades(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Trades returned unexpected error: %v", err)
+	}
+	if len(all) != 2 || all[0].ExecID != "e0" || all[1].OrderID != 7 {
+		t.Fatalf("expected e0 added and e1 left as captured got %+v", all)
+	}
+}
+
+func TestJournal_HasFill(t *testing.T) {
+	j := openTemp(t)
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	live := Execution{ExecID: "e1", Time: t0, Account: "U1", ConID: 265598, Side: "BOT", Quantity: 40, Price: 189.5}
+	if _, err := j.PutExecutions([]Execution{live}); err != nil {
+		t.Fatalf("PutExecutions returned unexpected error: %v", err)
+	}
+	imported := live
+	imported.ExecID = "flex:811"
+	if ok, err := j.HasFill(imported); err != nil || !ok {
+		t.Fatalf("expected the same fill under another ID to be found got %v, %v", ok, err)
+	}
+	imported.Price = 189.52
+	if ok, err := j.HasFill(imported); err != nil || ok {
+		t.Fatalf("expected a fill at another price to not be found got %v, %v", ok, err)
+	}
+	imported.Price, imported.Time = live.Price, t0.Add(time.Second)
+	if ok, err := j.HasFill(imported); err != nil || ok {
+		t.Fatalf("expected a fill at another time to not be found got %v, %v", ok, err)
+	}
+}
+
+func TestJournal_SetNote(t *testing.T) {
+	j := openTemp(t)
+	if err := j.SetNote("nope", Note{Text: "x"}); !errors.Is(err, ErrUnknownExecution) {
+		t.Fatalf("expected ErrUnknownExecution got %v", err)
+	}
+	if _, err := j.PutExecutions([]Execution{{ExecID: "e1", Time: time.Now()}}); err != nil {
+		t.Fatalf("couldn't store execution: %v", err)
+	}
+	if err := j.SetNote("e1", Note{Text: "breakout", Tags: []string{"momentum"}}); err != nil {
+		t.Fatalf("SetNote returned unexpected error: %v", err)
+	}
+	trades, _ := j.Trades(time.Time{}, time.Time{})
+	if len(trades) != 1 || trades[0].Text != "breakout" || trades[0].Tags[0] != "momentum" {
+		t.Fatalf("expected the note on the trade got %+v", trades)
+	}
+	if err := j.SetNote("e1", Note{}); err != nil {
+		t.Fatalf("SetNote returned unexpected error clearing a note: %v", err)
+	}
+	trades, _ = j.Trades(time.Time{}, time.Time{})
+	if trades[0].Text != "" || trades[0].Tags != nil {
+		t.Fatalf("expected the note to be cleared got %+v", trades[0].Note)
+	}
+}
+
+func TestJournal_PutOrders(t *testing.T) {
+	j := openTemp(t)
+	first := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	orders := []Order{{PermID: 200, Status: "Cancelled", Time: first}, {PermID: 9, Status: "Filled"}}
+	if err := j.PutOrders(orders); err != nil {
+		t.Fatalf("PutOrders returned unexpected error: %v", err)
+	}
+	if err := j.PutOrders([]Order{{PermID: 200, Status: "Cancelled", Time: first.Add(time.Hour)}}); err != nil {
+		t.Fatalf("PutOrders returned unexpected error: %v", err)
+	}
+	orders, err := j.Orders()
+	if err != nil {
+		t.Fatalf("Orders returned unexpected error: %v", err)
+	}
+	if len(orders) != 2 || orders[0].PermID != 9 || !orders[1].Time.Equal(first) {
+		t.Fatalf("expected 2 orders by perm ID keeping the first recorded time got %+v", orders)
+	}
+}
+
+func TestJournal_LotPicks(t *testing.T) {
+	j := openTemp(t)
+	if err := j.SetLotPicks("s1", []string{"b3", "b1"}); err != nil {
+		t.Fatalf("SetLotPicks returned unexpected error: %v", err)
+	}
+	picks, err := j.LotPicks()
+	if err != nil || len(picks["s1"]) != 2 || picks["s1"][0] != "b3" {
+		t.Fatalf("expected s1 to close b3 then b1 got %v, %v", picks, err)
+	}
+	if err = j.SetLotPicks("s1", nil); err != nil {
+		t.Fatalf("SetLotPicks returned unexpected error clearing picks: %v", err)
+	}
+	if picks, _ = j.LotPicks(); len(picks) != 0 {
+		t.Fatalf("expected no picks left got %v", picks)
+	}
+}
+
+func TestOpen_in_use(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.db")
+	j, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open returned unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = j.Close() })
+	if _, err = Open(path); !errors.Is(err, ErrInUse) {
+		t.Fatalf("expected ErrInUse opening a journal already open got %v", err)
+	}
+}
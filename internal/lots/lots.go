@@ -0,0 +1,283 @@
+// Package lots matches journaled executions into tax lots, giving the lots
+// still open and the gains realized by closing them.
+package lots
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/journal"
+)
+
+// Method chooses which open lots a closing execution sells first.
+type Method string
+
+// Lot matching methods.
+const (
+	FIFO        Method = "fifo"     // Oldest lot first
+	LIFO        Method = "lifo"     // Newest lot first
+	HighestCost Method = "hifo"     // Highest cost lot first
+	SpecificLot Method = "specific" // Lots picked per closing execution, then oldest first
+)
+
+// Quantities smaller than this are rounding left over from fractional shares.
+const epsilon = 1e-9
+
+// ErrUnknownMethod occurs when parsing a lot method that doesn't exist.
+var ErrUnknownMethod = errors.New("unknown lot method")
+
+// Lot is an open position opened by one execution. Quantity is negative for a short.
+type Lot struct {
+	Account    string
+	ConID      int64
+	Symbol     string
+	ExecID     string // The execution that opened it
+	Opened     time.Time
+	Quantity   float64
+	Price      float64 // Per unit, net of the opening commission
+	Multiplier float64
+}
+
+// Gain is the result of closing all or part of one lot.
+type Gain struct {
+	Account  string
+	ConID    int64
+	Symbol   string
+	ExecID   string  // The execution that closed it
+	OpenedBy string  // The execution that opened it
+	Quantity float64 // Always positive
+	Opened   time.Time
+	Closed   time.Time
+	Cost     float64
+	Proceeds float64
+	LongTerm bool
+}
+
+// Book is every open lot and every realized gain, in execution order.
+type Book struct {
+	Open     []Lot
+	Realized []Gain
+}
+
+// YearReport sums the gains realized in one calendar year.
+type YearReport struct {
+	Year      int
+	ShortTerm float64
+	LongTerm  float64
+	Gains     []Gain
+}
+
+// A position's lots are kept apart by account and contract.
+type positionKey struct {
+	account string
+	conID   int64
+	symbol  string
+}
+
+// Methods lists every lot method in the order the TUI cycles through them.
+func Methods() []Method {
+	return []Method{FIFO, LIFO, HighestCost, SpecificLot}
+}
+
+// ParseMethod reads a method name, e.g. "fifo".
+func ParseMethod(s string) (Method, error) {
+	m := Method(strings.ToLower(strings.TrimSpace(s)))
+	if !slices.Contains(Methods(), m) {
+		return FIFO, fmt.Errorf("couldn't parse lot method %q: %w", s, ErrUnknownMethod)
+	}
+	return m, nil
+}
+
+// Cost is what the lot's units cost, or were sold short for, net of commission.
+func (l Lot) Cost() float64 {
+	return math.Abs(l.Quantity) * l.Price * multiplier(l.Multiplier)
+}
+
+// LongTerm reports whether closing the lot at closed would be long-term: a
+// long lot held more than a year, i.e. closed on a later calendar date than
+// the anniversary of its opening, in local time. Short sales are always short-term.
+func (l Lot) LongTerm(closed time.Time) bool {
+	if l.Quantity <= 0 {
+		return false
+	}
+	y, m, d := l.Opened.Local().Date()
+	anniversary := time.Date(y+1, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = closed.Local().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).After(anniversary)
+}
+
+// Amount is the realized gain, negative for a loss.
+func (g Gain) Amount() float64 {
+	return g.Proceeds - g.Cost
+}
+
+// Match replays execs in time order, opening a lot for every execution that
+// adds to a position and closing lots by method for every one that reduces it.
+// For SpecificLot, picks maps a closing execution's ID to the IDs of the
+// executions that opened the lots it should close.
+func Match(execs []journal.Execution, method Method, picks map[string][]string) Book {
+	execs = slices.Clone(execs)
+	slices.SortStableFunc(execs, func(a, b journal.Execution) int {
+		return a.Time.Compare(b.Time)
+	})
+	open := make(map[positionKey][]Lot)
+	var book Book
+	for _, e := range execs {
+		if e.Quantity <= 0 {
+			continue
+		}
+		key := positionKey{e.Account, e.ConID, e.Symbol}
+		if e.ConID != 0 {
+			key.symbol = "" // The same contract can be journaled under a changed symbol
+		}
+		qty := e.Quantity
+		if e.Side == "SLD" {
+			qty = -qty
+		}
+		lots := open[key]
+		if len(lots) > 0 && (lots[0].Quantity > 0) != (qty > 0) {
+			var gains []Gain
+			lots, gains, qty = closeLots(lots, e, qty, closingOrder(method, lots, picks[e.ExecID]))
+			book.Realized = append(book.Realized, gains...)
+		}
+		if math.Abs(qty) > epsilon {
+			lots = append(lots, Lot{
+				Account:    e.Account,
+				ConID:      e.ConID,
+				Symbol:     e.Symbol,
+				ExecID:     e.ExecID,
+				Opened:     e.Time,
+				Quantity:   qty,
+				Price:      netPrice(e, qty > 0),
+				Multiplier: multiplier(e.Multiplier),
+			})
+		}
+		open[key] = lots
+	}
+	for _, lots := range open {
+		book.Open = append(book.Open, lots...)
+	}
+	slices.SortStableFunc(book.Open, func(a, b Lot) int {
+		return a.Opened.Compare(b.Opened)
+	})
+	return book
+}
+
+// OpenLots returns the open lots of one account's position in a contract.
+func (b Book) OpenLots(account string, conID int64) []Lot {
+	lots := make([]Lot, 0)
+	for _, l := range b.Open {
+		if l.Account == account && l.ConID == conID {
+			lots = append(lots, l)
+		}
+	}
+	return lots
+}
+
+// Report sums the gains closed in year, in loc, split by holding period.
+func (b Book) Report(year int, loc *time.Location) YearReport {
+	r := YearReport{Year: year, Gains: make([]Gain, 0)}
+	for _, g := range b.Realized {
+		if g.Closed.In(loc).Year() != year {
+			continue
+		}
+		r.Gains = append(r.Gains, g)
+		if g.LongTerm {
+			r.LongTerm += g.Amount()
+		} else {
+			r.ShortTerm += g.Amount()
+		}
+	}
+	return r
+}
+
+// Close lots in the given order against an execution of qty units, the
+// opposite sign to the lots. Returns the lots left, the gains and any units
+// left over, which open a lot the other way.
+func closeLots(lots []Lot, e journal.Execution, qty float64, byOrder []int) ([]Lot, []Gain, float64) {
+	gains := make([]Gain, 0, len(byOrder))
+	closePrice := netPrice(e, qty > 0)
+	for _, i := range byOrder {
+		if math.Abs(qty) <= epsilon {
+			break
+		}
+		l := &lots[i]
+		n := math.Min(math.Abs(qty), math.Abs(l.Quantity))
+		mult := multiplier(l.Multiplier)
+		g := Gain{
+			Account:  l.Account,
+			ConID:    l.ConID,
+			Symbol:   l.Symbol,
+			ExecID:   e.ExecID,
+			OpenedBy: l.ExecID,
+			Quantity: n,
+			Opened:   l.Opened,
+			Closed:   e.Time,
+		}
+		g.LongTerm = l.LongTerm(e.Time)
+		if l.Quantity > 0 {
+			g.Cost, g.Proceeds = n*l.Price*mult, n*closePrice*mult
+			l.Quantity -= n
+			qty += n
+		} else {
+			g.Cost, g.Proceeds = n*closePrice*mult, n*l.Price*mult
+			l.Quantity += n
+			qty -= n
+		}
+		gains = append(gains, g)
+	}
+	return slices.DeleteFunc(lots, func(l Lot) bool {
+		return math.Abs(l.Quantity) <= epsilon
+	}), gains, qty
+}
+
+// The order, as indexes into lots, in which method closes them.
+func closingOrder(method Method, lots []Lot, picked []string) []int {
+	idx := make([]int, len(lots))
+	for i := range idx {
+		idx[i] = i
+	}
+	switch method {
+	case LIFO:
+		slices.Reverse(idx)
+	case HighestCost:
+		slices.SortStableFunc(idx, func(a, b int) int {
+			return cmp.Compare(lots[b].Price, lots[a].Price)
+		})
+	case SpecificLot:
+		// Picked lots first, in the order picked, then the rest oldest first
+		rank := func(i int) int {
+			if p := slices.Index(picked, lots[i].ExecID); p >= 0 {
+				return p
+			}
+			return len(picked)
+		}
+		slices.SortStableFunc(idx, func(a, b int) int {
+			return cmp.Compare(rank(a), rank(b))
+		})
+	case FIFO:
+	}
+	return idx
+}
+
+// Per unit price net of commission: a buy costs more, a sale brings in less.
+func netPrice(e journal.Execution, buy bool) float64 {
+	perUnit := e.Commission / e.Quantity / multiplier(e.Multiplier)
+	if buy {
+		return e.Price + perUnit
+	}
+	return e.Price - perUnit
+}
+
+// Contracts without a multiplier, e.g. stocks, count each unit once.
+func multiplier(m float64) float64 {
+	if m == 0 {
+		return 1
+	}
+	return m
+}
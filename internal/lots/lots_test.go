@@ -0,0 +1,131 @@
+package lots
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/journal"
+)
+
+// An AAPL execution the given number of days after 10 Jan 2025.
+func exec(id string, days int, side string, qty, price float64) journal.Execution {
+	return journal.Execution{
+		ExecID: id, Account: "U1", ConID: 265598, Symbol: "AAPL",
+		Time: time.Date(2025, 1, 10+days, 15, 0, 0, 0, time.UTC), Side: side, Quantity: qty, Price: price,
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestParseMethod(t *testing.T) {
+	if m, err := ParseMethod(" HIFO "); err != nil || m != HighestCost {
+		t.Fatalf("expected hifo got %v, %v", m, err)
+	}
+	if _, err := ParseMethod("average"); !errors.Is(err, ErrUnknownMethod) {
+		t.Fatalf("expected ErrUnknownMethod got %v", err)
+	}
+}
+
+func TestMatch_methods(t *testing.T) {
+	execs := []journal.Execution{
+		exec("b1", 0, "BOT", 100, 10),
+		exec("b2", 10, "BOT", 100, 30),
+		exec("b3", 20, "BOT", 100, 20),
+		exec("s1", 30, "SLD", 150, 25),
+	}
+	tests := []struct {
+		method Method
+		picks  map[string][]string
+		gain   float64
+		left   []string
+	}{
+		{FIFO, nil, 100*15 + 50*-5, []string{"b2", "b3"}},
+		{LIFO, nil, 100*5 + 50*-5, []string{"b1", "b2"}},
+		{HighestCost, nil, 100*-5 + 50*5, []string{"b1", "b3"}},
+		{SpecificLot, map[string][]string{"s1": {"b3"}}, 100*5 + 50*15, []string{"b1", "b2"}},
+	}
+	for _, tt := range tests {
+		book := Match(execs, tt.method, tt.picks)
+		total := 0.0
+		for _, g := range book.Realized {
+			total += g.Amount()
+		}
+		if !near(total, tt.gain) {
+			t.Fatalf("%v: expected gain %v got %v", tt.method, tt.gain, total)
+		}
+		if len(book.Open) != len(tt.left) {
+			t.Fatalf("%v: expected open lots %v got %+v", tt.method, tt.left, book.Open)
+		}
+		for i, id := range tt.left {
+			if book.Open[i].ExecID != id {
+				t.Fatalf("%v: expected open lots %v got %+v", tt.method, tt.left, book.Open)
+			}
+		}
+		if book.Open[0].Quantity+book.Open[1].Quantity != 150 {
+			t.Fatalf("%v: expected 150 units left open got %+v", tt.method, book.Open)
+		}
+	}
+}
+
+func TestMatch_shortsCommissionsAndTerm(t *testing.T) {
+	buy := exec("b1", 0, "BOT", 10, 100)
+	buy.Commission = 1
+	sell := exec("s1", 400, "SLD", 15, 120) // Flips to a 5 unit short
+	sell.Commission = 1.5
+	cover := exec("b2", 410, "BOT", 5, 110)
+	book := Match([]journal.Execution{cover, sell, buy}, FIFO, nil)
+	if len(book.Realized) != 2 || len(book.Open) != 0 {
+		t.Fatalf("expected the long and the short closed got %+v", book)
+	}
+	long, short := book.Realized[0], book.Realized[1]
+	if long.OpenedBy != "b1" || long.ExecID != "s1" || short.OpenedBy != "s1" || short.ExecID != "b2" {
+		t.Fatalf("expected each gain to name the executions that opened and closed it got %+v", book.Realized)
+	}
+	if !long.LongTerm || !near(long.Cost, 1001) || !near(long.Proceeds, 1200-1) {
+		t.Fatalf("expected a long-term gain net of commissions got %+v", long)
+	}
+	if short.LongTerm || !near(short.Proceeds, 600-0.5) || !near(short.Cost, 550) {
+		t.Fatalf("expected a short-term short gain got %+v", short)
+	}
+
+	report := book.Report(2026, time.UTC)
+	if len(report.Gains) != 2 || !near(report.LongTerm, 198) || !near(report.ShortTerm, 49.5) {
+		t.Fatalf("expected 2026's gains split by term got %+v", report)
+	}
+	if r := book.Report(2025, time.UTC); len(r.Gains) != 0 {
+		t.Fatalf("expected no gains closed in 2025 got %+v", r)
+	}
+}
+
+func TestMatch_multiplier(t *testing.T) {
+	open := exec("b1", 0, "BOT", 2, 1.5)
+	open.Multiplier = 100
+	closing := exec("s1", 5, "SLD", 1, 2)
+	closing.Multiplier = 100
+	book := Match([]journal.Execution{open, closing}, FIFO, nil)
+	if len(book.Realized) != 1 || !near(book.Realized[0].Amount(), 50) {
+		t.Fatalf("expected an option gain of 50 got %+v", book.Realized)
+	}
+	lots := book.OpenLots("U1", 265598)
+	if len(lots) != 1 || !near(lots[0].Cost(), 150) {
+		t.Fatalf("expected one contract left costing 150 got %+v", lots)
+	}
+}
+
+func TestLot_LongTerm(t *testing.T) {
+	opened := time.Date(2025, 3, 6, 9, 30, 0, 0, time.Local)
+	l := Lot{Opened: opened, Quantity: 10}
+	if l.LongTerm(time.Date(2026, 3, 6, 15, 0, 0, 0, time.Local)) {
+		t.Fatalf("expected a sale later on the anniversary to be short-term")
+	}
+	if !l.LongTerm(time.Date(2026, 3, 7, 9, 0, 0, 0, time.Local)) {
+		t.Fatalf("expected a sale the day after the anniversary to be long-term")
+	}
+	if short := (Lot{Opened: opened, Quantity: -10}); short.LongTerm(opened.AddDate(2, 0, 0)) {
+		t.Fatalf("expected a short sale to always be short-term")
+	}
+}
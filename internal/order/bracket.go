@@ -0,0 +1,115 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// OCACancel is IB's OCA type that cancels the rest of the group once one order fills.
+const OCACancel = 1
+
+// ErrEmptyBracket occurs when a bracket has neither a profit taker nor a stop loss.
+var ErrEmptyBracket = errors.New("bracket needs a take profit or a stop loss")
+
+// ErrBadBracket occurs when a bracket's exits are on the wrong side of its entry.
+var ErrBadBracket = errors.New("take profit and stop loss must sit either side of the entry")
+
+// ErrBracketOCA occurs when a bracket entry is also put in an OCA group.
+var ErrBracketOCA = errors.New("bracket exits are already one-cancels-all, leave the OCA group empty")
+
+// Bracket is an entry order with a profit taker and/or stop loss attached.
+// IB holds the exits until the entry fills and cancels one when the other fills.
+type Bracket struct {
+	Entry      Ticket
+	TakeProfit float64 // Limit price of the profit taker, 0 for none
+	StopLoss   float64 // Stop price of the stop loss, 0 for none
+}
+
+// Validate reports the first thing wrong with b, or nil if IB can be sent it.
+func (b Bracket) Validate() error {
+	if err := b.Entry.Validate(); err != nil {
+		return err
+	}
+	if b.Entry.OCAGroup != "" {
+		return ErrBracketOCA
+	}
+	if b.TakeProfit <= 0 && b.StopLoss <= 0 {
+		return ErrEmptyBracket
+	}
+	// A buy exits by selling higher or lower, so the profit taker must be above the stop loss.
+	above, below := b.TakeProfit, b.StopLoss
+	if b.Entry.Side == Sell {
+		above, below = below, above
+	}
+	if above > 0 && below > 0 && above <= below {
+		return fmt.Errorf("couldn't validate bracket: %w", ErrBadBracket)
+	}
+	if ref := b.entryPrice(); ref > 0 {
+		if (above > 0 && above <= ref) || (below > 0 && below >= ref) {
+			return fmt.Errorf("couldn't validate bracket around %v: %w", ref, ErrBadBracket)
+		}
+	}
+	return nil
+}
+
+// Tickets returns the entry followed by its exits, in the order they must be placed.
+func (b Bracket) Tickets() []Ticket {
+	tickets := []Ticket{b.Entry}
+	exit := Ticket{
+		Spec:       b.Entry.Spec,
+		Account:    b.Entry.Account,
+		Side:       Buy,
+		Quantity:   b.Entry.Quantity,
+		TIF:        b.exitTIF(),
+		GoodTill:   b.Entry.GoodTill,
+		OutsideRTH: b.Entry.OutsideRTH,
+	}
+	if b.Entry.Side == Buy {
+		exit.Side = Sell
+	}
+	if b.TakeProfit > 0 {
+		tp := exit
+		tp.Type, tp.LimitPrice = Limit, b.TakeProfit
+		tickets = append(tickets, tp)
+	}
+	if b.StopLoss > 0 {
+		sl := exit
+		sl.Type, sl.StopPrice = Stop, b.StopLoss
+		tickets = append(tickets, sl)
+	}
+	return tickets
+}
+
+// String summarises b for a confirmation prompt.
+func (b Bracket) String() string {
+	parts := []string{b.Entry.String()}
+	if b.TakeProfit > 0 {
+		parts = append(parts, "take profit "+formatFloat(b.TakeProfit))
+	}
+	if b.StopLoss > 0 {
+		parts = append(parts, "stop loss "+formatFloat(b.StopLoss))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// The price the entry is expected to fill near, if it has one.
+func (b Bracket) entryPrice() float64 {
+	switch {
+	case b.Entry.Type.NeedsLimit():
+		return b.Entry.LimitPrice
+	case b.Entry.Type.NeedsStop():
+		return b.Entry.StopPrice
+	}
+	return 0
+}
+
+// Exits keep a GTC or GTD entry's time in force. Anything shorter lasts the day.
+func (b Bracket) exitTIF() TIF {
+	switch b.Entry.TIF {
+	case GTC, GTD:
+		return b.Entry.TIF
+	case Day, IOC, FOK, OpeningAuction:
+	}
+	return Day
+}
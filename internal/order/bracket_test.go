@@ -0,0 +1,67 @@
+package order
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glenntam/ibtui/internal/contract"
+)
+
+func TestBracket(t *testing.T) {
+	entry := Ticket{
+		Spec:       contract.Spec{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"},
+		Account:    "U1",
+		Side:       Buy,
+		Quantity:   100,
+		Type:       Limit,
+		LimitPrice: 190,
+		TIF:        GTC,
+		OutsideRTH: true,
+	}
+	b := Bracket{Entry: entry, TakeProfit: 200, StopLoss: 185}
+	if err := b.Validate(); err != nil {
+		t.Fatalf("expected valid bracket got %v", err)
+	}
+	legs := b.Tickets()
+	if len(legs) != 3 {
+		t.Fatalf("expected entry and 2 exits got %+v", legs)
+	}
+	tp, sl := legs[1], legs[2]
+	if tp.Side != Sell || tp.Type != Limit || tp.LimitPrice != 200 || tp.Quantity != 100 || tp.TIF != GTC {
+		t.Fatalf("unexpected take profit %+v", tp)
+	}
+	if sl.Side != Sell || sl.Type != Stop || sl.StopPrice != 185 || sl.Account != "U1" {
+		t.Fatalf("unexpected stop loss %+v", sl)
+	}
+	if !tp.OutsideRTH || !sl.OutsideRTH {
+		t.Fatalf("expected both exits to fill outside regular hours like the entry got %+v and %+v", tp, sl)
+	}
+
+	cases := []struct {
+		name string
+		b    Bracket
+		want error
+	}{
+		{"no exits", Bracket{Entry: entry}, ErrEmptyBracket},
+		{"take profit below entry", Bracket{Entry: entry, TakeProfit: 189}, ErrBadBracket},
+		{"stop loss above take profit", Bracket{Entry: entry, TakeProfit: 200, StopLoss: 201}, ErrBadBracket},
+		{"in an OCA group", Bracket{Entry: Ticket{
+			Spec: entry.Spec, Side: Buy, Quantity: 1, Type: Market, TIF: Day, OCAGroup: "g",
+		}, StopLoss: 1}, ErrBracketOCA},
+	}
+	for _, c := range cases {
+		if err := c.b.Validate(); !errors.Is(err, c.want) {
+			t.Fatalf("%s: expected %v got %v", c.name, c.want, err)
+		}
+	}
+
+	short := entry
+	short.Side, short.TIF = Sell, IOC
+	sb := Bracket{Entry: short, TakeProfit: 180, StopLoss: 195}
+	if err := sb.Validate(); err != nil {
+		t.Fatalf("expected valid short bracket got %v", err)
+	}
+	if exit := sb.Tickets()[1]; exit.Side != Buy || exit.TIF != Day {
+		t.Fatalf("expected a DAY buy to cover got %+v", exit)
+	}
+}
@@ -0,0 +1,380 @@
+// Package order describes an order ticket as the user fills it in, and checks
+// it before anything is sent to IB.
+package order
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/contract"
+)
+
+// Sides of an order, as IB spells them.
+const (
+	Buy  = "BUY"
+	Sell = "SELL"
+)
+
+// GoodTillLayout is how a GTD expiry is typed, in local time (IBTUI_TIMEZONE).
+// Orders send it to IB in UTC.
+const GoodTillLayout = "20060102 15:04:05"
+
+const percent = 100
+
+// Type is IB's order type code, except for the opening auction types.
+type Type string
+
+// Supported order types.
+const (
+	Market        Type = "MKT"
+	Limit         Type = "LMT"
+	Stop          Type = "STP"
+	StopLimit     Type = "STP LMT"
+	Trail         Type = "TRAIL"
+	TrailLimit    Type = "TRAIL LIMIT"
+	MarketOnClose Type = "MOC"
+	LimitOnClose  Type = "LOC"
+	MarketOnOpen  Type = "MOO" // Sent as MKT with the OPG time in force
+	LimitOnOpen   Type = "LOO" // Sent as LMT with the OPG time in force
+	Relative      Type = "REL"
+)
+
+// TIF is IB's time in force code.
+type TIF string
+
+// Supported times in force.
+const (
+	Day            TIF = "DAY"
+	GTC            TIF = "GTC" // Good till cancelled
+	GTD            TIF = "GTD" // Good till Ticket.GoodTill
+	IOC            TIF = "IOC" // Immediate or cancel
+	FOK            TIF = "FOK" // Fill or kill
+	OpeningAuction TIF = "OPG"
+)
+
+// ErrNoContract occurs when a ticket has no symbol.
+var ErrNoContract = errors.New("order needs a symbol")
+
+// ErrBadSide occurs when a ticket is neither a buy nor a sell.
+var ErrBadSide = errors.New("order side must be BUY or SELL")
+
+// ErrBadQuantity occurs when a ticket's quantity isn't a positive number.
+var ErrBadQuantity = errors.New("order quantity must be a positive number")
+
+// ErrBadType occurs when a ticket's order type isn't supported.
+var ErrBadType = errors.New("unsupported order type")
+
+// ErrBadTIF occurs when a ticket's time in force isn't supported by its order type.
+var ErrBadTIF = errors.New("unsupported time in force")
+
+// ErrBadPrice occurs when a price the order type needs is missing or not positive.
+var ErrBadPrice = errors.New("order price must be a positive number")
+
+// ErrBadTrail occurs when a trailing order has no trailing amount or percent, or both.
+var ErrBadTrail = errors.New("trailing amount must be a positive price or a percent under 100%")
+
+// ErrBadGoodTill occurs when a GTD order has no valid expiry.
+var ErrBadGoodTill = errors.New("GTD expiry must be formatted YYYYMMDD HH:MM:SS")
+
+// ErrPastGoodTill occurs when a GTD order's expiry has already passed.
+var ErrPastGoodTill = errors.New("GTD expiry is in the past")
+
+// ErrOutsideRTH occurs when outside regular trading hours is asked of an order type that doesn't allow it.
+var ErrOutsideRTH = errors.New("order type can't fill outside regular trading hours")
+
+// ErrWrongSecType occurs when an order type isn't offered for the contract's security type.
+var ErrWrongSecType = errors.New("order type isn't available for this security type")
+
+// Ticket is everything needed to place a single order.
+// Prices an order type doesn't use are ignored.
+type Ticket struct {
+	Spec         contract.Spec
+	Account      string // Empty lets IB pick the only account
+	Side         string
+	Quantity     float64
+	Type         Type
+	LimitPrice   float64 // Also the optional price cap of a REL order
+	StopPrice    float64
+	TrailAmount  float64 // Either TrailAmount or TrailPercent trails a TRAIL order
+	TrailPercent float64
+	LimitOffset  float64 // How far a TRAIL LIMIT's limit sits from its stop
+	Offset       float64 // How far a REL order pegs from the NBBO
+	TIF          TIF
+	GoodTill     string // GTD expiry in GoodTillLayout
+	OutsideRTH   bool
+	OCAGroup     string // Orders sharing a group cancel each other once one fills
+}
+
+// Types lists the supported order types in the order a form cycles through them.
+func Types() []Type {
+	return []Type{
+		Market, Limit, Stop, StopLimit, Trail, TrailLimit,
+		MarketOnClose, LimitOnClose, MarketOnOpen, LimitOnOpen, Relative,
+	}
+}
+
+// TIFs lists the times in force a user can choose, in the order a form cycles through them.
+func TIFs() []TIF {
+	return []TIF{Day, GTC, GTD, IOC, FOK}
+}
+
+// NeedsLimit reports whether t must have a limit price.
+func (t Type) NeedsLimit() bool {
+	return t == Limit || t == StopLimit || t == LimitOnClose || t == LimitOnOpen
+}
+
+// UsesLimit reports whether t takes a limit price, even an optional one.
+func (t Type) UsesLimit() bool {
+	return t.NeedsLimit() || t == Relative
+}
+
+// NeedsStop reports whether t is triggered by a stop price.
+func (t Type) NeedsStop() bool {
+	return t == Stop || t == StopLimit
+}
+
+// NeedsTrail reports whether t trails the market by an amount or percent.
+func (t Type) NeedsTrail() bool {
+	return t == Trail || t == TrailLimit
+}
+
+// NeedsLimitOffset reports whether t places its limit at an offset from the stop.
+func (t Type) NeedsLimitOffset() bool {
+	return t == TrailLimit
+}
+
+// NeedsOffset reports whether t pegs to the market at an offset.
+func (t Type) NeedsOffset() bool {
+	return t == Relative
+}
+
+// FixedTIF returns the only time in force IB accepts for t, if there is just one.
+func (t Type) FixedTIF() (TIF, bool) {
+	switch t {
+	case MarketOnClose, LimitOnClose:
+		return Day, true
+	case MarketOnOpen, LimitOnOpen:
+		return OpeningAuction, true
+	case Market, Limit, Stop, StopLimit, Trail, TrailLimit, Relative:
+	}
+	return "", false
+}
+
+// AllowsOutsideRTH reports whether t may fill outside regular trading hours.
+func (t Type) AllowsOutsideRTH() bool {
+	switch t {
+	case Limit, Stop, StopLimit, Trail, TrailLimit:
+		return true
+	case Market, MarketOnClose, LimitOnClose, MarketOnOpen, LimitOnOpen, Relative:
+	}
+	return false
+}
+
+// SecTypes returns the security types IB offers t for, or nil if it's offered for all.
+func (t Type) SecTypes() []string {
+	switch t {
+	case MarketOnClose, LimitOnClose, MarketOnOpen, LimitOnOpen:
+		return []string{"STK"}
+	case Relative:
+		return []string{"STK", "OPT", "FUT"}
+	case Market, Limit, Stop, StopLimit, Trail, TrailLimit:
+	}
+	return nil
+}
+
+// IB returns the order type and time in force codes to send IB.
+func (t Ticket) IB() (string, string) {
+	switch t.Type {
+	case MarketOnOpen:
+		return string(Market), string(OpeningAuction)
+	case LimitOnOpen:
+		return string(Limit), string(OpeningAuction)
+	case Market, Limit, Stop, StopLimit, Trail, TrailLimit, MarketOnClose, LimitOnClose, Relative:
+	}
+	return string(t.Type), string(t.TIF)
+}
+
+// ParseQuantity reads a quantity as typed, allowing thousands separators.
+func ParseQuantity(s string) (float64, error) {
+	q, ok := parsePositive(s)
+	if !ok {
+		return 0, fmt.Errorf("couldn't parse quantity %q: %w", s, ErrBadQuantity)
+	}
+	return q, nil
+}
+
+// ParsePrice reads a price as typed. An empty string is 0, i.e. not set.
+func ParsePrice(s string) (float64, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	p, ok := parsePositive(s)
+	if !ok {
+		return 0, fmt.Errorf("couldn't parse price %q: %w", s, ErrBadPrice)
+	}
+	return p, nil
+}
+
+// TrailBy is how far a trailing order follows the market. Only one of its fields is set.
+type TrailBy struct {
+	Amount  float64
+	Percent float64
+}
+
+// ParseTrail reads a trailing amount as typed: "1.5" trails by a price and "2%" by a percent.
+func ParseTrail(s string) (TrailBy, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return TrailBy{}, nil
+	}
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		p, ok := parsePositive(pct)
+		if !ok || p >= percent {
+			return TrailBy{}, fmt.Errorf("couldn't parse trail %q: %w", s, ErrBadTrail)
+		}
+		return TrailBy{Percent: p}, nil
+	}
+	a, ok := parsePositive(s)
+	if !ok {
+		return TrailBy{}, fmt.Errorf("couldn't parse trail %q: %w", s, ErrBadTrail)
+	}
+	return TrailBy{Amount: a}, nil
+}
+
+// ParseGoodTill reads a GTD expiry typed in GoodTillLayout, in local time,
+// and checks it hasn't passed.
+func ParseGoodTill(s string) (time.Time, error) {
+	t, err := time.ParseInLocation(GoodTillLayout, strings.TrimSpace(s), time.Local)
+	if err != nil {
+		return t, fmt.Errorf("couldn't parse expiry %q: %w", s, ErrBadGoodTill)
+	}
+	if !t.After(time.Now()) {
+		return t, fmt.Errorf("couldn't use expiry %q: %w", s, ErrPastGoodTill)
+	}
+	return t, nil
+}
+
+// Validate reports the first thing wrong with t, or nil if IB can be sent it.
+func (t Ticket) Validate() error {
+	if t.Spec.Symbol == "" {
+		return ErrNoContract
+	}
+	if t.Side != Buy && t.Side != Sell {
+		return fmt.Errorf("couldn't validate side %q: %w", t.Side, ErrBadSide)
+	}
+	if !(t.Quantity > 0) || math.IsInf(t.Quantity, 0) {
+		return fmt.Errorf("couldn't validate quantity %v: %w", t.Quantity, ErrBadQuantity)
+	}
+	if !slices.Contains(Types(), t.Type) {
+		return fmt.Errorf("couldn't validate order type %q: %w", t.Type, ErrBadType)
+	}
+	if secTypes := t.Type.SecTypes(); secTypes != nil && !slices.Contains(secTypes, t.Spec.SecType) {
+		return fmt.Errorf("couldn't validate %v for %v: %w", t.Type, t.Spec.SecType, ErrWrongSecType)
+	}
+	if err := t.validateTIF(); err != nil {
+		return err
+	}
+	if t.OutsideRTH && !t.Type.AllowsOutsideRTH() {
+		return fmt.Errorf("couldn't validate %v: %w", t.Type, ErrOutsideRTH)
+	}
+	return t.validatePrices()
+}
+
+// String summarises t for a confirmation prompt, e.g. "BUY 100 AAPL LMT 189.5 DAY".
+func (t Ticket) String() string {
+	parts := []string{t.Side, formatFloat(t.Quantity), t.Spec.String(), string(t.Type)}
+	if t.Type.NeedsStop() {
+		parts = append(parts, "stop "+formatFloat(t.StopPrice))
+	}
+	if t.Type.NeedsTrail() {
+		if t.TrailPercent > 0 {
+			parts = append(parts, "trail "+formatFloat(t.TrailPercent)+"%")
+		} else {
+			parts = append(parts, "trail "+formatFloat(t.TrailAmount))
+		}
+	}
+	if t.Type.NeedsLimitOffset() {
+		parts = append(parts, "limit offset "+formatFloat(t.LimitOffset))
+	}
+	if t.Type.NeedsOffset() {
+		parts = append(parts, "offset "+formatFloat(t.Offset))
+	}
+	if t.Type.UsesLimit() && t.LimitPrice > 0 {
+		parts = append(parts, formatFloat(t.LimitPrice))
+	}
+	parts = append(parts, string(t.TIF))
+	if t.TIF == GTD {
+		parts = append(parts, t.GoodTill)
+	}
+	if t.OutsideRTH {
+		parts = append(parts, "outside RTH")
+	}
+	if t.OCAGroup != "" {
+		parts = append(parts, "OCA "+t.OCAGroup)
+	}
+	if t.Account != "" {
+		parts = append(parts, "in "+t.Account)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Check the time in force suits the order type, and that a GTD order has an expiry.
+func (t Ticket) validateTIF() error {
+	if fixed, ok := t.Type.FixedTIF(); ok {
+		if t.TIF != fixed {
+			return fmt.Errorf("couldn't validate %v with %v, it must be %v: %w", t.Type, t.TIF, fixed, ErrBadTIF)
+		}
+		return nil
+	}
+	if !slices.Contains(TIFs(), t.TIF) {
+		return fmt.Errorf("couldn't validate time in force %q: %w", t.TIF, ErrBadTIF)
+	}
+	if t.TIF == GTD {
+		if _, err := ParseGoodTill(t.GoodTill); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check every price the order type needs is set.
+func (t Ticket) validatePrices() error {
+	if t.Type.NeedsLimit() && !(t.LimitPrice > 0) {
+		return fmt.Errorf("couldn't validate %v limit price: %w", t.Type, ErrBadPrice)
+	}
+	if t.Type.NeedsStop() && !(t.StopPrice > 0) {
+		return fmt.Errorf("couldn't validate %v stop price: %w", t.Type, ErrBadPrice)
+	}
+	if t.Type.NeedsTrail() && (t.TrailAmount > 0) == (t.TrailPercent > 0) {
+		return fmt.Errorf("couldn't validate %v: %w", t.Type, ErrBadTrail)
+	}
+	if t.TrailPercent >= percent {
+		return fmt.Errorf("couldn't validate %v%% trail: %w", t.TrailPercent, ErrBadTrail)
+	}
+	if t.Type.NeedsLimitOffset() && !(t.LimitOffset > 0) {
+		return fmt.Errorf("couldn't validate %v limit offset: %w", t.Type, ErrBadPrice)
+	}
+	if t.Type.NeedsOffset() && !(t.Offset > 0) {
+		return fmt.Errorf("couldn't validate %v offset: %w", t.Type, ErrBadPrice)
+	}
+	return nil
+}
+
+// Parse a positive, finite number that may contain thousands separators.
+func parsePositive(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	if err != nil || !(v > 0) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+// Format a number with as few decimals as it needs.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
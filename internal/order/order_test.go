@@ -0,0 +1,122 @@
+package order
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glenntam/ibtui/internal/contract"
+)
+
+func TestTicket_Validate(t *testing.T) {
+	aapl := contract.Spec{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"}
+	valid := Ticket{Spec: aapl, Side: Buy, Quantity: 100, Type: Limit, LimitPrice: 189.5, TIF: Day}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid ticket got %v", err)
+	}
+
+	cases := []struct {
+		name string
+		edit func(*Ticket)
+		want error
+	}{
+		{"no symbol", func(t *Ticket) { t.Spec = contract.Spec{} }, ErrNoContract},
+		{"no side", func(t *Ticket) { t.Side = "" }, ErrBadSide},
+		{"zero quantity", func(t *Ticket) { t.Quantity = 0 }, ErrBadQuantity},
+		{"unknown type", func(t *Ticket) { t.Type = "PEG" }, ErrBadType},
+		{"unknown tif", func(t *Ticket) { t.TIF = "NEVER" }, ErrBadTIF},
+		{"limit without price", func(t *Ticket) { t.LimitPrice = 0 }, ErrBadPrice},
+		{"stop without price", func(t *Ticket) { t.Type = Stop }, ErrBadPrice},
+	}
+	for _, c := range cases {
+		ticket := valid
+		c.edit(&ticket)
+		if err := ticket.Validate(); !errors.Is(err, c.want) {
+			t.Fatalf("%s: expected %v got %v", c.name, c.want, err)
+		}
+	}
+
+	market := Ticket{Spec: aapl, Side: Sell, Quantity: 5, Type: Market, TIF: GTC, Account: "U1"}
+	if err := market.Validate(); err != nil {
+		t.Fatalf("expected market order without prices to be valid got %v", err)
+	}
+	if got, want := market.String(), "SELL 5 AAPL MKT GTC in U1"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestParseQuantityAndPrice(t *testing.T) {
+	if q, err := ParseQuantity("1,000"); err != nil || q != 1000 {
+		t.Fatalf("ParseQuantity(1,000) = %v, %v", q, err)
+	}
+	if _, err := ParseQuantity("-3"); !errors.Is(err, ErrBadQuantity) {
+		t.Fatalf("expected ErrBadQuantity for a negative quantity got %v", err)
+	}
+	if p, err := ParsePrice(""); err != nil || p != 0 {
+		t.Fatalf("expected an empty price to be unset got %v, %v", p, err)
+	}
+	if _, err := ParsePrice("abc"); !errors.Is(err, ErrBadPrice) {
+		t.Fatalf("expected ErrBadPrice got %v", err)
+	}
+}
+
+func TestTicket_Validate_order_types(t *testing.T) {
+	aapl := contract.Spec{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"}
+	es := contract.Spec{Symbol: "ES", SecType: "FUT", Exchange: "CME", Currency: "USD"}
+	cases := []struct {
+		name   string
+		ticket Ticket
+		want   error
+	}{
+		{"stop limit", Ticket{Type: StopLimit, StopPrice: 180, LimitPrice: 179.5, TIF: GTC}, nil},
+		{"stop limit without limit", Ticket{Type: StopLimit, StopPrice: 180, TIF: Day}, ErrBadPrice},
+		{"trail by amount", Ticket{Type: Trail, TrailAmount: 1.5, TIF: Day, OutsideRTH: true}, nil},
+		{"trail by percent", Ticket{Type: Trail, TrailPercent: 2, TIF: IOC}, nil},
+		{"trail by both", Ticket{Type: Trail, TrailAmount: 1, TrailPercent: 2, TIF: Day}, ErrBadTrail},
+		{"trail limit without offset", Ticket{Type: TrailLimit, TrailAmount: 1, TIF: Day}, ErrBadPrice},
+		{"trail limit", Ticket{Type: TrailLimit, TrailPercent: 1, LimitOffset: 0.1, TIF: Day}, nil},
+		{"moc", Ticket{Type: MarketOnClose, TIF: Day}, nil},
+		{"moc gtc", Ticket{Type: MarketOnClose, TIF: GTC}, ErrBadTIF},
+		{"loo", Ticket{Type: LimitOnOpen, LimitPrice: 190, TIF: OpeningAuction}, nil},
+		{"moo outside rth", Ticket{Type: MarketOnOpen, TIF: OpeningAuction, OutsideRTH: true}, ErrOutsideRTH},
+		{"rel", Ticket{Type: Relative, Offset: 0.01, TIF: Day}, nil},
+		{"rel without offset", Ticket{Type: Relative, LimitPrice: 190, TIF: Day}, ErrBadPrice},
+		{"gtd", Ticket{Type: Market, TIF: GTD, GoodTill: "20991231 16:00:00"}, nil},
+		{"gtd without expiry", Ticket{Type: Market, TIF: GTD}, ErrBadGoodTill},
+		{"gtd expired", Ticket{Type: Market, TIF: GTD, GoodTill: "20200102 16:00:00"}, ErrPastGoodTill},
+		{"fok", Ticket{Type: Limit, LimitPrice: 190, TIF: FOK}, nil},
+	}
+	for _, c := range cases {
+		c.ticket.Spec, c.ticket.Side, c.ticket.Quantity = aapl, Buy, 10
+		if err := c.ticket.Validate(); !errors.Is(err, c.want) {
+			t.Fatalf("%s: expected %v got %v", c.name, c.want, err)
+		}
+	}
+
+	moc := Ticket{Spec: es, Side: Buy, Quantity: 1, Type: MarketOnClose, TIF: Day}
+	if err := moc.Validate(); !errors.Is(err, ErrWrongSecType) {
+		t.Fatalf("expected ErrWrongSecType for a futures MOC got %v", err)
+	}
+}
+
+func TestTicket_IB(t *testing.T) {
+	moo := Ticket{Type: MarketOnOpen, TIF: OpeningAuction}
+	if orderType, tif := moo.IB(); orderType != "MKT" || tif != "OPG" {
+		t.Fatalf("expected MOO to be sent as MKT OPG got %v %v", orderType, tif)
+	}
+	stpLmt := Ticket{Type: StopLimit, TIF: GTC}
+	if orderType, tif := stpLmt.IB(); orderType != "STP LMT" || tif != "GTC" {
+		t.Fatalf("expected STP LMT GTC got %v %v", orderType, tif)
+	}
+}
+
+func TestParseTrail(t *testing.T) {
+	if trail, err := ParseTrail("2.5%"); err != nil || trail != (TrailBy{Percent: 2.5}) {
+		t.Fatalf("ParseTrail(2.5%%) = %+v, %v", trail, err)
+	}
+	if trail, err := ParseTrail("0.75"); err != nil || trail != (TrailBy{Amount: 0.75}) {
+		t.Fatalf("ParseTrail(0.75) = %+v, %v", trail, err)
+	}
+	if _, err := ParseTrail("150%"); !errors.Is(err, ErrBadTrail) {
+		t.Fatalf("expected ErrBadTrail for 150%% got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package panels
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	columnGap      = 2
+	thousandsGroup = 3
+)
+
+// RenderTable aligns a header and rows into fixed width columns.
+// The first leftCols columns are left aligned (e.g. symbols), the rest
+// are right aligned so that numbers line up on their decimal places.
+func RenderTable(header []string, rows [][]string, leftCols int) string {
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = lipgloss.Width(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], lipgloss.Width(cell))
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	lines = append(lines, renderRow(header, widths, leftCols))
+	for _, row := range rows {
+		lines = append(lines, renderRow(row, widths, leftCols))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Pad each cell of a row to its column width.
+func renderRow(row []string, widths []int, leftCols int) string {
+	cells := make([]string, len(widths))
+	for i, w := range widths {
+		var cell string
+		if i < len(row) {
+			cell = row[i]
+		}
+		pad := strings.Repeat(" ", w-lipgloss.Width(cell))
+		if i < leftCols {
+			cells[i] = cell + pad
+		} else {
+			cells[i] = pad + cell
+		}
+	}
+	return strings.TrimRight(strings.Join(cells, strings.Repeat(" ", columnGap)), " ")
+}
+
+// FormatNumber formats a float to a fixed number of decimals with
+// thousands separators, or as few as needed if decimals is -1.
+// NaN and infinities (IB's "unset") render as "-".
+func FormatNumber(v float64, decimals int) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) || v == math.MaxFloat64 {
+		return "-"
+	}
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%thousandsGroup == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if hasFrac {
+		b.WriteByte('.')
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package panels
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRenderTable(t *testing.T) {
+	header := []string{"Symbol", "Qty", "Price"}
+	rows := [][]string{
+		{"AAPL", "100", "1.50"},
+		{"MSFT", "5", "412.25"},
+	}
+	got := RenderTable(header, rows, 1)
+	lines := strings.Split(got, "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines got %d: %q", len(lines), got)
+	}
+	if lines[1] != "AAPL    100    1.50" {
+		t.Fatalf("unexpected row alignment: %q", lines[1])
+	}
+	if lines[2] != "MSFT      5  412.25" {
+		t.Fatalf("unexpected row alignment: %q", lines[2])
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	cases := []struct {
+		in       float64
+		decimals int
+		want     string
+	}{
+		{0, 2, "0.00"},
+		{1234567.891, 2, "1,234,567.89"},
+		{-1234.5, 1, "-1,234.5"},
+		{-0.001, 2, "0.00"},
+		{100, 0, "100"},
+		{12.5, -1, "12.5"},
+		{math.NaN(), 2, "-"},
+		{math.MaxFloat64, 2, "-"},
+	}
+	for _, c := range cases {
+		if got := FormatNumber(c.in, c.decimals); got != c.want {
+			t.Errorf("FormatNumber(%v, %d) = %q, want %q", c.in, c.decimals, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package risk holds orders up against configured limits before they are sent,
+// to catch fat-fingered prices and sizes.
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/glenntam/ibtui/internal/order"
+)
+
+const percent = 100
+
+// ErrSymbolNotAllowed occurs when a symbol isn't on the allowed list.
+var ErrSymbolNotAllowed = errors.New("symbol isn't allowed")
+
+// ErrSecTypeNotAllowed occurs when a security type isn't on the allowed list.
+var ErrSecTypeNotAllowed = errors.New("security type isn't allowed")
+
+// ErrMaxQuantity occurs when an order is bigger than the maximum quantity.
+var ErrMaxQuantity = errors.New("quantity over the limit")
+
+// ErrMaxNotional occurs when an order is worth more than the maximum notional.
+var ErrMaxNotional = errors.New("notional over the limit")
+
+// ErrUnpriced occurs when an order can't be valued against the notional limit or price band.
+var ErrUnpriced = errors.New("no price to check the order against")
+
+// ErrPriceBand occurs when an order's price is too far from the market.
+var ErrPriceBand = errors.New("price outside the band around the market")
+
+// ErrMaxOpenOrders occurs when a symbol already has the maximum number of open orders.
+var ErrMaxOpenOrders = errors.New("too many open orders for the symbol")
+
+// Limits are the pre-trade checks every order must pass. A zero or empty limit isn't checked.
+type Limits struct {
+	MaxNotional      float64  // Quantity times price times multiplier, in the contract's currency
+	MaxQuantity      float64  // Shares or contracts per order
+	PriceBandPct     float64  // How far, in percent, a price may be from the last or mid price
+	MaxOpenPerSymbol int      // Open orders per symbol, counting the new one
+	AllowedSymbols   []string // Only these symbols may be traded
+	AllowedSecTypes  []string // Only these security types may be traded
+}
+
+// Market is what is known about a contract when its order is checked.
+// Prices are 0 when unknown.
+type Market struct {
+	Bid        float64
+	Ask        float64
+	Last       float64
+	Multiplier float64 // Contract multiplier, 0 for contracts without one, e.g. stocks
+	OpenOrders int     // Already working for the symbol
+}
+
+// Reference is the price an order is judged against: the bid/ask midpoint, or else the last price.
+func (mkt Market) Reference() float64 {
+	if mkt.Bid > 0 && mkt.Ask > 0 {
+		return (mkt.Bid + mkt.Ask) / 2 //nolint:mnd // Midpoint
+	}
+	return mkt.Last
+}
+
+// The contract's multiplier, or 1 for contracts without one.
+func (mkt Market) multiplier() float64 {
+	if mkt.Multiplier > 0 {
+		return mkt.Multiplier
+	}
+	return 1
+}
+
+// Check returns every limit t breaks, joined into one error, or nil if it passes.
+func (l Limits) Check(t order.Ticket, mkt Market) error {
+	var errs []error
+	if len(l.AllowedSymbols) > 0 && !containsFold(l.AllowedSymbols, t.Spec.Symbol) {
+		errs = append(errs, fmt.Errorf("%v: %w", t.Spec.Symbol, ErrSymbolNotAllowed))
+	}
+	if len(l.AllowedSecTypes) > 0 && !containsFold(l.AllowedSecTypes, t.Spec.SecType) {
+		errs = append(errs, fmt.Errorf("%v: %w", t.Spec.SecType, ErrSecTypeNotAllowed))
+	}
+	if l.MaxQuantity > 0 && t.Quantity > l.MaxQuantity {
+		errs = append(errs, fmt.Errorf("%v > %v: %w", t.Quantity, l.MaxQuantity, ErrMaxQuantity))
+	}
+	if l.MaxOpenPerSymbol > 0 && mkt.OpenOrders+1 > l.MaxOpenPerSymbol {
+		errs = append(errs, fmt.Errorf("%v already has %d: %w", t.Spec.Symbol, mkt.OpenOrders, ErrMaxOpenOrders))
+	}
+	errs = append(errs, l.checkPrice(t, mkt)...)
+	return errors.Join(errs...)
+}
+
+// CheckBracket returns every limit b breaks, joined into one error, or nil
+// if it passes. The bracket is checked once, as its entry: every leg counts
+// towards the open orders, but only the entry is held to the price band,
+// since exits are set away from the market on purpose.
+func (l Limits) CheckBracket(b order.Bracket, mkt Market) error {
+	mkt.OpenOrders += len(b.Tickets()) - 1
+	return l.Check(b.Entry, mkt)
+}
+
+// Check the order's value and its price against the market.
+func (l Limits) checkPrice(t order.Ticket, mkt Market) []error {
+	var errs []error
+	ref := mkt.Reference()
+	named := orderPrice(t)
+	if l.MaxNotional > 0 {
+		price := named
+		if price == 0 {
+			price = ref // Market orders fill near the market
+		}
+		switch notional := t.Quantity * price * mkt.multiplier(); {
+		case price == 0:
+			errs = append(errs, fmt.Errorf("notional: %w", ErrUnpriced))
+		case notional > l.MaxNotional:
+			errs = append(errs, fmt.Errorf("%.2f > %.2f: %w", notional, l.MaxNotional, ErrMaxNotional))
+		}
+	}
+	if l.PriceBandPct > 0 && named > 0 {
+		if ref == 0 {
+			errs = append(errs, fmt.Errorf("price band: %w", ErrUnpriced))
+		} else if off := math.Abs(named-ref) / ref * percent; off > l.PriceBandPct {
+			errs = append(errs, fmt.Errorf("%v is %.1f%% from %v, over %v%%: %w",
+				named, off, ref, l.PriceBandPct, ErrPriceBand))
+		}
+	}
+	return errs
+}
+
+// The price the order names, if any: its limit, else its stop.
+func orderPrice(t order.Ticket) float64 {
+	switch {
+	case t.Type.UsesLimit() && t.LimitPrice > 0:
+		return t.LimitPrice
+	case t.Type.NeedsStop():
+		return t.StopPrice
+	}
+	return 0
+}
+
+// Report whether list has s, ignoring case.
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool {
+		return strings.EqualFold(v, s)
+	})
+}
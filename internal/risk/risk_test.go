@@ -0,0 +1,95 @@
+package risk
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glenntam/ibtui/internal/contract"
+	"github.com/glenntam/ibtui/internal/order"
+)
+
+func TestLimits_Check(t *testing.T) {
+	limits := Limits{
+		MaxNotional:      50_000,
+		MaxQuantity:      1_000,
+		PriceBandPct:     5,
+		MaxOpenPerSymbol: 3,
+		AllowedSymbols:   []string{"AAPL", "msft"},
+		AllowedSecTypes:  []string{"STK"},
+	}
+	mkt := Market{Bid: 99.9, Ask: 100.1, Last: 100}
+	ok := order.Ticket{
+		Spec:       contract.Spec{Symbol: "MSFT", SecType: "STK"},
+		Side:       order.Buy,
+		Quantity:   100,
+		Type:       order.Limit,
+		LimitPrice: 101,
+	}
+	if err := limits.Check(ok, mkt); err != nil {
+		t.Fatalf("expected order within limits to pass got %v", err)
+	}
+
+	cases := []struct {
+		name string
+		edit func(*order.Ticket, *Market)
+		want error
+	}{
+		{"symbol", func(t *order.Ticket, _ *Market) { t.Spec.Symbol = "TSLA" }, ErrSymbolNotAllowed},
+		{"sec type", func(t *order.Ticket, _ *Market) { t.Spec.SecType = "OPT" }, ErrSecTypeNotAllowed},
+		{"quantity", func(t *order.Ticket, _ *Market) { t.Quantity = 1_001 }, ErrMaxQuantity},
+		{"notional", func(t *order.Ticket, _ *Market) { t.Quantity = 600 }, ErrMaxNotional},
+		{"fat finger", func(t *order.Ticket, _ *Market) { t.LimitPrice = 110 }, ErrPriceBand},
+		{"open orders", func(_ *order.Ticket, m *Market) { m.OpenOrders = 3 }, ErrMaxOpenOrders},
+		{"unpriced market order", func(t *order.Ticket, m *Market) {
+			t.Type = order.Market
+			*m = Market{}
+		}, ErrUnpriced},
+	}
+	for _, c := range cases {
+		ticket, market := ok, mkt
+		c.edit(&ticket, &market)
+		if err := limits.Check(ticket, market); !errors.Is(err, c.want) {
+			t.Fatalf("%s: expected %v got %v", c.name, c.want, err)
+		}
+	}
+
+	es := order.Ticket{
+		Spec:       contract.Spec{Symbol: "ES", SecType: "FUT"},
+		Side:       order.Buy,
+		Quantity:   1,
+		Type:       order.Limit,
+		LimitPrice: 5000,
+	}
+	futures := Limits{MaxNotional: 50_000}
+	if err := futures.Check(es, Market{Last: 5000}); err != nil {
+		t.Fatalf("expected 1 contract at 5000 to pass without a multiplier got %v", err)
+	}
+	if err := futures.Check(es, Market{Last: 5000, Multiplier: 50}); !errors.Is(err, ErrMaxNotional) {
+		t.Fatalf("expected ErrMaxNotional for 1 contract worth 250,000 got %v", err)
+	}
+
+	if err := (Limits{}).Check(order.Ticket{Quantity: 1e9, Type: order.Market}, Market{}); err != nil {
+		t.Fatalf("expected zero limits to check nothing got %v", err)
+	}
+}
+
+func TestLimits_CheckBracket(t *testing.T) {
+	limits := Limits{MaxNotional: 50_000, PriceBandPct: 5, MaxOpenPerSymbol: 3}
+	b := order.Bracket{
+		Entry: order.Ticket{
+			Spec:       contract.Spec{Symbol: "AAPL", SecType: "STK"},
+			Side:       order.Buy,
+			Quantity:   400,
+			Type:       order.Limit,
+			LimitPrice: 100,
+		},
+		TakeProfit: 120,
+		StopLoss:   92,
+	}
+	if err := limits.CheckBracket(b, Market{Last: 100}); err != nil {
+		t.Fatalf("expected exits outside the band and a 40,000 entry to pass got %v", err)
+	}
+	if err := limits.CheckBracket(b, Market{Last: 100, OpenOrders: 1}); !errors.Is(err, ErrMaxOpenOrders) {
+		t.Fatalf("expected the bracket's 3 orders and 1 working to break the open order limit got %v", err)
+	}
+}
@@ -1,8 +1,11 @@
-// Package state polls IB Gateway/TWS to receive current state of account.
+// Package state follows IB Gateway/TWS to keep the current state of the account.
 package state
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/scmhub/ibsync"
@@ -11,29 +14,79 @@ import (
 const (
 	oneThousand = 1_000
 	oneMillion  = 1_000_000
+	percent     = 100
 )
 
-// IBState constains the results of polling the IB account state.
+// IBState contains the latest known IB account state, as pushed by a Feed.
+// It is safe for concurrent use; read it through Snapshot.
 type IBState struct {
-	CurrentTime time.Time
+	mu          sync.RWMutex
+	snap        Snapshot
+	positionPnL map[pnlKey]PnL
+}
+
+// Snapshot is a point in time copy of IBState for rendering.
+// Nothing in it is shared with IBState, so it never changes underneath a reader.
+type Snapshot struct {
+	CurrentTime    time.Time
+	Accounts       []string // Managed accounts reported by IB on connect
+	ActiveAccount  string   // One of Accounts, or AllAccounts
+	Summaries      map[string]AccountSummary
+	PnLs           map[string]PnL
+	Portfolio      []PortfolioItem
+	OpenOrders     []OpenOrder
+	DoneOrders     []OpenOrder     // Filled or cancelled this session
+	Executions     []Execution     // Newest first
+	Quotes         map[int64]Quote // Keyed by contract ID
+	MarketDataType MarketDataType  // Last requested from IB
+	Chart          Chart           // Streamed through Feed.StreamBars
 }
 
 // NewIBState makes a new IBSState container.
 func NewIBState() *IBState {
 	return &IBState{
-		CurrentTime: time.Now(),
+		snap: Snapshot{
+			CurrentTime:   time.Now(),
+			ActiveAccount: AllAccounts,
+			Summaries:     make(map[string]AccountSummary),
+			PnLs:          make(map[string]PnL),
+			Quotes:        make(map[int64]Quote),
+		},
+		positionPnL: make(map[pnlKey]PnL),
 	}
 }
 
+// Snapshot returns a deep copy of the current state.
+func (s *IBState) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snap := s.snap
+	snap.Accounts = slices.Clone(s.snap.Accounts)
+	snap.Summaries = maps.Clone(s.snap.Summaries)
+	snap.PnLs = maps.Clone(s.snap.PnLs)
+	snap.Portfolio = slices.Clone(s.snap.Portfolio)
+	snap.OpenOrders = slices.Clone(s.snap.OpenOrders)
+	snap.DoneOrders = slices.Clone(s.snap.DoneOrders)
+	snap.Executions = slices.Clone(s.snap.Executions)
+	snap.Quotes = maps.Clone(s.snap.Quotes)
+	snap.Chart.Bars = slices.Clone(s.snap.Chart.Bars)
+	return snap
+}
+
+// SetCurrentTime records IB's server time.
+func (s *IBState) SetCurrentTime(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.CurrentTime = t
+}
+
 // ReqCurrentTimeMilli retrieves IB account system time in time.Time format.
-func (s *IBState) ReqCurrentTimeMilli(ib *ibsync.IB) error {
+func ReqCurrentTimeMilli(ib *ibsync.IB) (time.Time, error) {
 	m, err := ib.ReqCurrentTimeInMillis()
 	if err != nil {
-		s.CurrentTime = time.Now()
-		return fmt.Errorf("couldn't request IB time, using system time instead: %w", err)
+		return time.Now(), fmt.Errorf("couldn't request IB time, using system time instead: %w", err)
 	}
 	seconds := m / oneThousand
 	nanoseconds := (m % oneThousand) * oneMillion
-	s.CurrentTime = time.Unix(seconds, nanoseconds)
-	return nil
+	return time.Unix(seconds, nanoseconds), nil
 }
@@ -0,0 +1,80 @@
+package state
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshot_is_isolated_from_later_writes(t *testing.T) {
+	s := NewIBState()
+	s.SetAccounts([]string{"U1"})
+	s.SetSummaries(map[string]AccountSummary{"U1": {NetLiquidation: 1}})
+	s.SetPortfolio([]PortfolioItem{{Account: "U1", ConID: 1, Symbol: "AAPL"}})
+
+	snap := s.Snapshot()
+	snap.Accounts[0] = "mutated"
+	snap.Summaries["U1"] = AccountSummary{NetLiquidation: 99}
+	snap.Portfolio[0].Symbol = "mutated"
+
+	s.SetPnL("U1", 1, PnL{Daily: 5})
+	s.SetPnL("U1", 0, PnL{Daily: 7})
+
+	after := s.Snapshot()
+	if after.Accounts[0] != "U1" || after.Summaries["U1"].NetLiquidation != 1 || after.Portfolio[0].Symbol != "AAPL" {
+		t.Fatalf("mutating a snapshot leaked into IBState: %+v", after)
+	}
+	if snap.Portfolio[0].DailyPNL != 0 || len(snap.PnLs) != 0 {
+		t.Fatalf("a later write leaked into an earlier snapshot: %+v", snap)
+	}
+}
+
+// Run with -race: writers stand in for Feed goroutines, readers for View().
+func TestIBState_concurrent_writes_and_snapshots(t *testing.T) {
+	s := NewIBState()
+	const rounds = 200
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range rounds {
+			s.SetCurrentTime(time.Unix(int64(i), 0))
+			s.SetAccounts([]string{"U1", "U2"})
+			s.SetSummaries(map[string]AccountSummary{"U1": {NetLiquidation: float64(i)}})
+			s.SetPortfolio([]PortfolioItem{{Account: "U1", ConID: 1, Position: float64(i)}})
+		}
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range rounds {
+			s.SetPnL("U1", 1, PnL{Daily: float64(i)})
+			s.SetPnL("U2", 0, PnL{Daily: float64(i)})
+			s.CycleAccount()
+		}
+	}()
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range rounds {
+				snap := s.Snapshot()
+				_ = snap.ActiveSummary()
+				_ = snap.ActivePnL()
+				for _, p := range snap.ActivePortfolio() {
+					_ = p.DailyPNL
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	snap := s.Snapshot()
+	if got := snap.Summaries["U1"].NetLiquidation; got != rounds-1 {
+		t.Fatalf("expected last written NetLiquidation %d got %v", rounds-1, got)
+	}
+	if got := snap.Portfolio[0].DailyPNL; got != rounds-1 {
+		t.Fatalf("expected last streamed daily P&L %d got %v", rounds-1, got)
+	}
+}
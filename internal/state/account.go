@@ -0,0 +1,167 @@
+package state
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/scmhub/ibsync"
+)
+
+const (
+	// AllAccounts selects the aggregate view across every managed account.
+	AllAccounts = ""
+
+	// baseCurrency is the pseudo currency IB uses for values converted to the account's base currency.
+	baseCurrency = "BASE"
+)
+
+// AccountSummary contains the headline balances and margin figures of an IB account.
+type AccountSummary struct {
+	Account         string  `json:"account"`
+	Currency        string  `json:"currency"`
+	NetLiquidation  float64 `json:"netLiquidation"`
+	TotalCashValue  float64 `json:"totalCashValue"`
+	BuyingPower     float64 `json:"buyingPower"`
+	InitMarginReq   float64 `json:"initMarginReq"`
+	MaintMarginReq  float64 `json:"maintMarginReq"`
+	ExcessLiquidity float64 `json:"excessLiquidity"`
+	Cushion         float64 `json:"cushion"`
+}
+
+// SetAccounts replaces the managed accounts list. A single account is
+// selected automatically; advisors start on the AllAccounts aggregate.
+func (s *IBState) SetAccounts(accounts []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.Accounts = slices.Clone(accounts)
+	switch {
+	case len(accounts) == 1:
+		s.snap.ActiveAccount = accounts[0]
+	case !slices.Contains(accounts, s.snap.ActiveAccount):
+		s.snap.ActiveAccount = AllAccounts
+	}
+}
+
+// CycleAccount selects the next managed account, wrapping around through
+// the AllAccounts aggregate view.
+func (s *IBState) CycleAccount() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accounts := s.snap.Accounts
+	if len(accounts) <= 1 {
+		return
+	}
+	i := slices.Index(accounts, s.snap.ActiveAccount) // -1 for AllAccounts
+	if i+1 < len(accounts) {
+		s.snap.ActiveAccount = accounts[i+1]
+	} else {
+		s.snap.ActiveAccount = AllAccounts
+	}
+}
+
+// SetSummaries replaces the per-account summaries.
+func (s *IBState) SetSummaries(summaries map[string]AccountSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.Summaries = maps.Clone(summaries)
+}
+
+// ActiveAccountLabel names the selected account for display.
+func (s Snapshot) ActiveAccountLabel() string {
+	if s.ActiveAccount == AllAccounts {
+		return "All"
+	}
+	return s.ActiveAccount
+}
+
+// InActiveAccount reports whether data belonging to account should be shown.
+func (s Snapshot) InActiveAccount(account string) bool {
+	return s.ActiveAccount == AllAccounts || s.ActiveAccount == account
+}
+
+// ActiveSummary returns the selected account's summary, or the sum of
+// every account's summary when AllAccounts is selected.
+func (s Snapshot) ActiveSummary() AccountSummary {
+	if s.ActiveAccount != AllAccounts {
+		return s.Summaries[s.ActiveAccount]
+	}
+	total := AccountSummary{Account: AllAccounts}
+	for _, account := range s.Accounts {
+		a := s.Summaries[account]
+		if total.Currency == "" {
+			total.Currency = a.Currency
+		}
+		total.NetLiquidation += a.NetLiquidation
+		total.TotalCashValue += a.TotalCashValue
+		total.BuyingPower += a.BuyingPower
+		total.InitMarginReq += a.InitMarginReq
+		total.MaintMarginReq += a.MaintMarginReq
+		total.ExcessLiquidity += a.ExcessLiquidity
+	}
+	if total.NetLiquidation != 0 {
+		total.Cushion = total.ExcessLiquidity / total.NetLiquidation
+	}
+	return total
+}
+
+// Summarize ibsync's streamed account values for each account.
+func reqSummaries(ib *ibsync.IB, accounts []string) map[string]AccountSummary {
+	summaries := make(map[string]AccountSummary, len(accounts))
+	for _, account := range accounts {
+		summaries[account] = summarize(ib.AccountValues(account))
+	}
+	return summaries
+}
+
+// ReqSummaries returns the summary of each account that IB has sent
+// balances for so far, leaving out those it hasn't.
+func (f *Feed) ReqSummaries(accounts []string) map[string]AccountSummary {
+	summaries := reqSummaries(f.ib, accounts)
+	maps.DeleteFunc(summaries, func(_ string, s AccountSummary) bool { return s.Account == "" })
+	return summaries
+}
+
+// Reduce a stream of tagged account values into an AccountSummary.
+// Values converted to the base currency take priority over per-currency ones.
+func summarize(values []ibsync.AccountValue) AccountSummary {
+	var sum AccountSummary
+	fromBase := make(map[string]bool)
+	for _, v := range values {
+		var field *float64
+		switch v.Tag {
+		case "NetLiquidation":
+			field = &sum.NetLiquidation
+		case "TotalCashValue":
+			field = &sum.TotalCashValue
+		case "BuyingPower":
+			field = &sum.BuyingPower
+		case "InitMarginReq":
+			field = &sum.InitMarginReq
+		case "MaintMarginReq":
+			field = &sum.MaintMarginReq
+		case "ExcessLiquidity":
+			field = &sum.ExcessLiquidity
+		case "Cushion":
+			field = &sum.Cushion
+		default:
+			continue
+		}
+		if fromBase[v.Tag] && v.Currency != baseCurrency {
+			continue
+		}
+		f, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil {
+			continue
+		}
+		*field = f
+		fromBase[v.Tag] = v.Currency == baseCurrency
+		if sum.Account == "" {
+			sum.Account = v.Account
+		}
+		if v.Tag == "NetLiquidation" {
+			sum.Currency = v.Currency
+		}
+	}
+	return sum
+}
@@ -0,0 +1,70 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/scmhub/ibsync"
+)
+
+func TestSummarize_prefers_base_currency(t *testing.T) {
+	values := []ibsync.AccountValue{
+		{Account: "DU123", Tag: "NetLiquidation", Value: "100500.25", Currency: "USD"},
+		{Account: "DU123", Tag: "TotalCashValue", Value: "9000", Currency: "BASE"},
+		{Account: "DU123", Tag: "TotalCashValue", Value: "-250", Currency: "EUR"},
+		{Account: "DU123", Tag: "Cushion", Value: "0.42", Currency: ""},
+		{Account: "DU123", Tag: "AccountType", Value: "INDIVIDUAL", Currency: ""},
+		{Account: "DU123", Tag: "BuyingPower", Value: "not a number", Currency: "USD"},
+	}
+	sum := summarize(values)
+	if sum.Account != "DU123" || sum.Currency != "USD" {
+		t.Fatalf("expected account DU123 in USD got %s in %s", sum.Account, sum.Currency)
+	}
+	if sum.NetLiquidation != 100500.25 {
+		t.Fatalf("expected NetLiquidation 100500.25 got %v", sum.NetLiquidation)
+	}
+	if sum.TotalCashValue != 9000 {
+		t.Fatalf("expected BASE TotalCashValue 9000 got %v", sum.TotalCashValue)
+	}
+	if sum.Cushion != 0.42 {
+		t.Fatalf("expected Cushion 0.42 got %v", sum.Cushion)
+	}
+	if sum.BuyingPower != 0 {
+		t.Fatalf("expected unparsable BuyingPower to be skipped got %v", sum.BuyingPower)
+	}
+}
+
+func TestCycleAccount(t *testing.T) {
+	s := NewIBState()
+	s.SetAccounts([]string{"U1", "U2"})
+	want := []string{"U1", "U2", AllAccounts, "U1"}
+	for _, w := range want {
+		s.CycleAccount()
+		if got := s.Snapshot().ActiveAccount; got != w {
+			t.Fatalf("expected active account %q got %q", w, got)
+		}
+	}
+	if snap := s.Snapshot(); !snap.InActiveAccount("U1") || snap.InActiveAccount("U2") {
+		t.Fatalf("expected only U1 to be in the active account")
+	}
+}
+
+func TestActiveSummary_aggregates_all_accounts(t *testing.T) {
+	s := NewIBState()
+	s.SetAccounts([]string{"U1", "U2"})
+	s.SetSummaries(map[string]AccountSummary{
+		"U1": {Account: "U1", Currency: "USD", NetLiquidation: 1000, ExcessLiquidity: 500},
+		"U2": {Account: "U2", Currency: "USD", NetLiquidation: 3000, ExcessLiquidity: 500},
+	})
+	sum := s.Snapshot().ActiveSummary()
+	if sum.NetLiquidation != 4000 {
+		t.Fatalf("expected aggregate NetLiquidation 4000 got %v", sum.NetLiquidation)
+	}
+	if sum.Cushion != 0.25 {
+		t.Fatalf("expected aggregate Cushion 0.25 got %v", sum.Cushion)
+	}
+	s.CycleAccount()
+	s.CycleAccount()
+	if got := s.Snapshot().ActiveSummary().NetLiquidation; got != 3000 {
+		t.Fatalf("expected U2 NetLiquidation 3000 got %v", got)
+	}
+}
@@ -0,0 +1,264 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/contract"
+	"github.com/scmhub/ibsync"
+)
+
+// Bar times come back as Unix seconds, or as a date for daily bars.
+const (
+	barFormatEpoch = 2
+	barDateLayout  = "20060102"
+)
+
+// ErrBadBarTime occurs when IB sends a bar time in a layout ibtui doesn't read.
+var ErrBadBarTime = errors.New("unrecognised bar time")
+
+// Timeframe is a chart's bar size, named as IB's historical data requests take it.
+type Timeframe string
+
+// Chart timeframes.
+const (
+	OneMinute   Timeframe = "1 min"
+	FiveMinutes Timeframe = "5 mins"
+	OneHour     Timeframe = "1 hour"
+	OneDay      Timeframe = "1 day"
+)
+
+// Bar is one period's open, high, low, close and volume.
+type Bar struct {
+	Time   time.Time // Start of the period
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+}
+
+// Timeframes lists every chart timeframe, shortest first.
+func Timeframes() []Timeframe {
+	return []Timeframe{OneMinute, FiveMinutes, OneHour, OneDay}
+}
+
+// String returns a short label, e.g. "5m".
+func (t Timeframe) String() string {
+	switch t {
+	case OneMinute:
+		return "1m"
+	case FiveMinutes:
+		return "5m"
+	case OneHour:
+		return "1h"
+	case OneDay:
+		return "1D"
+	}
+	return string(t)
+}
+
+// Next returns the timeframe after t, wrapping from 1D back to 1m.
+func (t Timeframe) Next() Timeframe {
+	all := Timeframes()
+	return all[(slices.Index(all, t)+1)%len(all)]
+}
+
+// Durations lists how far back a chart of t can reach, in IB's duration
+// syntax, the default first. Longer ones would be too many bars to fetch.
+func (t Timeframe) Durations() []string {
+	switch t {
+	case OneMinute:
+		return []string{"1 D", "2 D", "5 D"}
+	case FiveMinutes:
+		return []string{"5 D", "2 W", "1 M", "1 D"}
+	case OneHour:
+		return []string{"1 M", "3 M", "6 M", "1 W"}
+	case OneDay:
+	}
+	return []string{"1 Y", "2 Y", "5 Y", "6 M"}
+}
+
+// NextDuration returns the duration after d among t's, or t's default if d isn't one.
+func (t Timeframe) NextDuration(d string) string {
+	all := t.Durations()
+	return all[(slices.Index(all, d)+1)%len(all)]
+}
+
+// ChartKey is what a chart streams: a contract's bars of one timeframe
+// reaching back duration from now.
+type ChartKey struct {
+	Spec      contract.Spec
+	Timeframe Timeframe
+	Duration  string
+}
+
+// Chart is the streamed chart's bars, oldest first. The newest bar keeps
+// changing until its period ends.
+type Chart struct {
+	ChartKey
+	Bars []Bar
+}
+
+// BarsMsg is sent when the streamed chart gains or updates bars.
+type BarsMsg struct{}
+
+// SetChart starts a new, empty chart for key, dropping the previous one's bars.
+func (s *IBState) SetChart(key ChartKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.Chart = Chart{ChartKey: key}
+}
+
+// AddBars records streamed bars of the chart for key, each replacing the bar
+// with the same start time if there is one. Bars of any other chart are ignored.
+func (s *IBState) AddBars(key ChartKey, bars ...Bar) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := &s.snap.Chart
+	if c.ChartKey != key {
+		return
+	}
+	for _, b := range bars {
+		i, found := slices.BinarySearchFunc(c.Bars, b.Time, func(b Bar, t time.Time) int { return b.Time.Compare(t) })
+		if found {
+			c.Bars[i] = b
+			continue
+		}
+		c.Bars = slices.Insert(c.Bars, i, b)
+	}
+}
+
+// StreamBars resolves key's contract and streams its bars into IBState,
+// first the history and then each update as IB keeps it up to date. It
+// replaces any chart streamed before, unless a later call replaced it first.
+// It blocks on IB, so call it from a tea.Cmd.
+func (f *Feed) StreamBars(key ChartKey) error {
+	c, err := f.qualify(key.Spec)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.barsRequests++
+	request := f.barsRequests
+	f.mu.Unlock()
+	ch, cancel := f.ib.ReqHistoricalDataUpToDate(
+		c, key.Duration, string(key.Timeframe), whatToShow(key.Spec), false, barFormatEpoch)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if request != f.barsRequests {
+		cancel() // A later chart was asked for while this one was requested
+		return nil
+	}
+	f.cancelBars()
+	stop := make(chan struct{})
+	f.barsStop, f.barsCancel = stop, cancel
+	f.ibs.SetChart(key)
+	f.wg.Add(1)
+	go f.forwardBars(key, stop, ch)
+	return nil
+}
+
+// Add streamed bars to the chart for key and send BarsMsg, until stopped.
+// Bars already waiting, e.g. the history, are added together with one message.
+func (f *Feed) forwardBars(key ChartKey, stop <-chan struct{}, ch <-chan ibsync.Bar) {
+	defer f.wg.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		case ib, ok := <-ch:
+			if !ok {
+				return
+			}
+			batch, open := waitingBars(ch, []ibsync.Bar{ib})
+			bars := make([]Bar, 0, len(batch))
+			for _, ib := range batch {
+				b, err := toBar(ib)
+				if err != nil {
+					slog.Error("Couldn't read chart bar", "symbol", key.Spec.String(), "error", err)
+					continue
+				}
+				bars = append(bars, b)
+			}
+			f.ibs.AddBars(key, bars...)
+			f.send(BarsMsg{})
+			if !open {
+				return
+			}
+		}
+	}
+}
+
+// Append the bars already waiting on ch to batch, without blocking.
+// Reports false once ch is closed.
+func waitingBars(ch <-chan ibsync.Bar, batch []ibsync.Bar) ([]ibsync.Bar, bool) {
+	for {
+		select {
+		case b, ok := <-ch:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, b)
+		default:
+			return batch, true
+		}
+	}
+}
+
+// Stop streaming the chart's bars, if any. f.mu must be held.
+func (f *Feed) cancelBars() {
+	if f.barsStop == nil {
+		return
+	}
+	close(f.barsStop)
+	f.barsCancel()
+	f.barsStop, f.barsCancel = nil, nil
+}
+
+// Convert an ibsync bar.
+func toBar(b ibsync.Bar) (Bar, error) {
+	t, err := parseBarTime(b.Date)
+	if err != nil {
+		return Bar{}, err
+	}
+	return Bar{
+		Time:   t,
+		Open:   b.Open,
+		High:   b.High,
+		Low:    b.Low,
+		Close:  b.Close,
+		Volume: max(b.Volume.Float(), 0), // IB sends -1 where there's no volume, e.g. forex
+	}, nil
+}
+
+// Read a bar time: Unix seconds for intraday bars, a date in local time for daily ones.
+func parseBarTime(s string) (time.Time, error) {
+	if len(s) == len(barDateLayout) {
+		t, err := time.ParseInLocation(barDateLayout, s, time.Local)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("couldn't read bar date %q: %w", s, err)
+		}
+		return t, nil
+	}
+	secs, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("couldn't read bar time %q: %w", s, ErrBadBarTime)
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// Forex and commodities have no trades to chart, only quotes.
+func whatToShow(spec contract.Spec) string {
+	switch spec.SecType {
+	case "CASH", "CMDTY":
+		return "MIDPOINT"
+	}
+	return "TRADES"
+}
@@ -0,0 +1,86 @@
+package state
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/contract"
+	"github.com/scmhub/ibsync"
+)
+
+func TestToBar(t *testing.T) {
+	b, err := toBar(ibsync.Bar{Date: "1741185000", Open: 10, High: 12, Low: 9, Close: 11, Volume: 300})
+	if err != nil {
+		t.Fatalf("toBar returned unexpected error: %v", err)
+	}
+	if !b.Time.Equal(time.Unix(1741185000, 0)) || b.High != 12 || b.Volume != 300 {
+		t.Fatalf("expected an intraday bar at its Unix time got %+v", b)
+	}
+	b, err = toBar(ibsync.Bar{Date: "20250306", Open: 11, High: 11, Low: 10, Close: 10.5, Volume: -1})
+	if err != nil {
+		t.Fatalf("toBar returned unexpected error: %v", err)
+	}
+	if y, m, d := b.Time.Date(); y != 2025 || m != time.March || d != 6 || b.Volume != 0 {
+		t.Fatalf("expected a daily bar on 6 March without volume got %+v", b)
+	}
+	if _, err = toBar(ibsync.Bar{Date: "20250306 09:30:00"}); !errors.Is(err, ErrBadBarTime) {
+		t.Fatalf("expected ErrBadBarTime got %v", err)
+	}
+}
+
+func TestIBState_AddBars(t *testing.T) {
+	ibs := NewIBState()
+	key := ChartKey{Spec: contract.Spec{Symbol: "AAPL"}, Timeframe: OneMinute, Duration: "1 D"}
+	at := func(minute int) time.Time { return time.Date(2025, 3, 6, 9, minute, 0, 0, time.UTC) }
+	ibs.SetChart(key)
+	ibs.AddBars(key, Bar{Time: at(30), Close: 1})
+	ibs.AddBars(key, Bar{Time: at(32), Close: 3})
+	ibs.AddBars(key, Bar{Time: at(31), Close: 2})
+	ibs.AddBars(key, Bar{Time: at(32), Close: 4})
+	ibs.AddBars(ChartKey{Spec: contract.Spec{Symbol: "MSFT"}}, Bar{Time: at(33), Close: 5})
+
+	bars := ibs.Snapshot().Chart.Bars
+	var closes []float64
+	for _, b := range bars {
+		closes = append(closes, b.Close)
+	}
+	if !slices.Equal(closes, []float64{1, 2, 4}) {
+		t.Fatalf("expected bars in time order with the newest updated and other charts ignored got %v", closes)
+	}
+	ibs.SetChart(key)
+	if n := len(ibs.Snapshot().Chart.Bars); n != 0 {
+		t.Fatalf("expected a new chart to start empty got %d bars", n)
+	}
+}
+
+func TestFeed_forwardBars(t *testing.T) {
+	ibs := NewIBState()
+	key := ChartKey{Spec: contract.Spec{Symbol: "AAPL"}, Timeframe: OneMinute, Duration: "1 D"}
+	ibs.SetChart(key)
+	sent := 0
+	f := &Feed{ibs: ibs, send: func(any) { sent++ }}
+	ch := make(chan ibsync.Bar, 3)
+	for _, date := range []string{"1741253400", "1741253460", "1741253520"} {
+		ch <- ibsync.Bar{Date: date, Close: 1}
+	}
+	close(ch)
+	f.wg.Add(1)
+	f.forwardBars(key, make(chan struct{}), ch)
+	if n := len(ibs.Snapshot().Chart.Bars); n != 3 || sent != 1 {
+		t.Fatalf("expected the 3 waiting bars added with one BarsMsg got %d bars and %d messages", n, sent)
+	}
+}
+
+func TestTimeframe(t *testing.T) {
+	if OneDay.Next() != OneMinute || FiveMinutes.String() != "5m" {
+		t.Fatalf("expected 1D to wrap to 1m and 5 mins to read 5m")
+	}
+	if d := OneHour.NextDuration("6 M"); d != "1 W" {
+		t.Fatalf("expected 1 W after 6 M got %q", d)
+	}
+	if d := OneMinute.NextDuration("1 Y"); d != "1 D" {
+		t.Fatalf("expected a duration 1m can't chart to fall back to the default got %q", d)
+	}
+}
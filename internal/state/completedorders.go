@@ -0,0 +1,67 @@
+package state
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/scmhub/ibsync"
+)
+
+// CompletedOrder is an order that stopped working: filled, cancelled or
+// rejected, whether it was placed by ibtui, another API client or TWS.
+type CompletedOrder struct {
+	OpenOrder
+	AvgPrice float64   // Average fill price, 0 if nothing filled
+	Time     time.Time // When IB last reported on the order, zero if it didn't say
+	Reason   string    // IB's last word on the order, e.g. "Cancelled by Trader"
+}
+
+// ReqCompletedOrders asks IB for every order that finished working lately,
+// including those other clients or TWS placed or cancelled, newest first.
+// Set apiOnly to leave out orders placed in TWS. It blocks on IB, so call
+// it from a tea.Cmd.
+func (f *Feed) ReqCompletedOrders(apiOnly bool) ([]CompletedOrder, error) {
+	trades, err := f.ib.ReqCompletedOrders(apiOnly)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't request completed orders: %w", err)
+	}
+	return toCompletedOrders(trades), nil
+}
+
+// Convert finished ibsync trades to completed orders, newest first.
+func toCompletedOrders(trades []*ibsync.Trade) []CompletedOrder {
+	done := make([]CompletedOrder, 0, len(trades))
+	for _, t := range trades {
+		o, ok := toOpenOrder(t)
+		if !ok {
+			continue
+		}
+		done = append(done, completed(o, t))
+	}
+	slices.SortStableFunc(done, func(a, b CompletedOrder) int {
+		return cmp.Or(
+			cmp.Compare(b.Time.UnixNano(), a.Time.UnixNano()),
+			cmp.Compare(b.PermID, a.PermID),
+		)
+	})
+	return done
+}
+
+// Finish an order with what IB sent when it completed: its filled quantity,
+// average price, final status, and when and why it completed.
+func completed(o OpenOrder, t *ibsync.Trade) CompletedOrder {
+	c := CompletedOrder{OpenOrder: o, AvgPrice: orZero(t.OrderStatus.AvgFillPrice)}
+	if filled := t.Order.FilledQuantity.Float(); filled > 0 && isSet(filled) {
+		c.Filled, c.Remaining = filled, max(c.Quantity-filled, 0)
+	}
+	if s := t.OrderState; s != nil {
+		if s.Status != "" {
+			c.Status = s.Status
+		}
+		c.Reason = s.CompletedStatus
+		c.Time, _ = parseIBTime(s.CompletedTime)
+	}
+	return c
+}
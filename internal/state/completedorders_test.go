@@ -0,0 +1,35 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scmhub/ibsync"
+)
+
+func TestCompleted(t *testing.T) {
+	trade := &ibsync.Trade{
+		Order:       &ibsync.Order{PermID: 9, TotalQuantity: 40, FilledQuantity: 10},
+		OrderStatus: ibsync.OrderStatus{AvgFillPrice: 189.5},
+		OrderState: &ibsync.OrderState{
+			Status:          "Cancelled",
+			CompletedStatus: "Cancelled by Trader",
+			CompletedTime:   "20260302-14:30:05",
+		},
+	}
+	got := completed(OpenOrder{PermID: 9, Quantity: 40, Status: "PreSubmitted"}, trade)
+	if got.Reason != "Cancelled by Trader" || got.Status != "Cancelled" || got.AvgPrice != 189.5 {
+		t.Fatalf("expected the cancel reason and status IB completed the order with got %+v", got)
+	}
+	if !got.Time.Equal(time.Date(2026, 3, 2, 14, 30, 5, 0, time.UTC)) {
+		t.Fatalf("expected the completed time 14:30:05 UTC got %v", got.Time)
+	}
+	if got.Filled != 10 || got.Remaining != 30 {
+		t.Fatalf("expected 10 filled and 30 remaining got %+v", got)
+	}
+
+	bare := &ibsync.Trade{Order: &ibsync.Order{PermID: 9}, OrderStatus: ibsync.OrderStatus{AvgFillPrice: Unset}}
+	if got = completed(OpenOrder{PermID: 9}, bare); got.AvgPrice != 0 || !got.Time.IsZero() {
+		t.Fatalf("expected IB's unset average price and no time got %+v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package state
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/scmhub/ibsync"
+)
+
+// IB reads an execution filter's time in this layout, in UTC.
+const execFilterLayout = "20060102-15:04:05"
+
+// ExecutionsMsg is sent when an execution or its commission report arrives.
+type ExecutionsMsg struct{}
+
+// Execution is one fill joined with IB's commission report for it.
+// Commission and RealizedPnL stay 0 until the report arrives.
+type Execution struct {
+	ExecID             string
+	OrderID            int64
+	PermID             int64
+	Time               time.Time
+	Account            string
+	ConID              int64
+	Symbol             string
+	SecType            string
+	Exchange           string
+	Currency           string
+	Side               string // BOT or SLD
+	Quantity           float64
+	Price              float64
+	Multiplier         float64 // 0 for contracts without one, e.g. stocks
+	Commission         float64
+	CommissionCurrency string
+	RealizedPnL        float64
+}
+
+// SetExecutions replaces every known execution.
+func (s *IBState) SetExecutions(execs []Execution) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.Executions = execs
+}
+
+// ActiveExecutions returns the executions of the active account, or of all accounts.
+func (s Snapshot) ActiveExecutions() []Execution {
+	execs := make([]Execution, 0, len(s.Executions))
+	for _, e := range s.Executions {
+		if s.InActiveAccount(e.Account) {
+			execs = append(execs, e)
+		}
+	}
+	return execs
+}
+
+// ReqExecutions asks IB for executions since the given time, which IB only
+// keeps for a few days. They are merged with today's streamed fills from then
+// on. It blocks on IB, so call it from a tea.Cmd.
+func (f *Feed) ReqExecutions(since time.Time) error {
+	filter := ibsync.NewExecutionFilter()
+	filter.Time = since.UTC().Format(execFilterLayout)
+	fills, err := f.ib.ReqExecutions(filter)
+	if err != nil {
+		return fmt.Errorf("couldn't request executions: %w", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pastFills = fills
+	return nil
+}
+
+// Read ibsync's streamed fills plus any requested past ones.
+func (f *Feed) reqExecutions() []Execution {
+	f.mu.Lock()
+	fills := slices.Concat(f.pastFills, f.ib.Fills())
+	f.mu.Unlock()
+	return joinExecutions(fills)
+}
+
+// Join executions to their commission reports by execution ID, newest first.
+// A fill seen twice, e.g. streamed and then requested again, is kept once.
+func joinExecutions(fills []ibsync.Fill) []Execution {
+	reports := make(map[string]ibsync.CommissionReport)
+	for _, fill := range fills {
+		if r := fill.CommissionReport; r.ExecID != "" {
+			reports[r.ExecID] = r
+		}
+	}
+	seen := make(map[string]bool)
+	execs := make([]Execution, 0, len(fills))
+	for _, fill := range fills {
+		e, c := fill.Execution, fill.Contract
+		if e == nil || c == nil || seen[e.ExecID] {
+			continue
+		}
+		seen[e.ExecID] = true
+		r := reports[e.ExecID]
+		execs = append(execs, Execution{
+			ExecID:             e.ExecID,
+			OrderID:            e.OrderID,
+			PermID:             e.PermID,
+			Time:               fill.Time,
+			Account:            e.AcctNumber,
+			ConID:              c.ConID,
+			Symbol:             c.Symbol,
+			SecType:            c.SecType,
+			Exchange:           e.Exchange,
+			Currency:           c.Currency,
+			Side:               e.Side,
+			Quantity:           e.Shares.Float(),
+			Price:              e.Price,
+			Multiplier:         parseMultiplier(c.Multiplier),
+			Commission:         orZero(r.Commission),
+			CommissionCurrency: r.Currency,
+			RealizedPnL:        orZero(r.RealizedPNL),
+		})
+	}
+	slices.SortStableFunc(execs, func(a, b Execution) int {
+		return cmp.Compare(b.Time.UnixNano(), a.Time.UnixNano())
+	})
+	return execs
+}
+
+// IB sends a contract's multiplier as text, blank when it has none.
+func parseMultiplier(s string) float64 {
+	m, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return m
+}
@@ -0,0 +1,44 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scmhub/ibsync"
+)
+
+func TestJoinExecutions(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	aapl := &ibsync.Contract{ConID: 265598, Symbol: "AAPL", SecType: "STK", Currency: "USD"}
+	fills := []ibsync.Fill{
+		{
+			Contract:  aapl,
+			Execution: &ibsync.Execution{ExecID: "e1", OrderID: 5, AcctNumber: "U1", Side: "BOT", Shares: 40, Price: 189.5},
+			Time:      t0,
+		},
+		{
+			Contract: aapl,
+			Execution: &ibsync.Execution{
+				ExecID: "e2", OrderID: 5, AcctNumber: "U1", Side: "BOT", Shares: 60, Price: 189.4,
+			},
+			CommissionReport: ibsync.CommissionReport{ExecID: "e2", Commission: 1.2, Currency: "USD", RealizedPNL: Unset},
+			Time:             t0.Add(time.Second),
+		},
+		{
+			// The first fill's report arrived on a later copy of it
+			Contract:         aapl,
+			Execution:        &ibsync.Execution{ExecID: "e1"},
+			CommissionReport: ibsync.CommissionReport{ExecID: "e1", Commission: 1, Currency: "USD", RealizedPNL: 12.5},
+		},
+	}
+	got := joinExecutions(fills)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 executions got %+v", got)
+	}
+	if got[0].ExecID != "e2" || got[0].Commission != 1.2 || got[0].RealizedPnL != 0 {
+		t.Fatalf("expected the newest fill first without an unset P&L got %+v", got[0])
+	}
+	if got[1].ExecID != "e1" || got[1].Quantity != 40 || got[1].Commission != 1 || got[1].RealizedPnL != 12.5 {
+		t.Fatalf("expected the first fill joined to its report got %+v", got[1])
+	}
+}
@@ -0,0 +1,257 @@
+package state
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/risk"
+	"github.com/scmhub/ibsync"
+)
+
+const (
+	watchInterval     = 250 * time.Millisecond
+	quoteInterval     = 50 * time.Millisecond // Quotes are copied more often, so they lag ticks by at most this
+	heartbeatInterval = time.Second
+)
+
+// ClockMsg is sent on every heartbeat after IB's server time is updated.
+type ClockMsg struct{}
+
+// AccountsMsg is sent when the managed accounts list changes.
+type AccountsMsg struct{}
+
+// SummaryMsg is sent when any account's summary values change.
+type SummaryMsg struct{}
+
+// PortfolioMsg is sent when positions or their market values change.
+type PortfolioMsg struct{}
+
+// PnLMsg is sent on every P&L stream update.
+type PnLMsg struct{}
+
+// Feed follows ibsync's streamed account data, writes each change into an
+// IBState and then tells the TUI which part changed with a typed message
+// through send (e.g. a tea.Program's Send).
+//
+// ibsync keeps its account values, portfolio, orders, executions and tickers
+// up to date from IB's streaming callbacks, but only offers channels for P&L
+// and bars; the rest it only keeps in caches. So P&L and bars are read
+// straight off their channels, and the caches are watched: comparing them
+// against what was last sent costs no gateway requests, and only what changed
+// is sent. Tickers are watched on their own, faster, so quotes keep up with
+// the market. Only the heartbeat, which keeps the clock in sync, makes a
+// request to IB.
+type Feed struct {
+	ib   *ibsync.IB
+	ibs  *IBState
+	send func(msg any)
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	limits risk.Limits // Set before Start, read only after
+
+	mu           sync.Mutex // Guards quotes, pastFills and the bars stream, which tea.Cmds also change
+	quotes       map[int64]*quoteSub
+	pastFills    []ibsync.Fill // Requested through ReqExecutions
+	barsStop     chan struct{}
+	barsCancel   ibsync.CancelFunc
+	barsRequests int // Counts StreamBars calls, so only the latest one's stream is kept
+
+	// Only touched by the watch goroutine:
+	accounts   []string
+	summaries  map[string]AccountSummary
+	portfolio  []PortfolioItem
+	openOrders []OpenOrder
+	doneOrders []OpenOrder
+	executions []Execution
+	pnlStops   map[pnlKey]chan struct{}
+}
+
+// NewFeed makes a Feed that hasn't started yet.
+func NewFeed(ib *ibsync.IB, ibs *IBState, send func(msg any)) *Feed {
+	return &Feed{
+		ib:       ib,
+		ibs:      ibs,
+		send:     send,
+		stop:     make(chan struct{}),
+		quotes:   make(map[int64]*quoteSub),
+		pnlStops: make(map[pnlKey]chan struct{}),
+	}
+}
+
+// Start the heartbeat and watcher goroutines.
+func (f *Feed) Start() {
+	f.wg.Add(1)
+	go f.heartbeat()
+	f.wg.Add(1)
+	go f.watch()
+	f.wg.Add(1)
+	go f.watchQuotes()
+}
+
+// Stop every goroutine and cancel P&L, market data and chart subscriptions.
+func (f *Feed) Stop() {
+	close(f.stop)
+	f.wg.Wait()
+}
+
+// Send IB's clock time at a low frequency.
+func (f *Feed) heartbeat() {
+	defer f.wg.Done()
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		t, err := ReqCurrentTimeMilli(f.ib)
+		if err != nil {
+			slog.Error("Couldn't get time from IB API", "error", err)
+		}
+		f.ibs.SetCurrentTime(t)
+		f.send(ClockMsg{})
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Compare ibsync's account caches against what was last sent and
+// send only what changed.
+func (f *Feed) watch() {
+	defer f.wg.Done()
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		if accounts := f.ib.ManagedAccounts(); !slices.Equal(accounts, f.accounts) {
+			f.accounts = accounts
+			f.ibs.SetAccounts(accounts)
+			f.send(AccountsMsg{})
+		}
+		if summaries := reqSummaries(f.ib, f.accounts); !maps.Equal(summaries, f.summaries) {
+			f.summaries = summaries
+			f.ibs.SetSummaries(summaries)
+			f.send(SummaryMsg{})
+		}
+		if items := reqPortfolio(f.ib); !slices.Equal(items, f.portfolio) {
+			f.portfolio = items
+			f.ibs.SetPortfolio(items)
+			f.send(PortfolioMsg{})
+		}
+		if orders := reqOpenOrders(f.ib); !slices.Equal(orders, f.openOrders) {
+			f.openOrders = orders
+			f.ibs.SetOpenOrders(orders)
+			f.send(OrdersMsg{})
+		}
+		if orders := reqDoneOrders(f.ib); !slices.Equal(orders, f.doneOrders) {
+			f.doneOrders = orders
+			f.ibs.SetDoneOrders(orders)
+			f.send(OrdersMsg{})
+		}
+		if execs := f.reqExecutions(); !slices.Equal(execs, f.executions) {
+			f.executions = execs
+			f.ibs.SetExecutions(execs)
+			f.send(ExecutionsMsg{})
+		}
+		f.syncPnL()
+
+		select {
+		case <-f.stop:
+			for k := range f.pnlStops {
+				f.cancelPnL(k)
+			}
+			f.mu.Lock()
+			f.cancelBars()
+			f.mu.Unlock()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Copy ticker changes into quotes and send QuoteMsg, until stopped.
+func (f *Feed) watchQuotes() {
+	defer f.wg.Done()
+	ticker := time.NewTicker(quoteInterval)
+	defer ticker.Stop()
+	for {
+		if f.syncQuotes() {
+			f.send(QuoteMsg{})
+		}
+		select {
+		case <-f.stop:
+			f.cancelQuotes()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Keep a P&L subscription open for every account and held position.
+func (f *Feed) syncPnL() {
+	wanted := make(map[pnlKey]bool)
+	for _, account := range f.accounts {
+		wanted[pnlKey{account: account}] = true
+	}
+	for _, p := range f.portfolio {
+		wanted[pnlKey{p.Account, p.ConID}] = true
+	}
+	for k := range f.pnlStops {
+		if !wanted[k] {
+			f.cancelPnL(k)
+		}
+	}
+	for k := range wanted {
+		if _, ok := f.pnlStops[k]; !ok {
+			f.reqPnL(k)
+		}
+	}
+}
+
+// Subscribe to one P&L stream and forward its updates until cancelled.
+func (f *Feed) reqPnL(k pnlKey) {
+	stop := make(chan struct{})
+	f.pnlStops[k] = stop
+	f.wg.Add(1)
+	if k.conID == 0 {
+		f.ib.ReqPnL(k.account, "")
+		go forward(f, stop, f.ib.PnlChan(k.account, ""), PnLMsg{}, func(p ibsync.Pnl) {
+			f.ibs.SetPnL(k.account, 0, PnL{Daily: p.DailyPNL, Unrealized: p.UnrealizedPnl, Realized: p.RealizedPNL})
+		})
+		return
+	}
+	f.ib.ReqPnLSingle(k.account, "", k.conID)
+	go forward(f, stop, f.ib.PnlSingleChan(k.account, "", k.conID), PnLMsg{}, func(p ibsync.PnlSingle) {
+		f.ibs.SetPnL(k.account, k.conID, PnL{Daily: p.DailyPNL, Unrealized: p.UnrealizedPnl, Realized: p.RealizedPNL})
+	})
+}
+
+// Unsubscribe from one P&L stream.
+func (f *Feed) cancelPnL(k pnlKey) {
+	close(f.pnlStops[k])
+	delete(f.pnlStops, k)
+	if k.conID == 0 {
+		f.ib.CancelPnL(k.account, "")
+	} else {
+		f.ib.CancelPnLSingle(k.account, "", k.conID)
+	}
+}
+
+// Apply every value from an ibsync channel to the state and send msg, until stopped.
+func forward[T any](f *Feed, stop <-chan struct{}, ch <-chan T, msg any, apply func(T)) {
+	defer f.wg.Done()
+	for {
+		select {
+		case <-stop:
+			return
+		case v, ok := <-ch:
+			if !ok {
+				return
+			}
+			apply(v)
+			f.send(msg)
+		}
+	}
+}
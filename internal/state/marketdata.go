@@ -0,0 +1,123 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MarketDataType is the kind of quote IB streams, as set by reqMarketDataType.
+type MarketDataType int64
+
+// IB's market data type codes.
+const (
+	UnknownData       MarketDataType = 0 // IB hasn't said yet
+	LiveData          MarketDataType = 1
+	FrozenData        MarketDataType = 2 // Last live quote, kept after the market closes
+	DelayedData       MarketDataType = 3 // Usually 15-20 minutes old
+	DelayedFrozenData MarketDataType = 4
+)
+
+// ErrUnknownMarketDataType occurs when a market data type name isn't recognised.
+var ErrUnknownMarketDataType = errors.New("unknown market data type, use live, frozen, delayed or delayed-frozen")
+
+// MarketDataTypeMsg is sent once a newly requested market data type is in effect.
+type MarketDataTypeMsg struct{}
+
+// ParseMarketDataType reads a market data type by name (e.g. "delayed-frozen") or IB code (e.g. "4").
+func ParseMarketDataType(s string) (MarketDataType, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "live", "realtime", "1":
+		return LiveData, nil
+	case "frozen", "2":
+		return FrozenData, nil
+	case "delayed", "3":
+		return DelayedData, nil
+	case "delayed-frozen", "delayedfrozen", "delayed_frozen", "4":
+		return DelayedFrozenData, nil
+	default:
+		return UnknownData, fmt.Errorf("couldn't parse %q: %w", s, ErrUnknownMarketDataType)
+	}
+}
+
+// String returns the name accepted by ParseMarketDataType.
+func (t MarketDataType) String() string {
+	switch t {
+	case LiveData:
+		return "live"
+	case FrozenData:
+		return "frozen"
+	case DelayedData:
+		return "delayed"
+	case DelayedFrozenData:
+		return "delayed-frozen"
+	case UnknownData:
+	}
+	return "-"
+}
+
+// Tag is a short label that fits in a table column.
+func (t MarketDataType) Tag() string {
+	switch t {
+	case LiveData:
+		return "Live"
+	case FrozenData:
+		return "Frzn"
+	case DelayedData:
+		return "Dlyd"
+	case DelayedFrozenData:
+		return "DlFz"
+	case UnknownData:
+	}
+	return "-"
+}
+
+// Next returns the type after t, wrapping from delayed-frozen back to live.
+func (t MarketDataType) Next() MarketDataType {
+	if t >= DelayedFrozenData || t < LiveData {
+		return LiveData
+	}
+	return t + 1
+}
+
+// SetMarketDataType records which market data type was last requested from IB.
+func (s *IBState) SetMarketDataType(t MarketDataType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.MarketDataType = t
+}
+
+// SetMarketDataType asks IB to stream t from now on and re-requests every
+// open quote, since IB only applies the type to new subscriptions.
+// It blocks on IB, so call it from a tea.Cmd once the TUI is running.
+func (f *Feed) SetMarketDataType(t MarketDataType) error {
+	f.ib.ReqMarketDataType(int64(t))
+	f.ibs.SetMarketDataType(t)
+
+	f.mu.Lock()
+	subs := make(map[int64]*quoteSub, len(f.quotes))
+	for conID, sub := range f.quotes {
+		if sub.ticker != nil {
+			subs[conID] = sub
+		}
+	}
+	f.mu.Unlock()
+
+	var errs []error
+	for conID, sub := range subs {
+		f.ib.CancelMktData(sub.contract)
+		ticker, err := f.ib.ReqMktData(sub.contract, "")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't re-request market data for %v: %w", sub.spec, err))
+			continue
+		}
+		f.mu.Lock()
+		if f.quotes[conID] == sub {
+			sub.ticker = ticker
+		} else {
+			f.ib.CancelMktData(sub.contract) // Cancelled while it was re-requested
+		}
+		f.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
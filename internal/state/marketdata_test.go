@@ -0,0 +1,40 @@
+package state
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMarketDataType(t *testing.T) {
+	cases := map[string]MarketDataType{
+		"live":           LiveData,
+		" Frozen ":       FrozenData,
+		"3":              DelayedData,
+		"delayed-frozen": DelayedFrozenData,
+	}
+	for in, want := range cases {
+		got, err := ParseMarketDataType(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseMarketDataType(%q) = %v, %v, want %v", in, got, err, want)
+		}
+		if again, _ := ParseMarketDataType(got.String()); again != got {
+			t.Fatalf("expected %v to survive a String round trip got %v", got, again)
+		}
+	}
+	if _, err := ParseMarketDataType("stale"); !errors.Is(err, ErrUnknownMarketDataType) {
+		t.Fatalf("expected ErrUnknownMarketDataType got %v", err)
+	}
+}
+
+func TestMarketDataType_Next(t *testing.T) {
+	got := []MarketDataType{UnknownData.Next()}
+	for range 4 {
+		got = append(got, got[len(got)-1].Next())
+	}
+	want := []MarketDataType{LiveData, FrozenData, DelayedData, DelayedFrozenData, LiveData}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Next sequence = %v, want %v", got, want)
+		}
+	}
+}
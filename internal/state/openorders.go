@@ -0,0 +1,246 @@
+package state
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/contract"
+	"github.com/glenntam/ibtui/internal/order"
+	"github.com/scmhub/ibsync"
+)
+
+// How IB writes a time in UTC.
+const ibUTCTimeLayout = "20060102-15:04:05"
+
+// OrdersMsg is sent when any open order is placed, changes status or goes away.
+type OrdersMsg struct{}
+
+// OpenOrder is a working order as IB last reported it.
+type OpenOrder struct {
+	Account      string
+	OrderID      int64
+	PermID       int64
+	ParentID     int64 // The bracket entry this order exits, 0 if none
+	ConID        int64
+	Symbol       string
+	SecType      string
+	Exchange     string
+	Currency     string
+	Side         string
+	Quantity     float64
+	Type         string
+	LimitPrice   float64
+	AuxPrice     float64 // Stop price, trailing amount or relative offset, depending on Type
+	TrailPercent float64
+	LimitOffset  float64
+	TIF          string
+	GoodTill     string
+	OutsideRTH   bool
+	OCAGroup     string
+	Status       string
+	WhyHeld      string // Why IB is holding the order back, e.g. "locate"
+	Filled       float64
+	Remaining    float64
+}
+
+// NestedOrder is an OpenOrder placed in a parent/child tree. Depth is 0 for
+// top level orders and 1 for bracket exits under their entry.
+type NestedOrder struct {
+	OpenOrder
+	Depth int
+}
+
+// SetDoneOrders replaces every order that finished working this session.
+func (s *IBState) SetDoneOrders(orders []OpenOrder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.DoneOrders = orders
+}
+
+// SetOpenOrders replaces every open order.
+func (s *IBState) SetOpenOrders(orders []OpenOrder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.OpenOrders = slices.Clone(orders)
+}
+
+// ActiveOpenOrders returns the open orders of the active account, or of all accounts.
+func (s Snapshot) ActiveOpenOrders() []OpenOrder {
+	orders := make([]OpenOrder, 0, len(s.OpenOrders))
+	for _, o := range s.OpenOrders {
+		if s.InActiveAccount(o.Account) {
+			orders = append(orders, o)
+		}
+	}
+	return orders
+}
+
+// Ticket rebuilds the order.Ticket that would place o again, for modifying it.
+func (o OpenOrder) Ticket() order.Ticket {
+	t := order.Ticket{
+		Spec: contract.Spec{
+			Symbol:   o.Symbol,
+			SecType:  o.SecType,
+			Exchange: o.Exchange,
+			Currency: o.Currency,
+		},
+		Account:     o.Account,
+		Side:        o.Side,
+		Quantity:    o.Quantity,
+		Type:        order.Type(o.Type),
+		LimitPrice:  o.LimitPrice,
+		LimitOffset: o.LimitOffset,
+		TIF:         order.TIF(o.TIF),
+		GoodTill:    localGoodTill(o.GoodTill),
+		OutsideRTH:  o.OutsideRTH,
+		OCAGroup:    o.OCAGroup,
+	}
+	if t.TIF == order.OpeningAuction {
+		t.Type = order.MarketOnOpen
+		if o.Type == string(order.Limit) {
+			t.Type = order.LimitOnOpen
+		}
+	}
+	switch {
+	case t.Type.NeedsStop():
+		t.StopPrice = o.AuxPrice
+	case t.Type.NeedsTrail() && o.TrailPercent > 0:
+		t.TrailPercent = o.TrailPercent
+	case t.Type.NeedsTrail():
+		t.TrailAmount = o.AuxPrice
+	case t.Type.NeedsOffset():
+		t.Offset = o.AuxPrice
+	}
+	return t
+}
+
+// IB sends a GTD expiry back with its timezone. Rewrite it in
+// order.GoodTillLayout and local time, the way it is typed. An expiry that
+// can't be read is left as it is.
+func localGoodTill(s string) string {
+	t, ok := parseIBTime(s)
+	if !ok {
+		return strings.TrimSpace(s)
+	}
+	return t.Local().Format(order.GoodTillLayout)
+}
+
+// Read a time IB writes with its timezone, e.g. "20250306 16:00:00 US/Eastern",
+// or in UTC as "20250306-21:00:00". Reports false if s isn't either.
+func parseIBTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation(ibUTCTimeLayout, s, time.UTC); err == nil {
+		return t, true
+	}
+	parts := strings.Fields(s)
+	if len(parts) != 3 { //nolint:mnd // Date, time and timezone
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(parts[2])
+	if err != nil {
+		slog.Warn("Couldn't read IB's timezone", "time", s, "error", err)
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(order.GoodTillLayout, parts[0]+" "+parts[1], loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Nest lists each order followed by its children. Children whose parent is
+// no longer open (e.g. the entry filled) are shown at the top level.
+func Nest(orders []OpenOrder) []NestedOrder {
+	open := make(map[int64]bool, len(orders))
+	for _, o := range orders {
+		open[o.OrderID] = true
+	}
+	children := make(map[int64][]OpenOrder)
+	nested := make([]NestedOrder, 0, len(orders))
+	for _, o := range orders {
+		if o.ParentID != 0 && open[o.ParentID] {
+			children[o.ParentID] = append(children[o.ParentID], o)
+		}
+	}
+	for _, o := range orders {
+		if o.ParentID != 0 && open[o.ParentID] {
+			continue
+		}
+		nested = append(nested, NestedOrder{OpenOrder: o})
+		for _, c := range children[o.OrderID] {
+			nested = append(nested, NestedOrder{OpenOrder: c, Depth: 1})
+		}
+	}
+	return nested
+}
+
+// Read every working order from ibsync's trade cache, oldest first.
+func reqOpenOrders(ib *ibsync.IB) []OpenOrder {
+	return toOpenOrders(ib.OpenTrades())
+}
+
+// Read every order that finished working this session from ibsync's trade cache, oldest first.
+func reqDoneOrders(ib *ibsync.IB) []OpenOrder {
+	return toOpenOrders(doneTrades(ib.Trades()))
+}
+
+// Keep only the trades that finished working.
+func doneTrades(trades []*ibsync.Trade) []*ibsync.Trade {
+	done := make([]*ibsync.Trade, 0, len(trades))
+	for _, t := range trades {
+		if t != nil && t.IsDone() {
+			done = append(done, t)
+		}
+	}
+	return done
+}
+
+// Convert ibsync trades to orders, oldest first.
+func toOpenOrders(trades []*ibsync.Trade) []OpenOrder {
+	orders := make([]OpenOrder, 0, len(trades))
+	for _, t := range trades {
+		if o, ok := toOpenOrder(t); ok {
+			orders = append(orders, o)
+		}
+	}
+	slices.SortFunc(orders, func(a, b OpenOrder) int {
+		return cmp.Compare(a.OrderID, b.OrderID)
+	})
+	return orders
+}
+
+// Convert one ibsync trade to an order. Reports false if IB left out its order or contract.
+func toOpenOrder(t *ibsync.Trade) (OpenOrder, bool) {
+	if t == nil || t.Order == nil || t.Contract == nil {
+		return OpenOrder{}, false
+	}
+	return OpenOrder{
+		Account:      t.Order.Account,
+		OrderID:      t.Order.OrderID,
+		PermID:       t.Order.PermID,
+		ParentID:     t.Order.ParentID,
+		ConID:        t.Contract.ConID,
+		Symbol:       t.Contract.Symbol,
+		SecType:      t.Contract.SecType,
+		Exchange:     t.Contract.Exchange,
+		Currency:     t.Contract.Currency,
+		Side:         t.Order.Action,
+		Quantity:     t.Order.TotalQuantity.Float(),
+		Type:         t.Order.OrderType,
+		LimitPrice:   orZero(t.Order.LmtPrice),
+		AuxPrice:     orZero(t.Order.AuxPrice),
+		TrailPercent: orZero(t.Order.TrailingPercent),
+		LimitOffset:  orZero(t.Order.LmtPriceOffset),
+		TIF:          t.Order.Tif,
+		GoodTill:     t.Order.GoodTillDate,
+		OutsideRTH:   t.Order.OutsideRTH,
+		OCAGroup:     t.Order.OcaGroup,
+		Status:       string(t.OrderStatus.Status),
+		WhyHeld:      t.OrderStatus.WhyHeld,
+		Filled:       t.OrderStatus.Filled.Float(),
+		Remaining:    t.OrderStatus.Remaining.Float(),
+	}, true
+}
@@ -0,0 +1,39 @@
+package state
+
+import "testing"
+
+func TestNest(t *testing.T) {
+	orders := []OpenOrder{
+		{OrderID: 10},
+		{OrderID: 11, ParentID: 10},
+		{OrderID: 12, ParentID: 10},
+		{OrderID: 13},
+		{OrderID: 21, ParentID: 20}, // Entry already filled
+	}
+	got := Nest([]OpenOrder{orders[4], orders[2], orders[0], orders[3], orders[1]})
+	want := []struct {
+		id    int64
+		depth int
+	}{{21, 0}, {10, 0}, {12, 1}, {11, 1}, {13, 0}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d orders got %+v", len(want), got)
+	}
+	for i, w := range want {
+		if got[i].OrderID != w.id || got[i].Depth != w.depth {
+			t.Fatalf("order %d = #%d depth %d, want #%d depth %d", i, got[i].OrderID, got[i].Depth, w.id, w.depth)
+		}
+	}
+}
+
+func TestSnapshot_ActiveOpenOrders(t *testing.T) {
+	ibs := NewIBState()
+	ibs.SetAccounts([]string{"U1", "U2"})
+	ibs.SetOpenOrders([]OpenOrder{{Account: "U1", OrderID: 1}, {Account: "U2", OrderID: 2}})
+	if got := ibs.Snapshot().ActiveOpenOrders(); len(got) != 2 {
+		t.Fatalf("expected both accounts' orders in the All view got %+v", got)
+	}
+	ibs.CycleAccount() // U1
+	if got := ibs.Snapshot().ActiveOpenOrders(); len(got) != 1 || got[0].OrderID != 1 {
+		t.Fatalf("expected only U1's order got %+v", got)
+	}
+}
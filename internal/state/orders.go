@@ -0,0 +1,278 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/order"
+	"github.com/glenntam/ibtui/internal/risk"
+	"github.com/scmhub/ibsync"
+)
+
+// ErrOrderNotOpen occurs when acting on an order that is no longer working.
+var ErrOrderNotOpen = errors.New("order isn't open")
+
+// SetRiskLimits sets the checks every order must pass. Call it before Start.
+func (f *Feed) SetRiskLimits(l risk.Limits) {
+	f.limits = l
+}
+
+// CheckRisk reports every risk limit t breaks against the latest quote of
+// conID, the contract t resolved to, and open orders, or nil if it passes.
+// When modifying an order, pass its ID as replacing so it isn't counted as
+// another open order.
+func (f *Feed) CheckRisk(t order.Ticket, conID, replacing int64) error {
+	if err := f.limits.Check(t, f.market(t, conID, replacing)); err != nil {
+		return fmt.Errorf("couldn't pass risk checks: %w", err)
+	}
+	return nil
+}
+
+// CheckBracketRisk reports every risk limit b breaks, as CheckRisk does.
+// See risk.Limits.CheckBracket.
+func (f *Feed) CheckBracketRisk(b order.Bracket, conID int64) error {
+	if err := f.limits.CheckBracket(b, f.market(b.Entry, conID, 0)); err != nil {
+		return fmt.Errorf("couldn't pass risk checks: %w", err)
+	}
+	return nil
+}
+
+// What is known about conID's market when t is checked, besides the order replacing.
+func (f *Feed) market(t order.Ticket, conID, replacing int64) risk.Market {
+	snap := f.ibs.Snapshot()
+	var mkt risk.Market
+	if q, ok := snap.Quotes[conID]; ok && conID != 0 {
+		mkt.Bid, mkt.Ask, mkt.Last = orZero(q.Bid), orZero(q.Ask), orZero(q.Last)
+		mkt.Multiplier = q.Multiplier
+	}
+	for _, o := range snap.OpenOrders {
+		if o.Symbol == t.Spec.Symbol && o.SecType == t.Spec.SecType && o.OrderID != replacing {
+			mkt.OpenOrders++
+		}
+	}
+	return mkt
+}
+
+// PlaceOrder validates and risk checks t, sends it to IB and returns the new
+// order's ID. Only an explicit override places an order over risk limits.
+// It blocks on IB, so call it from a tea.Cmd.
+func (f *Feed) PlaceOrder(t order.Ticket, override bool) (int64, error) {
+	if err := t.Validate(); err != nil {
+		return 0, fmt.Errorf("couldn't place order: %w", err)
+	}
+	c, err := f.qualify(t.Spec)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't place order: %w", err)
+	}
+	if err = f.enforceRisk(t.String(), f.CheckRisk(t, c.ConID, 0), override); err != nil {
+		return 0, fmt.Errorf("couldn't place order: %w", err)
+	}
+	trade := f.ib.PlaceOrder(c, newOrder(t))
+	return trade.Order.OrderID, nil
+}
+
+// ModifyOrder replaces a working order's terms with t, keeping its ID and
+// any bracket or OCA links. It passes the same checks as PlaceOrder.
+// It blocks on IB, so call it from a tea.Cmd.
+func (f *Feed) ModifyOrder(orderID int64, t order.Ticket, override bool) error {
+	if err := t.Validate(); err != nil {
+		return fmt.Errorf("couldn't modify order #%d: %w", orderID, err)
+	}
+	trade, err := f.openTrade(orderID)
+	if err != nil {
+		return fmt.Errorf("couldn't modify order: %w", err)
+	}
+	err = f.enforceRisk(t.String(), f.CheckRisk(t, trade.Contract.ConID, orderID), override)
+	if err != nil {
+		return fmt.Errorf("couldn't modify order #%d: %w", orderID, err)
+	}
+	o := newOrder(t)
+	o.OrderID = trade.Order.OrderID
+	o.ParentID = trade.Order.ParentID
+	o.OcaGroup, o.OcaType = trade.Order.OcaGroup, trade.Order.OcaType
+	f.ib.PlaceOrder(trade.Contract, o)
+	return nil
+}
+
+// CancelOrder asks IB to cancel a working order.
+func (f *Feed) CancelOrder(orderID int64) error {
+	trade, err := f.openTrade(orderID)
+	if err != nil {
+		return fmt.Errorf("couldn't cancel order: %w", err)
+	}
+	f.ib.CancelOrder(trade.Order, ibsync.NewOrderCancel())
+	return nil
+}
+
+// CancelAllOrders asks IB to cancel every open order in every account,
+// including those placed from TWS or other API clients.
+func (f *Feed) CancelAllOrders() {
+	f.ib.ReqGlobalCancel()
+}
+
+// Preview is IB's what-if estimate of an order's margin and commission.
+// Values IB didn't estimate are Unset.
+type Preview struct {
+	InitMarginChange     float64
+	InitMarginAfter      float64
+	MaintMarginChange    float64
+	MaintMarginAfter     float64
+	EquityWithLoanChange float64
+	EquityWithLoanAfter  float64
+	Commission           float64
+	MinCommission        float64
+	MaxCommission        float64
+	CommissionCurrency   string
+	Warning              string
+}
+
+// WhatIf asks IB what placing t would do to margin and commission, without placing it.
+// It blocks on IB, so call it from a tea.Cmd.
+func (f *Feed) WhatIf(t order.Ticket) (Preview, error) {
+	if err := t.Validate(); err != nil {
+		return Preview{}, fmt.Errorf("couldn't preview order: %w", err)
+	}
+	c, err := f.qualify(t.Spec)
+	if err != nil {
+		return Preview{}, fmt.Errorf("couldn't preview order: %w", err)
+	}
+	o := newOrder(t)
+	o.WhatIf = true
+	estimate, err := f.ib.WhatIfOrder(c, o)
+	if err != nil {
+		return Preview{}, fmt.Errorf("couldn't preview order: %w", err)
+	}
+	return Preview{
+		InitMarginChange:     parseIBFloat(estimate.InitMarginChange),
+		InitMarginAfter:      parseIBFloat(estimate.InitMarginAfter),
+		MaintMarginChange:    parseIBFloat(estimate.MaintMarginChange),
+		MaintMarginAfter:     parseIBFloat(estimate.MaintMarginAfter),
+		EquityWithLoanChange: parseIBFloat(estimate.EquityWithLoanChange),
+		EquityWithLoanAfter:  parseIBFloat(estimate.EquityWithLoanAfter),
+		Commission:           estimate.Commission,
+		MinCommission:        estimate.MinCommission,
+		MaxCommission:        estimate.MaxCommission,
+		CommissionCurrency:   estimate.CommissionCurrency,
+		Warning:              estimate.WarningText,
+	}, nil
+}
+
+// PlaceBracket validates and risk checks b's orders, then places its entry and exits as one bracket,
+// returning their order IDs. Only the last order transmits, so IB doesn't act
+// on a half-built bracket. It blocks on IB, so call it from a tea.Cmd.
+func (f *Feed) PlaceBracket(b order.Bracket, override bool) ([]int64, error) {
+	if err := b.Validate(); err != nil {
+		return nil, fmt.Errorf("couldn't place bracket: %w", err)
+	}
+	c, err := f.qualify(b.Entry.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't place bracket: %w", err)
+	}
+	if err = f.enforceRisk(b.String(), f.CheckBracketRisk(b, c.ConID), override); err != nil {
+		return nil, fmt.Errorf("couldn't place bracket: %w", err)
+	}
+	tickets := b.Tickets()
+	ids := make([]int64, 0, len(tickets))
+	var parentID int64
+	for i, t := range tickets {
+		o := newOrder(t)
+		o.OrderID = f.ib.NextID()
+		o.ParentID = parentID
+		o.Transmit = i == len(tickets)-1
+		f.ib.PlaceOrder(c, o)
+		if i == 0 {
+			parentID = o.OrderID
+		}
+		ids = append(ids, o.OrderID)
+	}
+	return ids, nil
+}
+
+// Find a working order in ibsync's trade cache.
+func (f *Feed) openTrade(orderID int64) (*ibsync.Trade, error) {
+	for _, t := range f.ib.OpenTrades() {
+		if t != nil && t.Order != nil && t.Order.OrderID == orderID {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("couldn't find order #%d: %w", orderID, ErrOrderNotOpen)
+}
+
+// Log and reject an order over risk limits, unless the user overrode them.
+func (f *Feed) enforceRisk(summary string, err error, override bool) error {
+	if err == nil {
+		return nil
+	}
+	if override {
+		slog.Warn("Placing order over risk limits", "order", summary, "error", err)
+		return nil
+	}
+	slog.Warn("Risk check rejected order", "order", summary, "error", err)
+	return err
+}
+
+// Write a GTD expiry typed in local time in UTC, so IB doesn't read it in the
+// TWS/Gateway timezone instead.
+func ibGoodTill(s string) string {
+	t, err := time.ParseInLocation(order.GoodTillLayout, strings.TrimSpace(s), time.Local)
+	if err != nil {
+		return s // Validated before sending, so IB will say what's wrong
+	}
+	return t.UTC().Format(ibUTCTimeLayout)
+}
+
+// Translate a ticket into ibsync's order.
+func newOrder(t order.Ticket) *ibsync.Order {
+	o := ibsync.NewOrder()
+	o.Action = t.Side
+	o.TotalQuantity = ibsync.StringToDecimal(strconv.FormatFloat(t.Quantity, 'f', -1, 64))
+	o.OrderType, o.Tif = t.IB()
+	o.Account = t.Account
+	o.OutsideRTH = t.OutsideRTH
+	if t.TIF == order.GTD {
+		o.GoodTillDate = ibGoodTill(t.GoodTill)
+	}
+	if t.Type.UsesLimit() && t.LimitPrice > 0 {
+		o.LmtPrice = t.LimitPrice
+	}
+	switch {
+	case t.Type.NeedsStop():
+		o.AuxPrice = t.StopPrice
+	case t.Type.NeedsTrail() && t.TrailPercent > 0:
+		o.TrailingPercent = t.TrailPercent
+	case t.Type.NeedsTrail():
+		o.AuxPrice = t.TrailAmount
+	case t.Type.NeedsOffset():
+		o.AuxPrice = t.Offset
+	}
+	if t.Type.NeedsLimitOffset() {
+		o.LmtPriceOffset = t.LimitOffset
+	}
+	if t.OCAGroup != "" {
+		o.OcaGroup = t.OCAGroup
+		o.OcaType = order.OCACancel
+	}
+	return o
+}
+
+// Risk checks treat a price IB hasn't sent as 0.
+func orZero(v float64) float64 {
+	if v == Unset {
+		return 0
+	}
+	return v
+}
+
+// IB sends what-if margins as text, blank or a huge number when not estimated.
+func parseIBFloat(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.Abs(v) >= Unset {
+		return Unset
+	}
+	return v
+}
@@ -0,0 +1,161 @@
+package state
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glenntam/ibtui/internal/contract"
+	"github.com/glenntam/ibtui/internal/order"
+	"github.com/glenntam/ibtui/internal/risk"
+)
+
+func TestNewOrder(t *testing.T) {
+	moo := newOrder(order.Ticket{Side: order.Buy, Quantity: 10, Type: order.MarketOnOpen, TIF: order.OpeningAuction})
+	if moo.OrderType != "MKT" || moo.Tif != "OPG" || moo.TotalQuantity.Float() != 10 {
+		t.Fatalf("expected MOO as MKT OPG got %+v", moo)
+	}
+	trail := newOrder(order.Ticket{Type: order.TrailLimit, TrailPercent: 2, LimitOffset: 0.1, TIF: order.GTC})
+	if trail.TrailingPercent != 2 || trail.LmtPriceOffset != 0.1 || trail.AuxPrice != 0 {
+		t.Fatalf("expected a percent trail with a limit offset got %+v", trail)
+	}
+	oca := newOrder(order.Ticket{Type: order.Limit, LimitPrice: 5, TIF: order.Day, OCAGroup: "exit"})
+	if oca.OcaGroup != "exit" || oca.OcaType != order.OCACancel || oca.LmtPrice != 5 {
+		t.Fatalf("expected an OCA limit order got %+v", oca)
+	}
+}
+
+func TestParseIBFloat(t *testing.T) {
+	cases := map[string]float64{
+		"1234.5":                 1234.5,
+		"-20":                    -20,
+		"":                       Unset,
+		"1.7976931348623157E308": Unset,
+	}
+	for in, want := range cases {
+		if got := parseIBFloat(in); got != want {
+			t.Fatalf("parseIBFloat(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestFeed_CheckRisk(t *testing.T) {
+	ibs := NewIBState()
+	f := NewFeed(nil, ibs, func(any) {})
+	f.SetRiskLimits(risk.Limits{PriceBandPct: 5, MaxOpenPerSymbol: 2})
+	aapl := contract.Spec{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"}
+	q := newQuote(aapl, 265598)
+	q.Last = 100
+	ibs.SetQuote(q)
+	ibs.SetOpenOrders([]OpenOrder{{OrderID: 1, Symbol: "AAPL", SecType: "STK"}})
+
+	ticket := order.Ticket{Spec: aapl, Side: order.Buy, Quantity: 1, Type: order.Limit, LimitPrice: 104, TIF: order.Day}
+	if err := f.CheckRisk(ticket, 265598, 0); err != nil {
+		t.Fatalf("expected order 4%% from the last price to pass got %v", err)
+	}
+	ticket.LimitPrice = 140
+	if err := f.CheckRisk(ticket, 265598, 0); !errors.Is(err, risk.ErrPriceBand) {
+		t.Fatalf("expected ErrPriceBand against the streamed last price got %v", err)
+	}
+	ticket.LimitPrice = 101
+	ibs.SetOpenOrders([]OpenOrder{
+		{OrderID: 1, Symbol: "AAPL", SecType: "STK"},
+		{OrderID: 2, Symbol: "AAPL", SecType: "STK"},
+	})
+	if err := f.CheckRisk(ticket, 265598, 0); !errors.Is(err, risk.ErrMaxOpenOrders) {
+		t.Fatalf("expected ErrMaxOpenOrders with 2 working orders got %v", err)
+	}
+	if err := f.CheckRisk(ticket, 265598, 2); err != nil {
+		t.Fatalf("expected modifying order #2 not to count it twice got %v", err)
+	}
+}
+
+func TestFeed_CheckRisk_by_conID(t *testing.T) {
+	ibs := NewIBState()
+	f := NewFeed(nil, ibs, func(any) {})
+	f.SetRiskLimits(risk.Limits{PriceBandPct: 5})
+	// Two futures months typed the same way, told apart only by conID.
+	es := contract.Spec{Symbol: "ES", SecType: "FUT", Exchange: "CME", Currency: "USD"}
+	front, back := newQuote(es, 1), newQuote(es, 2)
+	front.Last, back.Last = 5000, 5100
+	ibs.SetQuote(front)
+	ibs.SetQuote(back)
+
+	ticket := order.Ticket{Spec: es, Side: order.Buy, Quantity: 1, Type: order.Limit, LimitPrice: 5090, TIF: order.Day}
+	if err := f.CheckRisk(ticket, 2, 0); err != nil {
+		t.Fatalf("expected the back month's quote to price the order got %v", err)
+	}
+	if err := f.CheckRisk(ticket, 3, 0); !errors.Is(err, risk.ErrUnpriced) {
+		t.Fatalf("expected ErrUnpriced without a quote for the contract got %v", err)
+	}
+	back.Multiplier = 50
+	ibs.SetQuote(back)
+	f.SetRiskLimits(risk.Limits{MaxNotional: 100_000})
+	if err := f.CheckRisk(ticket, 2, 0); !errors.Is(err, risk.ErrMaxNotional) {
+		t.Fatalf("expected the quote's multiplier to value the order at 254,500 got %v", err)
+	}
+}
+
+func TestFeed_CheckBracketRisk(t *testing.T) {
+	ibs := NewIBState()
+	f := NewFeed(nil, ibs, func(any) {})
+	f.SetRiskLimits(risk.Limits{PriceBandPct: 5, MaxOpenPerSymbol: 3})
+	aapl := contract.Spec{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"}
+	q := newQuote(aapl, 265598)
+	q.Last = 100
+	ibs.SetQuote(q)
+
+	entry := order.Ticket{Spec: aapl, Side: order.Buy, Quantity: 1, Type: order.Limit, LimitPrice: 100, TIF: order.Day}
+	b := order.Bracket{Entry: entry, TakeProfit: 104, StopLoss: 97}
+	if err := f.CheckBracketRisk(b, 265598); err != nil {
+		t.Fatalf("expected a bracket within the band to pass got %v", err)
+	}
+	b.TakeProfit, b.StopLoss = 150, 92
+	if err := f.CheckBracketRisk(b, 265598); err != nil {
+		t.Fatalf("expected exits to be left out of the price band got %v", err)
+	}
+	b.Entry.LimitPrice = 110
+	if err := f.CheckBracketRisk(b, 265598); !errors.Is(err, risk.ErrPriceBand) {
+		t.Fatalf("expected ErrPriceBand for an entry far from the market got %v", err)
+	}
+	b.Entry.LimitPrice = 100
+	ibs.SetOpenOrders([]OpenOrder{{OrderID: 1, Symbol: "AAPL", SecType: "STK"}})
+	if err := f.CheckBracketRisk(b, 265598); !errors.Is(err, risk.ErrMaxOpenOrders) {
+		t.Fatalf("expected the bracket's own orders to count towards the open order limit got %v", err)
+	}
+}
+
+func TestOpenOrder_Ticket(t *testing.T) {
+	loo := OpenOrder{Symbol: "AAPL", SecType: "STK", Side: "BUY", Quantity: 10, Type: "LMT", LimitPrice: 190, TIF: "OPG"}
+	if got := loo.Ticket(); got.Type != order.LimitOnOpen || got.LimitPrice != 190 || got.Validate() != nil {
+		t.Fatalf("expected a valid LOO ticket got %+v", got)
+	}
+	trail := OpenOrder{
+		Symbol: "ES", SecType: "FUT", Side: "SELL", Quantity: 1, Type: "TRAIL", TrailPercent: 1.5, TIF: "GTC",
+	}
+	if got := trail.Ticket(); got.TrailPercent != 1.5 || got.TrailAmount != 0 || got.Validate() != nil {
+		t.Fatalf("expected a valid percent trailing ticket got %+v", got)
+	}
+	stp := OpenOrder{Symbol: "AAPL", SecType: "STK", Side: "SELL", Quantity: 10, Type: "STP", AuxPrice: 180, TIF: "DAY"}
+	if got := stp.Ticket(); got.StopPrice != 180 || got.Validate() != nil {
+		t.Fatalf("expected a valid stop ticket got %+v", got)
+	}
+}
+
+func TestOpenOrder_Ticket_good_till(t *testing.T) {
+	eastern, err := time.LoadLocation("US/Eastern")
+	if err != nil {
+		t.Skipf("no timezone database: %v", err)
+	}
+	want := time.Date(2099, 3, 6, 16, 0, 0, 0, eastern).Local().Format(order.GoodTillLayout)
+	gtd := OpenOrder{Symbol: "AAPL", SecType: "STK", Side: "BUY", Quantity: 10, Type: "LMT", LimitPrice: 190, TIF: "GTD"}
+	for _, ib := range []string{"20990306 16:00:00 US/Eastern", "20990306-21:00:00"} {
+		gtd.GoodTill = ib
+		if got := gtd.Ticket(); got.GoodTill != want || got.Validate() != nil {
+			t.Fatalf("expected IB's expiry %q as a valid %q got %+v", ib, want, got)
+		}
+		if sent := newOrder(gtd.Ticket()).GoodTillDate; sent != "20990306-21:00:00" {
+			t.Fatalf("expected the modified order to keep its expiry, in UTC, got %q", sent)
+		}
+	}
+}
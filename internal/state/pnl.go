@@ -0,0 +1,47 @@
+package state
+
+// PnL is IB's real-time profit and loss for an account or a single position.
+type PnL struct {
+	Daily      float64
+	Unrealized float64
+	Realized   float64
+}
+
+// Identifies a P&L stream. A zero conID is the account-level stream.
+type pnlKey struct {
+	account string
+	conID   int64
+}
+
+// SetPnL records a streamed P&L update. A zero conID is account-level,
+// anything else belongs to that position in the account.
+func (s *IBState) SetPnL(account string, conID int64, pnl PnL) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conID == 0 {
+		s.snap.PnLs[account] = pnl
+		return
+	}
+	s.positionPnL[pnlKey{account, conID}] = pnl
+	for i := range s.snap.Portfolio {
+		if s.snap.Portfolio[i].Account == account && s.snap.Portfolio[i].ConID == conID {
+			s.snap.Portfolio[i].applyPnL(pnl)
+		}
+	}
+}
+
+// ActivePnL returns the selected account's P&L, or the sum across
+// every account when AllAccounts is selected.
+func (s Snapshot) ActivePnL() PnL {
+	if s.ActiveAccount != AllAccounts {
+		return s.PnLs[s.ActiveAccount]
+	}
+	var total PnL
+	for _, account := range s.Accounts {
+		p := s.PnLs[account]
+		total.Daily += p.Daily
+		total.Unrealized += p.Unrealized
+		total.Realized += p.Realized
+	}
+	return total
+}
@@ -0,0 +1,27 @@
+package state
+
+import "testing"
+
+func TestSetPnL_survives_portfolio_refresh(t *testing.T) {
+	s := NewIBState()
+	s.SetPortfolio([]PortfolioItem{{Account: "U1", ConID: 265598, Symbol: "AAPL", UnrealizedPNL: 1}})
+	s.SetPnL("U1", 265598, PnL{Daily: 12.5, Unrealized: 40, Realized: 2})
+	if got := s.Snapshot().Portfolio[0].DailyPNL; got != 12.5 {
+		t.Fatalf("expected streamed daily P&L 12.5 got %v", got)
+	}
+
+	// A later portfolio update must not roll back to its own stale P&L.
+	s.SetPortfolio([]PortfolioItem{{Account: "U1", ConID: 265598, Symbol: "AAPL", UnrealizedPNL: 1}})
+	if got := s.Snapshot().Portfolio[0].UnrealizedPNL; got != 40 {
+		t.Fatalf("expected streamed unrealized P&L 40 got %v", got)
+	}
+
+	s.SetPnL("U1", 0, PnL{Daily: 100})
+	if got := s.Snapshot().ActivePnL().Daily; got != 0 {
+		t.Fatalf("expected no P&L before accounts are known got %v", got)
+	}
+	s.SetAccounts([]string{"U1"})
+	if got := s.Snapshot().ActivePnL().Daily; got != 100 {
+		t.Fatalf("expected account daily P&L 100 got %v", got)
+	}
+}
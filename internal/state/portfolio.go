@@ -0,0 +1,129 @@
+package state
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/scmhub/ibsync"
+)
+
+// PortfolioItem is a single position held in an IB account, marked to market.
+type PortfolioItem struct {
+	Account       string  `json:"account"`
+	ConID         int64   `json:"conId"`
+	Symbol        string  `json:"symbol"`
+	SecType       string  `json:"secType"`
+	Currency      string  `json:"currency"`
+	Position      float64 `json:"position"`
+	AvgCost       float64 `json:"avgCost"`
+	MarketPrice   float64 `json:"marketPrice"`
+	MarketValue   float64 `json:"marketValue"`
+	DailyPNL      float64 `json:"dailyPnl"`
+	UnrealizedPNL float64 `json:"unrealizedPnl"`
+	RealizedPNL   float64 `json:"realizedPnl"`
+}
+
+// ReqPortfolio asks IB for the market values of every position in each
+// account, waits until IB says it has sent them all, and returns every
+// account's portfolio. If IB fails, what ibsync has so far is returned
+// with the error. It blocks on IB, so call it from a tea.Cmd.
+func (f *Feed) ReqPortfolio(accounts []string) ([]PortfolioItem, error) {
+	for _, account := range accounts {
+		if err := f.ib.ReqAccountUpdates(true, account); err != nil {
+			return reqPortfolio(f.ib), fmt.Errorf("couldn't request the portfolio of %v: %w", account, err)
+		}
+	}
+	return reqPortfolio(f.ib), nil
+}
+
+// Merge ibsync's positions and portfolio updates into one list.
+// Portfolio updates carry market prices; positions fill in any holdings
+// that ibsync hasn't received an account update for yet.
+func reqPortfolio(ib *ibsync.IB) []PortfolioItem {
+	type key struct {
+		account string
+		conID   int64
+	}
+	merged := make(map[key]PortfolioItem)
+	for _, p := range ib.Positions() {
+		if p.Contract == nil {
+			continue
+		}
+		merged[key{p.Account, p.Contract.ConID}] = PortfolioItem{
+			Account:  p.Account,
+			ConID:    p.Contract.ConID,
+			Symbol:   p.Contract.Symbol,
+			SecType:  p.Contract.SecType,
+			Currency: p.Contract.Currency,
+			Position: p.Position.Float(),
+			AvgCost:  p.AvgCost,
+		}
+	}
+	for _, p := range ib.Portfolio() {
+		if p.Contract == nil {
+			continue
+		}
+		merged[key{p.Account, p.Contract.ConID}] = PortfolioItem{
+			Account:       p.Account,
+			ConID:         p.Contract.ConID,
+			Symbol:        p.Contract.Symbol,
+			SecType:       p.Contract.SecType,
+			Currency:      p.Contract.Currency,
+			Position:      p.Position.Float(),
+			AvgCost:       p.AverageCost,
+			MarketPrice:   p.MarketPrice,
+			MarketValue:   p.MarketValue,
+			UnrealizedPNL: p.UnrealizedPNL,
+			RealizedPNL:   p.RealizedPNL,
+		}
+	}
+
+	items := make([]PortfolioItem, 0, len(merged))
+	for _, item := range merged {
+		if item.Position == 0 && item.RealizedPNL == 0 {
+			continue // Closed out and nothing left to report
+		}
+		items = append(items, item)
+	}
+	slices.SortFunc(items, func(a, b PortfolioItem) int {
+		return cmp.Or(
+			cmp.Compare(a.Account, b.Account),
+			cmp.Compare(a.Symbol, b.Symbol),
+			cmp.Compare(a.ConID, b.ConID),
+		)
+	})
+	return items
+}
+
+// SetPortfolio replaces the portfolio, keeping any P&L already streamed
+// for positions that are still held.
+func (s *IBState) SetPortfolio(items []PortfolioItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items = slices.Clone(items)
+	for i := range items {
+		if pnl, ok := s.positionPnL[pnlKey{items[i].Account, items[i].ConID}]; ok {
+			items[i].applyPnL(pnl)
+		}
+	}
+	s.snap.Portfolio = items
+}
+
+// Overwrite the portfolio update's P&L with the live P&L stream.
+func (p *PortfolioItem) applyPnL(pnl PnL) {
+	p.DailyPNL = pnl.Daily
+	p.UnrealizedPNL = pnl.Unrealized
+	p.RealizedPNL = pnl.Realized
+}
+
+// ActivePortfolio returns the portfolio items belonging to the selected account.
+func (s Snapshot) ActivePortfolio() []PortfolioItem {
+	items := make([]PortfolioItem, 0, len(s.Portfolio))
+	for _, p := range s.Portfolio {
+		if s.InActiveAccount(p.Account) {
+			items = append(items, p)
+		}
+	}
+	return items
+}
@@ -0,0 +1,203 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/glenntam/ibtui/internal/contract"
+	"github.com/scmhub/ibsync"
+)
+
+// Unset is IB's marker for a price or size it hasn't sent yet.
+const Unset = math.MaxFloat64
+
+// ErrUnresolvedContract occurs when IB can't match a contract.Spec to a unique contract.
+var ErrUnresolvedContract = errors.New("IB couldn't resolve contract")
+
+// Quote is the latest streamed market data for one contract.
+type Quote struct {
+	Spec       contract.Spec
+	ConID      int64
+	Bid        float64
+	Ask        float64
+	Last       float64
+	Close      float64 // Previous session's close
+	Change     float64
+	ChangePct  float64
+	Volume     float64
+	High       float64
+	Low        float64
+	DataType   MarketDataType // What IB actually sent, which can differ from what was asked
+	Multiplier float64        // Contract multiplier, 0 for stocks and others without one
+}
+
+// QuoteMsg is sent when any streamed quote changes.
+type QuoteMsg struct{}
+
+// A market data subscription shared by everything that shows a contract's quote.
+type quoteSub struct {
+	spec     contract.Spec
+	contract *ibsync.Contract
+	ticker   *ibsync.Ticker // Nil until IB answers the request
+	refs     int
+	last     Quote
+}
+
+// A quote that IB hasn't sent any data for yet.
+func newQuote(spec contract.Spec, conID int64) Quote {
+	return Quote{
+		Spec:      spec,
+		ConID:     conID,
+		Bid:       Unset,
+		Ask:       Unset,
+		Last:      Unset,
+		Close:     Unset,
+		Change:    Unset,
+		ChangePct: Unset,
+		Volume:    Unset,
+		High:      Unset,
+		Low:       Unset,
+	}
+}
+
+// SetQuote records the latest quote for a contract.
+func (s *IBState) SetQuote(q Quote) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap.Quotes[q.ConID] = q
+}
+
+// RemoveQuote forgets a contract's quote once nothing streams it anymore.
+func (s *IBState) RemoveQuote(conID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.snap.Quotes, conID)
+}
+
+// ReqQuote resolves spec to a contract and streams its quote into IBState.
+// It blocks on IB, so call it from a tea.Cmd. Every successful ReqQuote
+// must be paired with a CancelQuote of the returned conID.
+func (f *Feed) ReqQuote(spec contract.Spec) (int64, error) {
+	c, err := f.qualify(spec)
+	if err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	if sub, ok := f.quotes[c.ConID]; ok {
+		sub.refs++
+		f.mu.Unlock()
+		return c.ConID, nil
+	}
+	sub := &quoteSub{spec: spec, contract: c, refs: 1} // Its ticker is set once IB answers
+	f.quotes[c.ConID] = sub
+	q := newQuote(spec, c.ConID)
+	q.Multiplier = parseMultiplier(c.Multiplier)
+	f.ibs.SetQuote(q)
+	f.mu.Unlock()
+
+	ticker, err := f.ib.ReqMktData(c, "")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err != nil {
+		if f.quotes[c.ConID] == sub {
+			delete(f.quotes, c.ConID)
+			f.ibs.RemoveQuote(c.ConID)
+		}
+		return 0, fmt.Errorf("couldn't request market data for %v: %w", spec, err)
+	}
+	sub.ticker = ticker
+	return c.ConID, nil
+}
+
+// CancelQuote releases a ReqQuote. The market data subscription is
+// cancelled once nothing else uses it.
+func (f *Feed) CancelQuote(conID int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sub, ok := f.quotes[conID]
+	if !ok {
+		return
+	}
+	sub.refs--
+	if sub.refs > 0 {
+		return
+	}
+	f.ib.CancelMktData(sub.contract)
+	delete(f.quotes, conID)
+	f.ibs.RemoveQuote(conID)
+}
+
+// Ask IB for the unique contract that spec describes.
+func (f *Feed) qualify(spec contract.Spec) (*ibsync.Contract, error) {
+	c := ibsync.NewContract()
+	c.Symbol = spec.Symbol
+	c.SecType = spec.SecType
+	c.Exchange = spec.Exchange
+	c.Currency = spec.Currency
+	if err := f.ib.QualifyContract(c); err != nil {
+		return nil, fmt.Errorf("couldn't qualify %v: %w", spec, err)
+	}
+	if c.ConID == 0 {
+		return nil, fmt.Errorf("couldn't qualify %v: %w", spec, ErrUnresolvedContract)
+	}
+	return c, nil
+}
+
+// Copy every ticker that moved since the last look into IBState.
+// Reports whether anything changed.
+func (f *Feed) syncQuotes() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changed := false
+	for conID, sub := range f.quotes {
+		if sub.ticker == nil {
+			continue // Still being requested
+		}
+		q := newQuote(sub.spec, conID)
+		q.Multiplier = parseMultiplier(sub.contract.Multiplier)
+		q.Bid = orUnset(sub.ticker.Bid())
+		q.Ask = orUnset(sub.ticker.Ask())
+		q.Last = orUnset(sub.ticker.Last())
+		q.Close = orUnset(sub.ticker.Close())
+		q.Volume = orUnset(sub.ticker.Volume().Float())
+		q.High = orUnset(sub.ticker.High())
+		q.Low = orUnset(sub.ticker.Low())
+		q.DataType = MarketDataType(sub.ticker.MarketDataType())
+		if isSet(q.Last) && isSet(q.Close) {
+			q.Change = q.Last - q.Close
+			q.ChangePct = q.Change / q.Close * percent
+		}
+		if q == sub.last {
+			continue
+		}
+		sub.last = q
+		f.ibs.SetQuote(q)
+		changed = true
+	}
+	return changed
+}
+
+// Cancel every market data subscription, however many users it has.
+func (f *Feed) cancelQuotes() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for conID, sub := range f.quotes {
+		f.ib.CancelMktData(sub.contract)
+		delete(f.quotes, conID)
+	}
+}
+
+// ibsync uses NaN for "no data yet". Swap it for Unset so that quotes compare with ==.
+func orUnset(v float64) float64 {
+	if math.IsNaN(v) {
+		return Unset
+	}
+	return v
+}
+
+// Report whether IB has sent a usable price.
+func isSet(v float64) bool {
+	return v > 0 && v != Unset
+}
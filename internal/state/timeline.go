@@ -0,0 +1,79 @@
+package state
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/scmhub/ibsync"
+)
+
+// ErrUnknownOrder occurs when IB has no record of an order this session.
+var ErrUnknownOrder = errors.New("order isn't known this session")
+
+// OrderEvent is one step in an order's life: a status change, a fill or an IB error.
+type OrderEvent struct {
+	Time      time.Time
+	Status    string
+	Message   string
+	ErrorCode int64   // IB error code, 0 if none
+	FillQty   float64 // Shares filled, 0 unless this is a fill
+	FillPrice float64
+}
+
+// OrderTimeline lists everything IB reported about an order this session,
+// oldest first. IB reuses order IDs across sessions, so the order is found
+// by its permanent ID, or by orderID only until IB has assigned one. It
+// reads ibsync's cache so it is safe to call from the TUI.
+func (f *Feed) OrderTimeline(orderID, permID int64) ([]OrderEvent, error) {
+	for _, t := range f.ib.Trades() {
+		if t != nil && t.Order != nil && sameOrder(t.Order, orderID, permID) {
+			return timeline(t.Logs(), t.Fills()), nil
+		}
+	}
+	return nil, fmt.Errorf("couldn't find order #%d: %w", orderID, ErrUnknownOrder)
+}
+
+// Report whether o is the order with permID, or with orderID if permID isn't known yet.
+func sameOrder(o *ibsync.Order, orderID, permID int64) bool {
+	if permID != 0 {
+		return o.PermID == permID
+	}
+	return orderID != 0 && o.OrderID == orderID
+}
+
+// Merge a trade's log entries and fills into one list in time order.
+func timeline(logs []ibsync.TradeLogEntry, fills []ibsync.Fill) []OrderEvent {
+	events := make([]OrderEvent, 0, len(logs)+len(fills))
+	for _, l := range logs {
+		if strings.HasPrefix(l.Message, "Fill ") {
+			continue // ibsync logs fills as text too; the fills below carry the numbers
+		}
+		events = append(events, OrderEvent{
+			Time:      l.Time,
+			Status:    l.Status,
+			Message:   l.Message,
+			ErrorCode: l.ErrorCode,
+		})
+	}
+	for _, fill := range fills {
+		if fill.Execution == nil {
+			continue
+		}
+		events = append(events, OrderEvent{
+			Time:      fill.Time,
+			Status:    "Fill",
+			Message:   fill.Execution.Exchange,
+			FillQty:   fill.Execution.Shares.Float(),
+			FillPrice: fill.Execution.Price,
+		})
+	}
+	// Stable, so events logged at the same instant keep IB's order
+	slices.SortStableFunc(events, func(a, b OrderEvent) int {
+		return cmp.Compare(a.Time.UnixNano(), b.Time.UnixNano())
+	})
+	return events
+}
@@ -0,0 +1,51 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scmhub/ibsync"
+)
+
+func TestTimeline(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	logs := []ibsync.TradeLogEntry{
+		{Time: t0, Status: "PendingSubmit"},
+		{Time: t0.Add(time.Second), Status: "Submitted"},
+		{Time: t0.Add(3 * time.Second), Status: "Submitted", Message: "Fill 40@189.5"},
+		{Time: t0.Add(5 * time.Second), Status: "Cancelled", Message: "Order canceled", ErrorCode: 202},
+	}
+	fills := []ibsync.Fill{
+		{Time: t0.Add(3 * time.Second), Execution: &ibsync.Execution{Shares: 40, Price: 189.5, Exchange: "ARCA"}},
+		{Time: t0.Add(2 * time.Second)}, // No execution
+	}
+	got := timeline(logs, fills)
+	want := []string{"PendingSubmit", "Submitted", "Fill", "Cancelled"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events got %+v", len(want), got)
+	}
+	for i, status := range want {
+		if got[i].Status != status {
+			t.Fatalf("expected event %d to be %q got %q", i, status, got[i].Status)
+		}
+	}
+	if got[2].FillQty != 40 || got[2].FillPrice != 189.5 {
+		t.Fatalf("expected a fill of 40 @ 189.5 got %+v", got[2])
+	}
+	if got[3].ErrorCode != 202 {
+		t.Fatalf("expected error code 202 on the cancel got %+v", got[3])
+	}
+}
+
+func TestSameOrder(t *testing.T) {
+	old := &ibsync.Order{OrderID: 5, PermID: 111}
+	if sameOrder(old, 5, 222) {
+		t.Fatalf("expected an earlier session's order #5 not to match this session's")
+	}
+	if !sameOrder(old, 0, 111) {
+		t.Fatalf("expected a match by permanent ID alone")
+	}
+	if !sameOrder(&ibsync.Order{OrderID: 5}, 5, 0) {
+		t.Fatalf("expected an order IB hasn't given a permanent ID to match by order ID")
+	}
+}
@@ -0,0 +1,62 @@
+package watchlist
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/glenntam/ibtui/internal/contract"
+)
+
+// The first header column, which tells a header row apart from a contract.
+const headerSymbol = "symbol"
+
+// ImportCSV reads "symbol,secType,exchange,currency" rows. A header row is
+// optional, and trailing columns may be left out to take the defaults.
+func ImportCSV(r io.Reader) ([]contract.Spec, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	specs := make([]contract.Spec, 0)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return specs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read watchlist CSV: %w", err)
+		}
+		if line == 1 && strings.EqualFold(record[0], headerSymbol) {
+			continue
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue // Blank line
+		}
+		spec, err := contract.Parse(strings.Join(record, ":"))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't import watchlist CSV line %d: %w", line, err)
+		}
+		specs = append(specs, spec)
+	}
+}
+
+// ExportCSV writes specs as "symbol,secType,exchange,currency" rows under a header.
+func ExportCSV(w io.Writer, specs []contract.Spec) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{headerSymbol, "secType", "exchange", "currency"}); err != nil {
+		return fmt.Errorf("couldn't write watchlist CSV header: %w", err)
+	}
+	for _, s := range specs {
+		if err := writer.Write([]string{s.Symbol, s.SecType, s.Exchange, s.Currency}); err != nil {
+			return fmt.Errorf("couldn't write watchlist CSV row: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("couldn't flush watchlist CSV: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Package watchlist keeps named lists of contracts in a local JSON file.
+package watchlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/glenntam/ibtui/internal/contract"
+)
+
+const (
+	// DefaultList is created when no watchlists exist yet.
+	DefaultList = "default"
+
+	filePermission = 0o600 // RW for owner only
+)
+
+// ErrEmptyName occurs when a watchlist is given a blank name.
+var ErrEmptyName = errors.New("watchlist name can't be empty")
+
+// ErrDuplicateName occurs when creating a watchlist whose name is already taken.
+var ErrDuplicateName = errors.New("watchlist name already exists")
+
+// ErrNotLoaded occurs when saving watchlists whose file couldn't be loaded,
+// which would overwrite the user's lists.
+var ErrNotLoaded = errors.New("watchlist file wasn't loaded")
+
+// Store contains every named watchlist and which one is shown.
+type Store struct {
+	Active string                     `json:"active"`
+	Lists  map[string][]contract.Spec `json:"lists"`
+
+	path     string
+	readOnly bool // The file couldn't be read, so Save mustn't replace it
+}
+
+// Load reads watchlists from path. A missing file isn't an error; it
+// gives a Store with one empty DefaultList that is created on first Save.
+// If the file can't be read or parsed, Load returns an empty Store that
+// refuses to Save, leaving the file for the user to fix.
+func Load(path string) (*Store, error) {
+	s := newStore(path)
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		s.readOnly = true
+		return s, fmt.Errorf("couldn't read watchlist file: %w", err)
+	}
+	if err = json.Unmarshal(data, s); err != nil {
+		s = newStore(path)
+		s.readOnly = true
+		return s, fmt.Errorf("couldn't parse watchlist file %v: %w", path, err)
+	}
+	if len(s.Lists) == 0 {
+		s.Lists = map[string][]contract.Spec{DefaultList: {}}
+	}
+	if _, ok := s.Lists[s.Active]; !ok {
+		s.Active = s.Names()[0]
+	}
+	return s, nil
+}
+
+// Save writes every watchlist back to the file it was loaded from.
+// It writes to a temporary file first so a crash can't leave it half written.
+func (s *Store) Save() error {
+	if s.readOnly {
+		return fmt.Errorf("couldn't save watchlists to %v: %w", s.path, ErrNotLoaded)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldn't encode watchlists: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, data, filePermission); err != nil {
+		return fmt.Errorf("couldn't write watchlist file: %w", err)
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("couldn't replace watchlist file: %w", err)
+	}
+	return nil
+}
+
+// A Store with one empty DefaultList.
+func newStore(path string) *Store {
+	return &Store{
+		Active: DefaultList,
+		Lists:  map[string][]contract.Spec{DefaultList: {}},
+		path:   path,
+	}
+}
+
+// Names returns every watchlist name in alphabetical order.
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.Lists))
+	for name := range s.Lists {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Specs returns the contracts of the active watchlist.
+func (s *Store) Specs() []contract.Spec {
+	return slices.Clone(s.Lists[s.Active])
+}
+
+// Cycle makes the next (step 1) or previous (step -1) watchlist active.
+func (s *Store) Cycle(step int) {
+	names := s.Names()
+	i := slices.Index(names, s.Active)
+	s.Active = names[((i+step)%len(names)+len(names))%len(names)]
+}
+
+// Create adds an empty watchlist and makes it active.
+func (s *Store) Create(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrEmptyName
+	}
+	if _, ok := s.Lists[name]; ok {
+		return fmt.Errorf("couldn't create watchlist %q: %w", name, ErrDuplicateName)
+	}
+	s.Lists[name] = []contract.Spec{}
+	s.Active = name
+	return nil
+}
+
+// Add appends spec to the active watchlist. Reports false if already listed.
+func (s *Store) Add(spec contract.Spec) bool {
+	if slices.Contains(s.Lists[s.Active], spec) {
+		return false
+	}
+	s.Lists[s.Active] = append(s.Lists[s.Active], spec)
+	return true
+}
+
+// Remove deletes spec from the active watchlist.
+func (s *Store) Remove(spec contract.Spec) {
+	s.Lists[s.Active] = slices.DeleteFunc(s.Lists[s.Active], func(c contract.Spec) bool {
+		return c == spec
+	})
+}
@@ -0,0 +1,111 @@
+package watchlist
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/glenntam/ibtui/internal/contract"
+)
+
+func TestStore_save_and_load(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchlists.json")
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load of missing file returned unexpected error: %v", err)
+	}
+	if s.Active != DefaultList || len(s.Specs()) != 0 {
+		t.Fatalf("expected an empty default list got %+v", s)
+	}
+
+	aapl := contract.Spec{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"}
+	es := contract.Spec{Symbol: "ES", SecType: "FUT", Exchange: "CME", Currency: "USD"}
+	if err = s.Create("futures"); err != nil {
+		t.Fatalf("Create returned unexpected error: %v", err)
+	}
+	if !s.Add(es) || s.Add(es) {
+		t.Fatalf("expected Add to accept ES once")
+	}
+	if err = s.Create("futures"); !errors.Is(err, ErrDuplicateName) {
+		t.Fatalf("expected ErrDuplicateName got %v", err)
+	}
+	s.Cycle(1) // futures -> default
+	s.Add(aapl)
+	if err = s.Save(); err != nil {
+		t.Fatalf("Save returned unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned unexpected error: %v", err)
+	}
+	if loaded.Active != DefaultList || !slices.Equal(loaded.Specs(), []contract.Spec{aapl}) {
+		t.Fatalf("expected active default list with AAPL got %+v", loaded)
+	}
+	loaded.Cycle(-1)
+	if loaded.Active != "futures" || !slices.Equal(loaded.Specs(), []contract.Spec{es}) {
+		t.Fatalf("expected futures list with ES got %+v", loaded)
+	}
+	loaded.Remove(es)
+	if len(loaded.Specs()) != 0 {
+		t.Fatalf("expected Remove to empty the futures list got %v", loaded.Specs())
+	}
+}
+
+func TestLoad_unparsable_file_is_kept(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchlists.json")
+	broken := `{"active": "tech", "lists": {"tech": [{"symbol": "AAPL"}]`
+	if err := os.WriteFile(path, []byte(broken), 0o600); err != nil {
+		t.Fatalf("couldn't write watchlist file: %v", err)
+	}
+	s, err := Load(path)
+	if err == nil {
+		t.Fatalf("expected a parse error")
+	}
+	if s.Active != DefaultList || len(s.Lists) != 1 || len(s.Specs()) != 0 {
+		t.Fatalf("expected an empty default list instead of a partial one got %+v", s)
+	}
+	s.Add(contract.Spec{Symbol: "MSFT"})
+	if err = s.Save(); !errors.Is(err, ErrNotLoaded) {
+		t.Fatalf("expected ErrNotLoaded got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != broken {
+		t.Fatalf("expected the unparsable file left alone got %q", data)
+	}
+}
+
+func TestCSV_round_trip(t *testing.T) {
+	in := "Symbol,SecType,Exchange,Currency\naapl\nEUR,CASH,IDEALPRO,USD\n\nSAP,STK,IBIS,EUR\n"
+	specs, err := ImportCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ImportCSV returned unexpected error: %v", err)
+	}
+	want := []contract.Spec{
+		{Symbol: "AAPL", SecType: "STK", Exchange: "SMART", Currency: "USD"},
+		{Symbol: "EUR", SecType: "CASH", Exchange: "IDEALPRO", Currency: "USD"},
+		{Symbol: "SAP", SecType: "STK", Exchange: "IBIS", Currency: "EUR"},
+	}
+	if !slices.Equal(specs, want) {
+		t.Fatalf("ImportCSV = %+v, want %+v", specs, want)
+	}
+
+	var buf bytes.Buffer
+	if err = ExportCSV(&buf, specs); err != nil {
+		t.Fatalf("ExportCSV returned unexpected error: %v", err)
+	}
+	again, err := ImportCSV(&buf)
+	if err != nil {
+		t.Fatalf("ImportCSV of export returned unexpected error: %v", err)
+	}
+	if !slices.Equal(again, want) {
+		t.Fatalf("round trip = %+v, want %+v", again, want)
+	}
+
+	if _, err = ImportCSV(strings.NewReader("AAPL\n,STK\n")); !errors.Is(err, contract.ErrEmptySymbol) {
+		t.Fatalf("expected ErrEmptySymbol for a row without symbol got %v", err)
+	}
+}